@@ -0,0 +1,57 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import "fmt"
+
+// ABIVersion is the FFI ABI version this bridge is built against. It is
+// bumped whenever a change to the C interface (a signature, a struct
+// layout, the meaning of a result code) breaks binaries built for the
+// previous version, independently of the pforge release version, which may
+// change without breaking the ABI or, by accident, break it in a patch
+// release.
+const ABIVersion = 1
+
+// NativeABIVersion returns the ABI version reported by the native library's
+// pforge_abi_version, or 0 if the library predates ABI versioning or the
+// Bridge is closed
+func (b *Bridge) NativeABIVersion() int {
+	if b.enter() != nil {
+		return 0
+	}
+	defer b.leave()
+	return b.nativeABIVersion()
+}
+
+func (b *Bridge) nativeABIVersion() int {
+	if !b.supports(FeatureABIVersion) {
+		return 0
+	}
+	var version C.uint32_t
+	b.native(func() {
+		version = C.pforge_call_abi_version(b.symbols())
+	})
+	return int(version)
+}
+
+// handshake runs the construction-time checks against the native library:
+// the ABI version, then codec negotiation
+func (b *Bridge) handshake() error {
+	if err := b.checkABI(); err != nil {
+		return err
+	}
+	return b.negotiateCodec()
+}
+
+// checkABI fails with ErrABIMismatch unless the library reports ABIVersion.
+// Libraries without pforge_abi_version predate ABI versioning and are
+// accepted.
+func (b *Bridge) checkABI() error {
+	version := b.nativeABIVersion()
+	if version == 0 || version == ABIVersion {
+		return nil
+	}
+	return fmt.Errorf("%w: library has ABI version %d, bridge expects %d", ErrABIMismatch, version, ABIVersion)
+}
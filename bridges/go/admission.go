@@ -0,0 +1,41 @@
+package pforge
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// admission counts handler calls from the moment they ask for a slot until
+// they finish, shedding calls beyond the limit set with
+// WithAdmissionControl
+type admission struct {
+	limit atomic.Int64
+	depth atomic.Int64
+	shed  atomic.Uint64
+}
+
+// admit counts a call in, or fails with ErrOverloaded if the limit is
+// reached. Every successful admit must be paired with done.
+func (a *admission) admit() error {
+	for {
+		depth, limit := a.depth.Load(), a.limit.Load()
+		if limit > 0 && depth >= limit {
+			a.shed.Add(1)
+			return fmt.Errorf("%w: %d calls running or queued", ErrOverloaded, depth)
+		}
+		if a.depth.CompareAndSwap(depth, depth+1) {
+			return nil
+		}
+	}
+}
+
+// done counts a call out
+func (a *admission) done() {
+	a.depth.Add(-1)
+}
+
+// SetAdmissionLimit changes the WithAdmissionControl limit at runtime.
+// Zero or less removes it. Calls already admitted are not affected.
+func (b *Bridge) SetAdmissionLimit(maxQueue int) {
+	b.admission.limit.Store(int64(maxQueue))
+}
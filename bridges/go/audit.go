@@ -0,0 +1,112 @@
+package pforge
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"example/pforgectx"
+)
+
+// AuditEvent records one handler invocation for WithAuditSink. Inputs and
+// results are represented only by their hashes, so the audit trail holds no
+// handler data.
+type AuditEvent struct {
+	// Time is when the call started
+	Time     time.Time
+	Duration time.Duration
+	// Client is "name/version" from WithClientIdentity, or empty
+	Client string
+	// RequestID and Tenant are taken from the call's context (see
+	// pforgectx), for context-aware calls
+	RequestID string
+	Tenant    string
+	Handler   string
+	// Call is the kind of call: "handler call", "batch call",
+	// "upload call", "handler stream" or "duplex stream"
+	Call string
+	// InputHash is the hex SHA-256 of the canonical JSON encoding of the
+	// caller's input, before envelope fields are added and after
+	// WithSensitiveFields redaction, so low-entropy secrets cannot be
+	// recovered from it by guessing. For uploads it is the hash of the raw
+	// bytes sent, unless sensitive fields are set and they are a JSON
+	// object; for duplex streams it is empty.
+	InputHash string
+	// ResultHash is the hex SHA-256 of the canonical JSON encoding of the
+	// result, also after redaction. It is empty when the call failed and
+	// for streamed results, which are never held in memory.
+	ResultHash string
+	// Err is the call's error, nil on success
+	Err error
+}
+
+// auditDigest is a hash computed where the hashed data was available,
+// passed to audit in place of the data
+type auditDigest string
+
+// audit reports a finished call to the audit sink, if one is set. input and
+// output are hashed with auditHash; a failed call's output is not hashed.
+// The call is also passed on to the trace recorder.
+func (b *Bridge) audit(ctx context.Context, msg, handlerName string, start time.Time, input, output any, err error) {
+	b.recordTrace(handlerName, input, output, err)
+	if b.auditSink == nil {
+		return
+	}
+
+	event := AuditEvent{
+		Time:      start,
+		Duration:  time.Since(start),
+		Client:    b.clientIdentity,
+		Handler:   handlerName,
+		Call:      msg,
+		InputHash: auditHash(b.redactAny(input)),
+		Err:       err,
+	}
+	event.RequestID, _ = pforgectx.RequestID(ctx)
+	event.Tenant, _ = pforgectx.Tenant(ctx)
+	if err == nil {
+		event.ResultHash = auditHash(b.redactAny(output))
+	}
+	b.auditSink(event)
+}
+
+// redactAny applies Redact to input and output maps, leaving other values,
+// such as digests and raw bytes, as they are
+func (b *Bridge) redactAny(v any) any {
+	if m, ok := v.(map[string]interface{}); ok {
+		return b.Redact(m)
+	}
+	return v
+}
+
+// auditRawHash is auditHash for raw bytes. With WithSensitiveFields, bytes
+// holding a JSON object are decoded so that it is hashed redacted.
+func (b *Bridge) auditRawHash(raw []byte) auditDigest {
+	if len(b.sensitive) > 0 {
+		var decoded map[string]interface{}
+		if err := b.jsonCodec.Unmarshal(raw, &decoded); err == nil && decoded != nil {
+			return auditDigest(auditHash(b.Redact(decoded)))
+		}
+	}
+	return auditDigest(auditHash(json.RawMessage(raw)))
+}
+
+// auditHash is HashInput for audit events: errors hash to "", raw bytes
+// that are not JSON are hashed as they are, and nil hashes to ""
+func auditHash(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case auditDigest:
+		return string(v)
+	case json.RawMessage:
+		if !json.Valid(v) {
+			return hashBytes(v)
+		}
+	}
+	hash, err := HashInput(v)
+	if err != nil {
+		return ""
+	}
+	return hash
+}
@@ -0,0 +1,41 @@
+package pforge
+
+import "testing"
+
+// TestAuditRedactsSensitiveFields checks audit hashes never cover the
+// values of sensitive fields, whether the result is decoded, borrowed or
+// split into primary and auxiliary payloads
+func TestAuditRedactsSensitiveFields(t *testing.T) {
+	var events []AuditEvent
+	b := newStubBridge(t, nil,
+		WithSensitiveFields([]string{"token"}),
+		WithAuditSink(func(event AuditEvent) { events = append(events, event) }),
+	)
+
+	input := map[string]interface{}{"token": "s3cret", "n": 1}
+	if _, err := b.ExecuteHandler("echo", input); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.ExecuteHandlerWith("echo", input, func([]byte) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.ExecuteHandlerMulti("echo", input); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := HashInput(map[string]interface{}{"token": RedactedValue, "n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d audit events, want 3", len(events))
+	}
+	for _, event := range events {
+		if event.InputHash != want {
+			t.Errorf("%s: InputHash = %s, want the redacted input's %s", event.Call, event.InputHash, want)
+		}
+		if event.ResultHash != want {
+			t.Errorf("%s: ResultHash = %s, want the redacted result's %s", event.Call, event.ResultHash, want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// DefaultBatchSize is how many inputs ExecuteBatch sends per native batch
+const DefaultBatchSize = 128
+
+// ExecuteBatch calls a handler once per input, returning outputs and errors
+// aligned with inputs: outputs[i] is nil exactly when errs[i] is not. See
+// BatchErrors for summarizing the errors.
+//
+// Inputs are processed in batches of WithBatchSize, or of a size tuned
+// between batches with WithAdaptiveBatching. Each batch holds one
+// in-flight slot and one handler concurrency slot, and its native results
+// are freed together: with a library providing pforge_free_results, a batch
+// of n inputs takes n+1 FFI crossings instead of 2n. Libraries without it
+// fall back to freeing each result.
+//
+// Once ctx is done, inputs not yet started fail with the context error.
+// CancelAll and the end of Close's grace period stop a batch the same way.
+func (b *Bridge) ExecuteBatch(ctx context.Context, handlerName string, inputs []map[string]interface{}) ([]map[string]interface{}, BatchErrors) {
+	defer releaseCallHandle(ctx)
+	ctx, cancelTimeout := b.handlerTimeout(ctx, handlerName)
+	defer cancelTimeout()
+	ctx, cancel := b.scope(ctx)
+	defer cancel()
+
+	start := time.Now()
+	outputs := make([]map[string]interface{}, len(inputs))
+	errs := make(BatchErrors, len(inputs))
+
+	for lo := 0; lo < len(inputs); {
+		size := b.currentBatchSize()
+		hi := min(lo+size, len(inputs))
+		batchStart := time.Now()
+		b.executeBatch(ctx, handlerName, inputs[lo:hi], outputs[lo:hi], errs[lo:hi])
+		if b.batchTuner != nil && ctx.Err() == nil {
+			b.batchTuner.observe(size, hi-lo, time.Since(batchStart))
+		}
+		lo = hi
+	}
+
+	for i, err := range errs {
+		b.record(err)
+		b.audit(ctx, "batch call", handlerName, start, inputs[i], outputs[i], err)
+	}
+	b.finishCall(ctx, "batch call", handlerName, start, errs.AsError())
+	return outputs, errs
+}
+
+// BatchErrors holds the per-input errors of ExecuteBatch, index-aligned
+// with the inputs; nil entries succeeded. It is a plain []error, so it can
+// be indexed and ranged over directly.
+type BatchErrors []error
+
+// Failed returns the indices of the inputs that failed, in ascending order
+func (e BatchErrors) Failed() []int {
+	var failed []int
+	for i, err := range e {
+		if err != nil {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// First returns the error of the lowest-indexed failed input, or nil
+func (e BatchErrors) First() error {
+	for _, err := range e {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AsError returns nil if every input succeeded, and otherwise one error
+// listing each failure as "input N: ..." in index order. errors.Is and
+// errors.As see through it to each input's error.
+func (e BatchErrors) AsError() error {
+	var errs []error
+	for i, err := range e {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("input %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// currentBatchSize returns the size of the next batch
+func (b *Bridge) currentBatchSize() int {
+	if b.batchTuner != nil {
+		return b.batchTuner.current()
+	}
+	b.settings.RLock()
+	defer b.settings.RUnlock()
+	if b.batchSize > 0 {
+		return b.batchSize
+	}
+	return DefaultBatchSize
+}
+
+// executeBatch runs one batch, filling outputs and errs in place
+func (b *Bridge) executeBatch(ctx context.Context, handlerName string, inputs, outputs []map[string]interface{}, errs []error) {
+	fail := func(err error) {
+		for i := range errs {
+			errs[i] = err
+		}
+	}
+	if ctx.Err() != nil {
+		fail(contextError(ctx))
+		return
+	}
+	if err := b.enter(); err != nil {
+		fail(err)
+		return
+	}
+	defer b.leave()
+
+	release, err := b.acquireHandler(ctx, handlerName)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer release()
+
+	payloads := make([][]byte, len(inputs))
+	for i, input := range inputs {
+		payloads[i], errs[i] = b.marshalBatchInput(handlerName, input)
+	}
+
+	b.native(func() {
+		var err error
+		defer func() {
+			if err == nil {
+				return
+			}
+			// Inputs the panic left without an outcome fail with it
+			for i := range errs {
+				if errs[i] == nil && outputs[i] == nil {
+					errs[i] = err
+				}
+			}
+		}()
+		defer b.recoverPanic(handlerName, &err)
+		b.executeBatchNative(ctx, handlerName, payloads, outputs, errs)
+	})
+}
+
+// marshalBatchInput is marshalInput for one batch input, failing just
+// that input if encoding it panics
+func (b *Bridge) marshalBatchInput(handlerName string, input map[string]interface{}) (payload []byte, err error) {
+	defer b.recoverPanic(handlerName, &err)
+	return b.marshalInput(handlerName, input)
+}
+
+// executeBatchNative calls the handler for each marshalled payload, keeping
+// the native results until the whole batch is done so they can be freed in
+// one crossing
+func (b *Bridge) executeBatchNative(ctx context.Context, handlerName string, payloads [][]byte, outputs []map[string]interface{}, errs []error) {
+	if s, ok := b.stubs[handlerName]; ok {
+		for i, payload := range payloads {
+			if errs[i] != nil {
+				continue
+			}
+			if ctx.Err() != nil {
+				errs[i] = contextError(ctx)
+				continue
+			}
+			errs[i] = b.invokeStub(s, handlerName, payload, func(result C.FfiResult) error {
+				var err error
+				outputs[i], err = b.decodeResult(handlerName, result)
+				return err
+			})
+		}
+		return
+	}
+
+	cHandlerName := C.CString(handlerName)
+	defer C.free(unsafe.Pointer(cHandlerName))
+
+	syms := b.symbols()
+	array := (*C.FfiResult)(C.calloc(C.size_t(len(payloads)), C.sizeof_FfiResult))
+	defer C.free(unsafe.Pointer(array))
+	results := unsafe.Slice(array, len(payloads))
+
+	n := 0
+	defer func() {
+		if b.supports(FeatureBulkFree) {
+			C.pforge_call_free_results(syms, array, C.size_t(n))
+			return
+		}
+		for _, result := range results[:n] {
+			C.pforge_call_free_result(syms, result)
+		}
+	}()
+
+	for i, payload := range payloads {
+		if errs[i] != nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			errs[i] = contextError(ctx)
+			continue
+		}
+		payload, err := b.wireInput(handlerName, payload)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		results[n] = C.pforge_call_execute_handler(
+			syms,
+			cHandlerName,
+			(*C.uchar)(unsafe.Pointer(&payload[0])),
+			C.size_t(len(payload)),
+		)
+		b.stats.bytesIn.Add(uint64(len(payload)))
+		b.stats.codes.observe(handlerName, int(results[n].code))
+		outputs[i], errs[i] = b.decodeResult(handlerName, results[n])
+		n++
+	}
+}
@@ -0,0 +1,38 @@
+package pforge
+
+import (
+	"context"
+	"testing"
+)
+
+// BenchmarkExecuteBatch1000 runs a 1000-input batch in one native crossing
+// per input, freeing the results with pforge_free_results in one more
+// crossing or, without FeatureBulkFree, one crossing per result
+func BenchmarkExecuteBatch1000(b *testing.B) {
+	inputs := make([]map[string]interface{}, 1000)
+	for i := range inputs {
+		inputs[i] = map[string]interface{}{"n": i}
+	}
+
+	for _, bench := range []struct {
+		name    string
+		defines []string
+	}{
+		{name: "bulk free"},
+		{name: "per-item free", defines: []string{"NO_BULK_FREE"}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			bridge := newStubBridge(b, bench.defines, WithBatchSize(len(inputs)))
+			if want := bench.defines == nil; bridge.Supports(FeatureBulkFree) != want {
+				b.Fatalf("Supports(FeatureBulkFree) = %v, want %v", !want, want)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, errs := bridge.ExecuteBatch(context.Background(), "echo", inputs); errs.First() != nil {
+					b.Fatal(errs.First())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package pforge
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// AdaptiveBatching configures ExecuteBatch to tune its batch size toward a
+// target latency window instead of using a fixed size
+type AdaptiveBatching struct {
+	// MinSize and MaxSize bound the batch size. MinSize defaults to 1 and
+	// MaxSize to 8 × DefaultBatchSize.
+	MinSize int
+	MaxSize int
+	// TargetLow and TargetHigh bound the desired latency of one batch
+	TargetLow  time.Duration
+	TargetHigh time.Duration
+}
+
+// batchGrowStep is how many inputs batchTuner adds to the batch size when
+// there is headroom
+const batchGrowStep = DefaultBatchSize / 8
+
+// batchTuner adjusts the batch size after each batch. It is shared by all
+// ExecuteBatch calls on a Bridge, so concurrent batches feed the same
+// estimate.
+//
+// The control loop is additive increase, multiplicative decrease:
+//
+//   - a full batch that finished under TargetLow grows the size by
+//     batchGrowStep, since there is headroom for more work per crossing;
+//   - any batch that took longer than TargetHigh halves the size, so a
+//     sudden jump in payload cost is corrected within a few batches;
+//   - anything in between leaves the size alone.
+//
+// Partial batches (the tail of an input slice) never grow the size, since
+// their latency says nothing about a full batch. The result is clamped to
+// [MinSize, MaxSize]. Growth being gentler than shrinkage keeps the size
+// from oscillating around the window edges.
+type batchTuner struct {
+	config AdaptiveBatching
+	size   atomic.Int64
+}
+
+func newBatchTuner(config AdaptiveBatching) *batchTuner {
+	if config.MinSize <= 0 {
+		config.MinSize = 1
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = 8 * DefaultBatchSize
+	}
+	if config.MaxSize < config.MinSize {
+		config.MaxSize = config.MinSize
+	}
+
+	t := &batchTuner{config: config}
+	t.size.Store(int64(min(max(DefaultBatchSize, config.MinSize), config.MaxSize)))
+	return t
+}
+
+// current returns the size to use for the next batch
+func (t *batchTuner) current() int {
+	return int(t.size.Load())
+}
+
+// observe feeds back the latency of a batch of n inputs started at size
+func (t *batchTuner) observe(size, n int, elapsed time.Duration) {
+	next := size
+	switch {
+	case elapsed > t.config.TargetHigh:
+		next = size / 2
+	case elapsed < t.config.TargetLow && n == size:
+		next = size + batchGrowStep
+	default:
+		return
+	}
+
+	next = min(max(next, t.config.MinSize), t.config.MaxSize)
+	// Only move from the size this batch ran with; a concurrent batch that
+	// already adjusted it wins
+	t.size.CompareAndSwap(int64(size), int64(next))
+}
@@ -0,0 +1,73 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"context"
+	"time"
+	"unsafe"
+)
+
+// ExecuteHandlerWith calls a handler and passes its raw result to fn
+// without copying it out of native memory, freeing the result when fn
+// returns. fn's error, if any, is returned.
+//
+// raw is only valid while fn runs: fn must not retain it, any slice of
+// it, or anything aliasing it (such as a string made with unsafe.String),
+// and must not modify it. Copy whatever must outlive the call. raw is nil
+// for an empty result. fn is not called if the handler fails.
+//
+// With WithLockedThread, fn runs on the locked thread and blocks other
+// native calls until it returns, so keep it short.
+func (b *Bridge) ExecuteHandlerWith(handlerName string, input map[string]interface{}, fn func(raw []byte) error) error {
+	start := time.Now()
+	var resultHash auditDigest
+	if b.auditSink != nil {
+		// Hash raw while it is still valid
+		inner := fn
+		fn = func(raw []byte) error {
+			resultHash = b.auditRawHash(raw)
+			return inner(raw)
+		}
+	}
+	err := b.record(b.executeWith(handlerName, input, fn))
+	b.finishCall(context.Background(), "handler call", handlerName, start, err)
+	b.audit(context.Background(), "handler call", handlerName, start, input, resultHash, err)
+	return err
+}
+
+func (b *Bridge) executeWith(handlerName string, input map[string]interface{}, fn func(raw []byte) error) error {
+	release, err := b.acquireHandler(context.Background(), handlerName)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	inputJSON, err := b.marshalInput(handlerName, input)
+	if err != nil {
+		return err
+	}
+
+	return b.invoke(entryExecute, handlerName, inputJSON, func(result C.FfiResult) error {
+		raw, err := resultView(result)
+		if err != nil {
+			return err
+		}
+		b.stats.bytesOut.Add(uint64(len(raw)))
+		return fn(raw)
+	})
+}
+
+// resultView is resultData without the copy: the returned slice aliases
+// the native result and is only valid until the result is freed
+func resultView(result C.FfiResult) ([]byte, error) {
+	if result.code != 0 {
+		return resultData(result)
+	}
+	if result.data == nil || result.data_len == 0 {
+		return nil, nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(result.data)), int(result.data_len)), nil
+}
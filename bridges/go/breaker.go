@@ -0,0 +1,166 @@
+package pforge
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a handler's circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets calls through while counting consecutive failures
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects calls with ErrCircuitOpen until the cooldown ends
+	CircuitOpen
+	// CircuitHalfOpen lets one trial call through; its outcome closes or
+	// reopens the circuit
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// BreakerState is a snapshot of one handler's circuit breaker
+type BreakerState struct {
+	State CircuitState
+	// Failures is the number of consecutive failures counted while closed,
+	// or that tripped the breaker while it is open
+	Failures int
+	// LastTransition is when State last changed, zero if never
+	LastTransition time.Time
+}
+
+// breakers holds the per-handler breakers enabled by WithCircuitBreaker
+type breakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	handlers map[string]*breaker
+}
+
+// breaker is one handler's state; trial is set while the half-open trial
+// call is in flight
+type breaker struct {
+	BreakerState
+	trial bool
+}
+
+// allow admits a call to handlerName, or fails with ErrCircuitOpen
+func (c *breakers) allow(handlerName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	br := c.handlers[handlerName]
+	if br == nil {
+		return nil
+	}
+	switch br.State {
+	case CircuitOpen:
+		if time.Since(br.LastTransition) < c.cooldown {
+			return fmt.Errorf("%w: %s", ErrCircuitOpen, handlerName)
+		}
+		br.transition(CircuitHalfOpen)
+		br.trial = true
+	case CircuitHalfOpen:
+		if br.trial {
+			return fmt.Errorf("%w: %s", ErrCircuitOpen, handlerName)
+		}
+		br.trial = true
+	}
+	return nil
+}
+
+// record counts the outcome of a finished call to handlerName. Only an
+// admitted call can be the half-open trial, so calls rejected with
+// ErrCircuitOpen are ignored, and streams, which are never admitted by
+// allow, are counted while closed but leave a half-open breaker alone.
+func (c *breakers) record(handlerName string, stream bool, err error) {
+	if errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+	counted := breakerFailure(err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	br := c.handlers[handlerName]
+	if br == nil {
+		if !counted {
+			return
+		}
+		br = &breaker{}
+		c.handlers[handlerName] = br
+	}
+
+	switch br.State {
+	case CircuitClosed:
+		if !counted {
+			if err == nil {
+				br.Failures = 0
+			}
+			return
+		}
+		br.Failures++
+		if br.Failures >= c.threshold {
+			br.transition(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		if stream {
+			return
+		}
+		br.trial = false
+		if counted {
+			br.transition(CircuitOpen)
+		} else if err == nil {
+			br.Failures = 0
+			br.transition(CircuitClosed)
+		}
+	}
+}
+
+func (br *breaker) transition(state CircuitState) {
+	br.State = state
+	br.LastTransition = time.Now()
+}
+
+// breakerFailure reports whether err counts against a breaker: failures
+// of the handler itself and deadlines, not errors caused by the caller or
+// the Bridge, such as cancellation, bad input or ErrCircuitOpen
+func breakerFailure(err error) bool {
+	var handlerErr *HandlerError
+	return errors.As(err, &handlerErr) || errors.Is(err, ErrHandlerPanic) || errors.Is(err, ErrCallerDeadlineExceeded)
+}
+
+// BreakerStates returns a snapshot of the circuit breaker of every handler
+// that has failed since the Bridge was created, for status pages; handlers
+// missing from the map are closed with no failures. It is empty without
+// WithCircuitBreaker. An open breaker whose cooldown has ended is reported
+// open until the next call to its handler makes it half-open. The snapshot
+// takes one short lock and is safe to call concurrently with calls.
+func (b *Bridge) BreakerStates() map[string]BreakerState {
+	states := make(map[string]BreakerState)
+	if b.breakers == nil {
+		return states
+	}
+
+	b.breakers.mu.Lock()
+	defer b.breakers.mu.Unlock()
+	for name, br := range b.breakers.handlers {
+		states[name] = br.BreakerState
+	}
+	return states
+}
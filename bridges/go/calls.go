@@ -0,0 +1,129 @@
+package pforge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// errCancelledAll is the cancellation cause of calls stopped by CancelAll
+var errCancelledAll = errors.New("cancelled by CancelAll")
+
+// CallHandle cancels one in-flight call from outside the call's own
+// context, e.g. when a user aborts one of several running operations.
+type CallHandle struct {
+	id     uint64
+	bridge *Bridge
+	cancel context.CancelCauseFunc
+}
+
+// callHandleKey is the context key under which a CallHandle is stored
+type callHandleKey struct{}
+
+// callRegistry tracks the handles of calls that have not completed
+type callRegistry struct {
+	mu      sync.Mutex
+	nextID  atomic.Uint64
+	handles map[uint64]*CallHandle
+
+	// all is cancelled by CancelAll and then replaced, so calls starting
+	// afterwards are unaffected
+	all       context.Context
+	cancelAll context.CancelFunc
+}
+
+// NewCallHandle registers a handle for one call. Pass the returned context
+// to a context-aware method such as ExecuteHandlerContext; Cancel on the
+// handle then makes that call return ErrCallCancelled without affecting
+// any other call. The handle is released when the call completes, so each
+// handle should be used for a single call.
+func (b *Bridge) NewCallHandle(ctx context.Context) (context.Context, *CallHandle) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	h := &CallHandle{
+		id:     b.calls.nextID.Add(1),
+		bridge: b,
+		cancel: cancel,
+	}
+
+	b.calls.mu.Lock()
+	if b.calls.handles == nil {
+		b.calls.handles = make(map[uint64]*CallHandle)
+	}
+	b.calls.handles[h.id] = h
+	b.calls.mu.Unlock()
+
+	return context.WithValue(ctx, callHandleKey{}, h), h
+}
+
+// ID identifies the handle for CancelCall
+func (h *CallHandle) ID() uint64 {
+	return h.id
+}
+
+// Cancel aborts the call and releases the handle. It is safe to call more
+// than once and after the call has completed.
+func (h *CallHandle) Cancel() {
+	h.cancel(ErrCallCancelled)
+	h.release()
+}
+
+// release removes the handle from the Bridge registry
+func (h *CallHandle) release() {
+	h.bridge.calls.mu.Lock()
+	delete(h.bridge.calls.handles, h.id)
+	h.bridge.calls.mu.Unlock()
+}
+
+// CancelCall cancels the call registered under id, reporting whether it
+// was still in flight
+func (b *Bridge) CancelCall(id uint64) bool {
+	b.calls.mu.Lock()
+	h, ok := b.calls.handles[id]
+	b.calls.mu.Unlock()
+
+	if ok {
+		h.Cancel()
+	}
+	return ok
+}
+
+// releaseCallHandle releases the handle carried by ctx, if any, once the
+// call using it has completed
+func releaseCallHandle(ctx context.Context) {
+	if h, ok := ctx.Value(callHandleKey{}).(*CallHandle); ok {
+		h.cancel(nil)
+		h.release()
+	}
+}
+
+// CancelAll cancels every context-aware call in flight, including calls
+// waiting for a concurrency slot, for fault containment such as an
+// emergency shutdown. They return an error wrapping ErrCallerCancelled.
+// Calls starting after CancelAll returns are unaffected, and calls without
+// a context, such as ExecuteHandler, cannot be cancelled. Native code
+// already running is not interrupted; its result is discarded. Unlike
+// Close, the Bridge stays usable. It is safe to call concurrently with new
+// calls starting, which are either cancelled or left alone.
+func (b *Bridge) CancelAll() {
+	b.calls.mu.Lock()
+	cancel := b.calls.cancelAll
+	b.calls.all, b.calls.cancelAll = nil, nil
+	b.calls.mu.Unlock()
+
+	if cancel != nil {
+		b.logger(context.Background()).Warn("cancelling all in-flight calls")
+		cancel()
+	}
+}
+
+// cancelAllContext returns the context CancelAll cancels next
+func (b *Bridge) cancelAllContext() context.Context {
+	b.calls.mu.Lock()
+	defer b.calls.mu.Unlock()
+
+	if b.calls.all == nil {
+		b.calls.all, b.calls.cancelAll = context.WithCancel(context.Background())
+	}
+	return b.calls.all
+}
@@ -0,0 +1,46 @@
+package pforge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestCancelAllBatch checks CancelAll stops a batch between inputs
+func TestCancelAllBatch(t *testing.T) {
+	b := newStubBridge(t, nil)
+	inputs := make([]map[string]interface{}, 20)
+	for i := range inputs {
+		inputs[i] = map[string]interface{}{"n": i}
+	}
+
+	time.AfterFunc(30*time.Millisecond, b.CancelAll)
+	outputs, errs := b.ExecuteBatch(context.Background(), "slow", inputs)
+
+	failed := errs.Failed()
+	if len(failed) == 0 {
+		t.Fatal("no input was cancelled")
+	}
+	if outputs[0] == nil {
+		t.Errorf("first input failed with %v, want it to finish before CancelAll", errs[0])
+	}
+	for _, i := range failed {
+		if !errors.Is(errs[i], ErrCallerCancelled) {
+			t.Errorf("input %d failed with %v, want ErrCallerCancelled", i, errs[i])
+		}
+	}
+}
+
+// TestExecuteHandlerTimeout checks a handler's default timeout applies to
+// ExecuteHandler as it does to ExecuteHandlerContext
+func TestExecuteHandlerTimeout(t *testing.T) {
+	b := newStubBridge(t, nil, WithHandlerTimeout(map[string]time.Duration{"slow": 5 * time.Millisecond}))
+
+	if _, err := b.ExecuteHandler("slow", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow handler: got %v, want a deadline error", err)
+	}
+	if _, err := b.ExecuteHandler("echo", nil); err != nil {
+		t.Errorf("handler without a timeout: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"fmt"
+	"sort"
+)
+
+// CatalogEntry describes one handler together with its schemas
+type CatalogEntry struct {
+	HandlerInfo
+	HandlerSchema
+}
+
+// Catalog lists every handler with its schemas, in ListHandlers order
+type Catalog []CatalogEntry
+
+// Catalog returns all handlers with their input and output schemas, for
+// building a complete description of the server in one round trip. A native
+// library with FeatureCatalog answers in a single FFI call; otherwise the
+// catalog is assembled from ListHandlers and Schema, one call per handler.
+//
+// The catalog is cached, and its schemas also fill the Schema cache, so
+// later lookups do not cross the FFI; RefreshSchemas drops both. The
+// returned slice is a copy, but the schemas in it are shared and must not
+// be modified.
+func (b *Bridge) Catalog() (Catalog, error) {
+	b.catalogMu.Lock()
+	defer b.catalogMu.Unlock()
+
+	if b.catalog == nil {
+		catalog, err := b.fetchCatalog()
+		if err != nil {
+			return nil, err
+		}
+		b.catalog = catalog
+	}
+	return append(Catalog{}, b.catalog...), nil
+}
+
+// fetchCatalog asks the native library for the catalog, falling back to
+// one schema call per handler
+func (b *Bridge) fetchCatalog() (Catalog, error) {
+	if !b.supports(FeatureCatalog) {
+		return b.assembleCatalog()
+	}
+
+	if err := b.enter(); err != nil {
+		return nil, err
+	}
+	defer b.leave()
+
+	syms := b.symbols()
+	var data []byte
+	var err error
+	b.native(func() {
+		result := C.pforge_call_catalog(syms)
+		data, err = resultData(result)
+		C.pforge_call_free_result(syms, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := Catalog{}
+	if data != nil {
+		if err := b.jsonCodec.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal catalog: %w", err)
+		}
+	}
+
+	for _, entry := range catalog {
+		// An unparsable schema is left out of the cache, so Schema and
+		// ValidateInput report the parse error when they fetch it
+		if cached, err := newCachedSchema(entry.HandlerSchema); err == nil && b.schemas != nil {
+			b.schemas.put(entry.Name, cached)
+		}
+	}
+	sort.SliceStable(catalog, func(i, j int) bool {
+		return handlerLess(catalog[i].HandlerInfo, catalog[j].HandlerInfo, b.handlerOrder)
+	})
+	return catalog, nil
+}
+
+// assembleCatalog builds the catalog from ListHandlers and Schema
+func (b *Bridge) assembleCatalog() (Catalog, error) {
+	handlers, err := b.ListHandlers()
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := make(Catalog, 0, len(handlers))
+	for _, handler := range handlers {
+		schema, err := b.Schema(handler.Name)
+		if err != nil {
+			return nil, fmt.Errorf("handler %s: %w", handler.Name, err)
+		}
+		catalog = append(catalog, CatalogEntry{HandlerInfo: handler, HandlerSchema: schema})
+	}
+	return catalog, nil
+}
@@ -0,0 +1,115 @@
+package pforge
+
+import (
+	"context"
+	"fmt"
+)
+
+// ChainTransform reshapes the output of one chain stage into the input of
+// the next. stage is the index of the handler that produced output.
+type ChainTransform func(stage int, output map[string]interface{}) (map[string]interface{}, error)
+
+// ChainError reports which stage of a HandlerChain failed
+type ChainError struct {
+	// Stage is the index of the failing handler in the chain
+	Stage   int
+	Handler string
+	// Transform is set when the handler succeeded but the transform after
+	// it failed
+	Transform bool
+	Err       error
+}
+
+func (e *ChainError) Error() string {
+	if e.Transform {
+		return fmt.Sprintf("chain stage %d (%s): transform: %v", e.Stage, e.Handler, e.Err)
+	}
+	return fmt.Sprintf("chain stage %d (%s): %v", e.Stage, e.Handler, e.Err)
+}
+
+// Unwrap returns the stage's error
+func (e *ChainError) Unwrap() error {
+	return e.Err
+}
+
+// HandlerChain calls handlers in sequence, each with the previous one's
+// output as input. Create one with Bridge.Chain:
+//
+//	fetchAndParse := bridge.Chain("fetch", "parse", "summarize").
+//		Transform(0, func(_ int, out map[string]interface{}) (map[string]interface{}, error) {
+//			return map[string]interface{}{"text": out["body"]}, nil
+//		})
+//	summary, err := fetchAndParse.Execute(ctx, input)
+//
+// A HandlerChain is immutable and safe for concurrent use.
+type HandlerChain struct {
+	bridge     *Bridge
+	handlers   []string
+	transforms map[int]ChainTransform
+	// err is a construction error reported by Execute
+	err error
+}
+
+// Chain returns a HandlerChain running handlers in the given order
+func (b *Bridge) Chain(handlers ...string) *HandlerChain {
+	return &HandlerChain{
+		bridge:   b,
+		handlers: append([]string(nil), handlers...),
+	}
+}
+
+// Transform returns a copy of c that passes the output of stage through fn
+// before it becomes the next stage's input. It replaces any transform
+// already set for stage. A transform after the last stage reshapes the
+// chain's result. A stage outside the chain makes Execute fail.
+func (c *HandlerChain) Transform(stage int, fn ChainTransform) *HandlerChain {
+	if stage < 0 || stage >= len(c.handlers) {
+		chain := *c
+		chain.err = fmt.Errorf("chain transform after stage %d, but the chain has %d stages", stage, len(c.handlers))
+		return &chain
+	}
+
+	transforms := make(map[int]ChainTransform, len(c.transforms)+1)
+	for i, t := range c.transforms {
+		transforms[i] = t
+	}
+	transforms[stage] = fn
+	return &HandlerChain{bridge: c.bridge, handlers: c.handlers, transforms: transforms, err: c.err}
+}
+
+// Handlers returns the handler names in chain order
+func (c *HandlerChain) Handlers() []string {
+	return append([]string(nil), c.handlers...)
+}
+
+// Execute runs the chain with input and returns the last stage's output.
+// The first failing stage stops the chain and is reported as a
+// *ChainError wrapping its error. Each stage is an ExecuteHandlerContext
+// call, so ctx is checked between stages as well as during them. An empty
+// chain returns input unchanged.
+func (c *HandlerChain) Execute(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	output := input
+	for i, handlerName := range c.handlers {
+		if ctx.Err() != nil {
+			return nil, &ChainError{Stage: i, Handler: handlerName, Err: contextError(ctx)}
+		}
+
+		var err error
+		output, err = c.bridge.ExecuteHandlerContext(ctx, handlerName, output)
+		if err != nil {
+			return nil, &ChainError{Stage: i, Handler: handlerName, Err: err}
+		}
+
+		if fn, ok := c.transforms[i]; ok {
+			output, err = fn(i, output)
+			if err != nil {
+				return nil, &ChainError{Stage: i, Handler: handlerName, Transform: true, Err: err}
+			}
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,236 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unsafe"
+)
+
+// Codec names a wire encoding for handler inputs and results
+type Codec string
+
+// Codecs known to the bridge protocol
+const (
+	CodecJSON Codec = "json"
+	// CodecMsgpack is MessagePack. The Bridge still builds inputs as JSON,
+	// envelope fields included, and converts them before each call; map
+	// results decode as JSON ones would, with binary values as []byte.
+	// Raw results and stream chunks are passed on as the handler encoded
+	// them.
+	CodecMsgpack Codec = "msgpack"
+)
+
+// DefaultCodecPreference is the handshake preference used without
+// WithDefaultCodec
+var DefaultCodecPreference = []Codec{CodecMsgpack, CodecJSON}
+
+// bridgeCodecs are the codecs this Bridge can encode and decode; other
+// codecs in a preference list are skipped
+var bridgeCodecs = []Codec{CodecJSON, CodecMsgpack}
+
+// ActiveCodec returns the codec negotiated with the native library at
+// construction
+func (b *Bridge) ActiveCodec() Codec {
+	return b.codec
+}
+
+// HandlerCodec returns the codec used for handlerName: its WithHandlerCodec
+// override, or ActiveCodec
+func (b *Bridge) HandlerCodec(handlerName string) Codec {
+	if codec, ok := b.handlerCodecs[handlerName]; ok {
+		return codec
+	}
+	return b.codec
+}
+
+// wireCodec returns the codec for handlerName's inputs and results on the
+// wire: msgpackCodec for CodecMsgpack, and the Bridge's JSONCodec otherwise
+func (b *Bridge) wireCodec(handlerName string) JSONCodec {
+	if b.HandlerCodec(handlerName) == CodecMsgpack {
+		return msgpackCodec{}
+	}
+	return b.jsonCodec
+}
+
+// wireInput converts an input payload, which the Bridge always builds as
+// JSON so envelope fields can be spliced in, to handlerName's codec
+func (b *Bridge) wireInput(handlerName string, payload []byte) ([]byte, error) {
+	if b.HandlerCodec(handlerName) != CodecMsgpack {
+		return payload, nil
+	}
+	data, err := msgpackCodec{}.Marshal(json.RawMessage(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode input as msgpack: %w", err)
+	}
+	return data, nil
+}
+
+// negotiateCodec picks the first preferred codec supported by both sides
+// and tells the native library about it. Libraries without the codec entry
+// points only understand JSON.
+func (b *Bridge) negotiateCodec() error {
+	preference := b.codecs
+	if preference == nil {
+		preference = DefaultCodecPreference
+	}
+
+	native := []Codec{CodecJSON}
+	if b.supports(FeatureCodecs) {
+		var err error
+		if native, err = b.nativeCodecs(); err != nil {
+			return err
+		}
+	}
+
+	for _, codec := range preference {
+		if !containsCodec(bridgeCodecs, codec) || !containsCodec(native, codec) {
+			continue
+		}
+		if b.supports(FeatureCodecs) {
+			if err := b.selectCodec(codec); err != nil {
+				return err
+			}
+		}
+		b.codec = codec
+		return b.negotiateHandlerCodecs()
+	}
+	return fmt.Errorf("%w: preferred %v, bridge supports %v, native library supports %v",
+		ErrNoCommonCodec, preference, bridgeCodecs, native)
+}
+
+// negotiateHandlerCodecs checks each WithHandlerCodec override against the
+// native handler and selects it there. Overrides are checked in name order
+// so the reported failure does not vary between runs.
+func (b *Bridge) negotiateHandlerCodecs() error {
+	names := make([]string, 0, len(b.handlerCodecs))
+	for name := range b.handlerCodecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		codec := b.handlerCodecs[name]
+		if codec == b.codec {
+			continue
+		}
+		if !containsCodec(bridgeCodecs, codec) {
+			return fmt.Errorf("%w: handler %s wants %s, bridge supports %v",
+				ErrNoCommonCodec, name, codec, bridgeCodecs)
+		}
+		if !b.supports(FeatureHandlerCodec) {
+			return fmt.Errorf("%w: per-handler codec for %s", ErrNotSupported, name)
+		}
+
+		native, err := b.nativeHandlerCodecs(name)
+		if err != nil {
+			return err
+		}
+		if !containsCodec(native, codec) {
+			return fmt.Errorf("%w: handler %s wants %s, native handler supports %v",
+				ErrNoCommonCodec, name, codec, native)
+		}
+		if err := b.selectHandlerCodec(name, codec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nativeCodecs asks the native library which codecs it supports
+func (b *Bridge) nativeCodecs() ([]Codec, error) {
+	syms := b.symbols()
+	var data []byte
+	var err error
+	b.native(func() {
+		result := C.pforge_call_codecs(syms)
+		data, err = resultData(result)
+		C.pforge_call_free_result(syms, result)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query native codecs: %w", err)
+	}
+
+	var codecs []Codec
+	if data != nil {
+		if err := b.jsonCodec.Unmarshal(data, &codecs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal native codecs: %w", err)
+		}
+	}
+	return codecs, nil
+}
+
+// nativeHandlerCodecs asks the native library which codecs one handler
+// supports
+func (b *Bridge) nativeHandlerCodecs(handlerName string) ([]Codec, error) {
+	cHandlerName := C.CString(handlerName)
+	defer C.free(unsafe.Pointer(cHandlerName))
+
+	syms := b.symbols()
+	var data []byte
+	var err error
+	b.native(func() {
+		result := C.pforge_call_handler_codecs(syms, cHandlerName)
+		data, err = resultData(result)
+		C.pforge_call_free_result(syms, result)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query codecs of handler %s: %w", handlerName, err)
+	}
+
+	var codecs []Codec
+	if data != nil {
+		if err := b.jsonCodec.Unmarshal(data, &codecs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal codecs of handler %s: %w", handlerName, err)
+		}
+	}
+	return codecs, nil
+}
+
+// selectHandlerCodec tells the native library which codec one handler's
+// inputs and results will use
+func (b *Bridge) selectHandlerCodec(handlerName string, codec Codec) error {
+	cHandlerName := C.CString(handlerName)
+	defer C.free(unsafe.Pointer(cHandlerName))
+	cCodec := C.CString(string(codec))
+	defer C.free(unsafe.Pointer(cCodec))
+
+	syms := b.symbols()
+	var code C.int
+	b.native(func() {
+		code = C.pforge_call_select_handler_codec(syms, cHandlerName, cCodec)
+	})
+	if code != 0 {
+		return fmt.Errorf("native library rejected codec %s for handler %s (code %d)", codec, handlerName, int(code))
+	}
+	return nil
+}
+
+// selectCodec tells the native library which codec the Bridge will use
+func (b *Bridge) selectCodec(codec Codec) error {
+	cCodec := C.CString(string(codec))
+	defer C.free(unsafe.Pointer(cCodec))
+
+	syms := b.symbols()
+	var code C.int
+	b.native(func() {
+		code = C.pforge_call_select_codec(syms, cCodec)
+	})
+	if code != 0 {
+		return fmt.Errorf("native library rejected codec %s (code %d)", codec, int(code))
+	}
+	return nil
+}
+
+func containsCodec(codecs []Codec, codec Codec) bool {
+	for _, c := range codecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
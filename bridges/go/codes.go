@@ -0,0 +1,62 @@
+package pforge
+
+import "sync"
+
+// MaxTrackedCodes caps how many distinct result codes are counted per
+// handler; further codes are counted together in CodeCounts.Other
+const MaxTrackedCodes = 16
+
+// CodeCounts is the distribution of native result codes for one handler
+type CodeCounts struct {
+	// Codes counts calls by exact FfiResult.code, including 0 for success
+	Codes map[int]uint64
+	// Other counts calls whose code arrived after MaxTrackedCodes
+	// distinct codes were already tracked
+	Other uint64
+}
+
+// codeHistogram counts result codes per handler
+type codeHistogram struct {
+	mu       sync.Mutex
+	handlers map[string]*CodeCounts
+}
+
+// observe counts one result code for a handler
+func (h *codeHistogram) observe(handlerName string, code int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.handlers == nil {
+		h.handlers = make(map[string]*CodeCounts)
+	}
+	counts, ok := h.handlers[handlerName]
+	if !ok {
+		counts = &CodeCounts{Codes: make(map[int]uint64)}
+		h.handlers[handlerName] = counts
+	}
+
+	if _, tracked := counts.Codes[code]; !tracked && len(counts.Codes) >= MaxTrackedCodes {
+		counts.Other++
+		return
+	}
+	counts.Codes[code]++
+}
+
+// snapshot copies the histogram for Stats
+func (h *codeHistogram) snapshot() map[string]CodeCounts {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.handlers) == 0 {
+		return nil
+	}
+	out := make(map[string]CodeCounts, len(h.handlers))
+	for name, counts := range h.handlers {
+		codes := make(map[int]uint64, len(counts.Codes))
+		for code, n := range counts.Codes {
+			codes[code] = n
+		}
+		out[name] = CodeCounts{Codes: codes, Other: counts.Other}
+	}
+	return out
+}
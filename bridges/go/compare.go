@@ -0,0 +1,184 @@
+package pforge
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// CallSpec is one handler call to replay
+type CallSpec struct {
+	Handler string
+	Input   map[string]interface{}
+}
+
+// DiffKind classifies a difference found by CompareOutputs
+type DiffKind int
+
+const (
+	// DiffChanged means the value at Path differs
+	DiffChanged DiffKind = iota
+	// DiffAdded means Path exists only in the new output
+	DiffAdded
+	// DiffRemoved means Path exists only in the old output
+	DiffRemoved
+	// DiffError means exactly one call failed, or both failed differently
+	DiffError
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffChanged:
+		return "changed"
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffError:
+		return "error"
+	default:
+		return fmt.Sprintf("DiffKind(%d)", int(k))
+	}
+}
+
+// Diff is one field-level difference between two outputs for the same call
+type Diff struct {
+	// Index is the position of the call in the inputs passed to CompareOutputs
+	Index   int
+	Handler string
+	Kind    DiffKind
+	// Path locates the field, e.g. "$.items[2].name"; "$" for DiffError
+	Path string
+	// Old and New hold the differing values, or the errors for DiffError
+	Old interface{}
+	New interface{}
+}
+
+func (d Diff) String() string {
+	return fmt.Sprintf("%s #%d %s %s: %v -> %v", d.Handler, d.Index, d.Path, d.Kind, d.Old, d.New)
+}
+
+// CompareOption configures CompareOutputs
+type CompareOption func(*compareConfig)
+
+type compareConfig struct {
+	ignore map[string]bool
+}
+
+// IgnoreFields skips object keys with these names at any depth, for
+// volatile fields such as timestamps or request IDs
+func IgnoreFields(names ...string) CompareOption {
+	return func(c *compareConfig) {
+		for _, name := range names {
+			c.ignore[name] = true
+		}
+	}
+}
+
+// CompareOutputs runs each call against two executors, typically Bridges
+// over the old and new native library, and reports field-level differences
+// between their outputs. Key order is irrelevant. A nil result means every
+// call behaved the same. Calls run sequentially in input order.
+func CompareOutputs(oldExec, newExec Executor, inputs []CallSpec, opts ...CompareOption) []Diff {
+	cfg := compareConfig{ignore: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var diffs []Diff
+	for i, spec := range inputs {
+		oldOut, oldErr := oldExec.ExecuteHandler(spec.Handler, spec.Input)
+		newOut, newErr := newExec.ExecuteHandler(spec.Handler, spec.Input)
+		diffs = append(diffs, cfg.diff(i, spec.Handler, oldOut, oldErr, newOut, newErr)...)
+	}
+	return diffs
+}
+
+// diff compares the outcomes of one call. Failed calls match when their
+// error messages do.
+func (cfg *compareConfig) diff(index int, handlerName string, oldOut map[string]interface{}, oldErr error, newOut map[string]interface{}, newErr error) []Diff {
+	if oldErr != nil || newErr != nil {
+		if oldErr == nil || newErr == nil || oldErr.Error() != newErr.Error() {
+			return []Diff{{Index: index, Handler: handlerName, Kind: DiffError, Path: "$", Old: oldErr, New: newErr}}
+		}
+		return nil
+	}
+
+	c := differ{cfg: cfg, index: index, handler: handlerName}
+	c.compare("$", oldOut, newOut)
+	return c.diffs
+}
+
+// differ walks two decoded JSON values collecting differences
+type differ struct {
+	cfg     *compareConfig
+	index   int
+	handler string
+	diffs   []Diff
+}
+
+func (c *differ) add(kind DiffKind, path string, oldValue, newValue interface{}) {
+	c.diffs = append(c.diffs, Diff{Index: c.index, Handler: c.handler, Kind: kind, Path: path, Old: oldValue, New: newValue})
+}
+
+func (c *differ) compare(path string, oldValue, newValue interface{}) {
+	switch o := oldValue.(type) {
+	case map[string]interface{}:
+		if n, ok := newValue.(map[string]interface{}); ok {
+			c.compareObjects(path, o, n)
+			return
+		}
+	case []interface{}:
+		if n, ok := newValue.([]interface{}); ok {
+			c.compareArrays(path, o, n)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(oldValue, newValue) {
+		c.add(DiffChanged, path, oldValue, newValue)
+	}
+}
+
+func (c *differ) compareObjects(path string, oldObj, newObj map[string]interface{}) {
+	keys := make([]string, 0, len(oldObj)+len(newObj))
+	for k := range oldObj {
+		keys = append(keys, k)
+	}
+	for k := range newObj {
+		if _, ok := oldObj[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if c.cfg.ignore[k] {
+			continue
+		}
+		o, inOld := oldObj[k]
+		n, inNew := newObj[k]
+		switch {
+		case !inNew:
+			c.add(DiffRemoved, path+"."+k, o, nil)
+		case !inOld:
+			c.add(DiffAdded, path+"."+k, nil, n)
+		default:
+			c.compare(path+"."+k, o, n)
+		}
+	}
+}
+
+func (c *differ) compareArrays(path string, oldArr, newArr []interface{}) {
+	for i := 0; i < len(oldArr) || i < len(newArr); i++ {
+		item := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(newArr):
+			c.add(DiffRemoved, item, oldArr[i], nil)
+		case i >= len(oldArr):
+			c.add(DiffAdded, item, nil, newArr[i])
+		default:
+			c.compare(item, oldArr[i], newArr[i])
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package pforge
+
+import "context"
+
+// acquireHandler waits for a concurrency slot for handlerName, returning a
+// function that frees it. Handlers without a WithHandlerConcurrency limit
+// never wait; others wait in priority order (see ContextWithPriority). The
+// call is first checked against its circuit breaker (see
+// WithCircuitBreaker) and admitted, or shed with ErrOverloaded; see
+// WithAdmissionControl.
+func (b *Bridge) acquireHandler(ctx context.Context, handlerName string) (release func(), err error) {
+	if b.breakers != nil {
+		if err := b.breakers.allow(handlerName); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.admission.admit(); err != nil {
+		return nil, err
+	}
+
+	b.settings.RLock()
+	slots, ok := b.handlerLimits[handlerName]
+	b.settings.RUnlock()
+	if !ok {
+		return b.admission.done, nil
+	}
+
+	if err := slots.acquire(ctx, PriorityFromContext(ctx)); err != nil {
+		b.admission.done()
+		return nil, err
+	}
+	return func() {
+		slots.release()
+		b.admission.done()
+	}, nil
+}
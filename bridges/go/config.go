@@ -0,0 +1,290 @@
+package pforge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Config is a snapshot of the call settings that can be changed while a
+// Bridge is running: timeouts, limits and sizes, globally and per handler.
+// Read the current settings with DumpConfig and replace them with
+// ApplyConfig, for example from a JSON file per environment:
+//
+//	{
+//	  "default_timeout": "5s",
+//	  "admission_limit": 500,
+//	  "handlers": {
+//	    "search": {"timeout": "30s", "concurrency": 4},
+//	    "ping": {"timeout": "0s"}
+//	  }
+//	}
+//
+// Durations are encoded in JSON as time.ParseDuration strings. Zero means
+// the default or no limit, as for the matching options. Settings fixed at
+// construction, such as codecs, envelopes and WithAdaptiveBatching, are not
+// part of a Config.
+type Config struct {
+	// DefaultTimeout is WithDefaultTimeout
+	DefaultTimeout time.Duration
+	// AdmissionLimit is WithAdmissionControl
+	AdmissionLimit int
+	// StreamRateLimit is WithStreamRateLimit, in bytes per second
+	StreamRateLimit   int
+	StreamIdleTimeout time.Duration
+	FanOutConcurrency int
+	// BatchSize is WithBatchSize; it has no effect with adaptive batching
+	BatchSize        int
+	DuplexWindow     int
+	CloseGracePeriod time.Duration
+	// Handlers holds per-handler settings, keyed by handler name
+	Handlers map[string]HandlerConfig
+}
+
+// HandlerConfig holds one handler's settings in a Config
+type HandlerConfig struct {
+	// Timeout is the handler's WithHandlerTimeout entry. Nil leaves the
+	// handler on DefaultTimeout; zero exempts it from the default.
+	Timeout *time.Duration
+	// Concurrency is the WithHandlerConcurrency limit; zero means none
+	Concurrency int
+}
+
+// Validate reports every problem that would make ApplyConfig reject c,
+// joined in an error wrapping ErrInvalidConfig
+func (c Config) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.DefaultTimeout >= 0, "default timeout %v is negative", c.DefaultTimeout)
+	check(c.AdmissionLimit >= 0, "admission limit %d is negative", c.AdmissionLimit)
+	check(c.StreamRateLimit >= 0, "stream rate limit %d is negative", c.StreamRateLimit)
+	check(c.StreamIdleTimeout >= 0, "stream idle timeout %v is negative", c.StreamIdleTimeout)
+	check(c.FanOutConcurrency >= 0, "fan-out concurrency %d is negative", c.FanOutConcurrency)
+	check(c.BatchSize >= 0, "batch size %d is negative", c.BatchSize)
+	check(c.DuplexWindow >= 0, "duplex window %d is negative", c.DuplexWindow)
+	check(c.CloseGracePeriod >= 0, "close grace period %v is negative", c.CloseGracePeriod)
+
+	for _, name := range sortedHandlerNames(c.Handlers) {
+		handler := c.Handlers[name]
+		check(name != "", "handler settings with an empty name")
+		if handler.Timeout != nil {
+			check(*handler.Timeout >= 0, "handler %s: timeout %v is negative", name, *handler.Timeout)
+		}
+		check(handler.Concurrency >= 0, "handler %s: concurrency %d is negative", name, handler.Concurrency)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(problems...))
+	}
+	return nil
+}
+
+// ApplyConfig validates c and replaces the Bridge's settings with it. The
+// Config is the whole new state, not a patch: a handler missing from
+// c.Handlers loses its timeout and concurrency limit. Apply a modified
+// DumpConfig to change single settings.
+//
+// Calls started before ApplyConfig keep the settings they started with.
+// Calls running under a changed concurrency limit count against the new
+// one, so lowering a limit holds new calls back until enough have finished.
+// Calls waiting for a slot of a handler whose limit is removed still wait
+// for a running call to finish.
+func (b *Bridge) ApplyConfig(c Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	timeouts := make(map[string]time.Duration)
+	limits := make(map[string]*handlerSlots)
+
+	b.settings.Lock()
+	defer b.settings.Unlock()
+
+	for name, handler := range c.Handlers {
+		if handler.Timeout != nil {
+			timeouts[name] = *handler.Timeout
+		}
+		if handler.Concurrency > 0 {
+			if slots, ok := b.handlerLimits[name]; ok {
+				slots.setLimit(handler.Concurrency)
+				limits[name] = slots
+			} else {
+				limits[name] = newHandlerSlots(handler.Concurrency)
+			}
+		}
+	}
+
+	b.defaultTimeout = c.DefaultTimeout
+	b.SetAdmissionLimit(c.AdmissionLimit)
+	b.streamRateLimit = c.StreamRateLimit
+	b.streamIdleTimeout = c.StreamIdleTimeout
+	b.fanOutConcurrency = c.FanOutConcurrency
+	b.batchSize = c.BatchSize
+	b.duplexWindow = c.DuplexWindow
+	b.closeGracePeriod = c.CloseGracePeriod
+	b.handlerTimeouts = timeouts
+	b.handlerLimits = limits
+	return nil
+}
+
+// DumpConfig returns the Bridge's current settings, including those set by
+// options at construction. Passing it to ApplyConfig changes nothing.
+func (b *Bridge) DumpConfig() Config {
+	b.settings.RLock()
+	defer b.settings.RUnlock()
+
+	c := Config{
+		DefaultTimeout:    b.defaultTimeout,
+		AdmissionLimit:    int(b.admission.limit.Load()),
+		StreamRateLimit:   b.streamRateLimit,
+		StreamIdleTimeout: b.streamIdleTimeout,
+		FanOutConcurrency: b.fanOutConcurrency,
+		BatchSize:         b.batchSize,
+		DuplexWindow:      b.duplexWindow,
+		CloseGracePeriod:  b.closeGracePeriod,
+	}
+	if c.AdmissionLimit < 0 {
+		c.AdmissionLimit = 0
+	}
+
+	handler := func(name string) HandlerConfig {
+		if c.Handlers == nil {
+			c.Handlers = make(map[string]HandlerConfig)
+		}
+		return c.Handlers[name]
+	}
+	for name, timeout := range b.handlerTimeouts {
+		timeout := timeout
+		h := handler(name)
+		h.Timeout = &timeout
+		c.Handlers[name] = h
+	}
+	for name, slots := range b.handlerLimits {
+		limit, _, _ := slots.snapshot()
+		h := handler(name)
+		h.Concurrency = limit
+		c.Handlers[name] = h
+	}
+	return c
+}
+
+// configJSON is the JSON form of Config, with durations as strings
+type configJSON struct {
+	DefaultTimeout    string                       `json:"default_timeout,omitempty"`
+	AdmissionLimit    int                          `json:"admission_limit,omitempty"`
+	StreamRateLimit   int                          `json:"stream_rate_limit,omitempty"`
+	StreamIdleTimeout string                       `json:"stream_idle_timeout,omitempty"`
+	FanOutConcurrency int                          `json:"fan_out_concurrency,omitempty"`
+	BatchSize         int                          `json:"batch_size,omitempty"`
+	DuplexWindow      int                          `json:"duplex_window,omitempty"`
+	CloseGracePeriod  string                       `json:"close_grace_period,omitempty"`
+	Handlers          map[string]handlerConfigJSON `json:"handlers,omitempty"`
+}
+
+type handlerConfigJSON struct {
+	Timeout     *string `json:"timeout,omitempty"`
+	Concurrency int     `json:"concurrency,omitempty"`
+}
+
+// MarshalJSON encodes c with durations as strings such as "1m30s"
+func (c Config) MarshalJSON() ([]byte, error) {
+	out := configJSON{
+		DefaultTimeout:    formatConfigDuration(c.DefaultTimeout),
+		AdmissionLimit:    c.AdmissionLimit,
+		StreamRateLimit:   c.StreamRateLimit,
+		StreamIdleTimeout: formatConfigDuration(c.StreamIdleTimeout),
+		FanOutConcurrency: c.FanOutConcurrency,
+		BatchSize:         c.BatchSize,
+		DuplexWindow:      c.DuplexWindow,
+		CloseGracePeriod:  formatConfigDuration(c.CloseGracePeriod),
+	}
+	if len(c.Handlers) > 0 {
+		out.Handlers = make(map[string]handlerConfigJSON, len(c.Handlers))
+		for name, handler := range c.Handlers {
+			var h handlerConfigJSON
+			if handler.Timeout != nil {
+				timeout := handler.Timeout.String()
+				h.Timeout = &timeout
+			}
+			h.Concurrency = handler.Concurrency
+			out.Handlers[name] = h
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes the form written by MarshalJSON. Unknown fields are
+// an error, so a misspelled setting is not silently ignored.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var in configJSON
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	var decoded Config
+	var err error
+	parse := func(field, s string) time.Duration {
+		if s == "" || err != nil {
+			return 0
+		}
+		var d time.Duration
+		d, err = time.ParseDuration(s)
+		if err != nil {
+			err = fmt.Errorf("%w: %s: %w", ErrInvalidConfig, field, err)
+		}
+		return d
+	}
+
+	decoded.DefaultTimeout = parse("default_timeout", in.DefaultTimeout)
+	decoded.AdmissionLimit = in.AdmissionLimit
+	decoded.StreamRateLimit = in.StreamRateLimit
+	decoded.StreamIdleTimeout = parse("stream_idle_timeout", in.StreamIdleTimeout)
+	decoded.FanOutConcurrency = in.FanOutConcurrency
+	decoded.BatchSize = in.BatchSize
+	decoded.DuplexWindow = in.DuplexWindow
+	decoded.CloseGracePeriod = parse("close_grace_period", in.CloseGracePeriod)
+	if len(in.Handlers) > 0 {
+		decoded.Handlers = make(map[string]HandlerConfig, len(in.Handlers))
+		for name, h := range in.Handlers {
+			handler := HandlerConfig{Concurrency: h.Concurrency}
+			if h.Timeout != nil {
+				timeout := parse("handlers."+name+".timeout", *h.Timeout)
+				handler.Timeout = &timeout
+			}
+			decoded.Handlers[name] = handler
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	*c = decoded
+	return nil
+}
+
+// formatConfigDuration leaves zero durations out of the JSON form
+func formatConfigDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+func sortedHandlerNames(handlers map[string]HandlerConfig) []string {
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,143 @@
+package pforge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// callOutcome carries an FFI result back from the calling goroutine
+type callOutcome struct {
+	output map[string]interface{}
+	err    error
+}
+
+// ExecuteHandlerContext calls a pforge handler, returning an error wrapping
+// ErrCallerCancelled or ErrCallerDeadlineExceeded if ctx is done before the
+// handler completes.
+//
+// When ctx has a deadline, the remaining duration is injected into the input
+// as DeadlineField so cooperative handlers can self-limit. This is advisory:
+// the native call itself is not interrupted, and only handlers that read the
+// field stop early.
+//
+// A seed attached with ContextWithSeed is injected as SeedField, a
+// projection attached with ContextWithProjection as ProjectionField, and a
+// key attached with ContextWithIdempotencyKey as IdempotencyKeyField. A
+// locale attached with ContextWithLocale replaces the WithLocale one.
+//
+// A CallHandle attached with NewCallHandle can cancel the call from
+// another goroutine.
+//
+// Without a deadline on ctx, the handler's default timeout applies; see
+// WithHandlerTimeout.
+//
+// The call is logged to the context's logger (see ContextWithLogger), or
+// the Bridge logger if the context has none.
+func (b *Bridge) ExecuteHandlerContext(ctx context.Context, handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	defer releaseCallHandle(ctx)
+
+	start := time.Now()
+	output, err := b.observe(b.executeHandlerContext(ctx, handlerName, input))
+	b.finishCall(ctx, "handler call", handlerName, start, err)
+	b.audit(ctx, "handler call", handlerName, start, input, output, err)
+	return output, err
+}
+
+func (b *Bridge) executeHandlerContext(ctx context.Context, handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	return b.callContext(ctx, handlerName, func(ctx context.Context) (map[string]interface{}, error) {
+		var err error
+		if deadline, ok := ctx.Deadline(); ok {
+			if input, err = b.reserveFields(handlerName, input, DeadlineField); err != nil {
+				return nil, err
+			}
+			input = withField(input, DeadlineField, time.Until(deadline).Milliseconds())
+		}
+		if seed, ok := SeedFromContext(ctx); ok {
+			if input, err = b.reserveFields(handlerName, input, SeedField); err != nil {
+				return nil, err
+			}
+			input = withField(input, SeedField, seed)
+		}
+		if fields, ok := ProjectionFromContext(ctx); ok {
+			if input, err = b.reserveFields(handlerName, input, ProjectionField); err != nil {
+				return nil, err
+			}
+			input = withField(input, ProjectionField, fields)
+		}
+		if key, ok := IdempotencyKeyFromContext(ctx); ok {
+			if input, err = b.reserveFields(handlerName, input, IdempotencyKeyField); err != nil {
+				return nil, err
+			}
+			input = withField(input, IdempotencyKeyField, key)
+		}
+
+		inputJSON, err := b.marshalInputLocale(handlerName, input, b.callLocale(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return b.call(entryExecute, handlerName, inputJSON)
+	})
+}
+
+// callContext runs a native call on its own goroutine once the handler's
+// concurrency slot is free, returning early if ctx is done first
+func (b *Bridge) callContext(ctx context.Context, handlerName string, run func(ctx context.Context) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, contextError(ctx)
+	}
+	ctx, cancelTimeout := b.handlerTimeout(ctx, handlerName)
+	defer cancelTimeout()
+	ctx, cancel := b.scope(ctx)
+	defer cancel()
+
+	release, err := b.acquireHandler(ctx, handlerName)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan callOutcome, 1)
+	go func() {
+		defer release()
+		var outcome callOutcome
+		func() {
+			defer b.recoverPanic(handlerName, &outcome.err)
+			outcome.output, outcome.err = run(ctx)
+		}()
+		done <- outcome
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	case outcome := <-done:
+		return outcome.output, outcome.err
+	}
+}
+
+// contextError explains why ctx ended. Cancellations caused by the Bridge
+// itself (CallHandle, Close) return their own error. Otherwise the error
+// wraps ErrCallerCancelled or ErrCallerDeadlineExceeded along with the
+// context error and any custom cause, so errors.Is matches all of them.
+// Errors that match neither came from the handler or the Bridge.
+func contextError(ctx context.Context) error {
+	err := ctx.Err()
+	if err == nil {
+		return nil
+	}
+
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrCallCancelled) || errors.Is(cause, ErrBridgeClosed) {
+		return cause
+	}
+
+	kind := ErrCallerCancelled
+	if errors.Is(err, context.DeadlineExceeded) {
+		kind = ErrCallerDeadlineExceeded
+	}
+	if cause == err {
+		return fmt.Errorf("%w: %w", kind, err)
+	}
+	return fmt.Errorf("%w: %w: %w", kind, err, cause)
+}
@@ -0,0 +1,93 @@
+package pforge
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer coalesces rapid calls to one handler, such as a search fired on
+// every keystroke: a call waits for the window to pass, and a newer call
+// within the window replaces it. Only the last call of a burst runs. Create
+// one with Bridge.Debounce; it is safe for concurrent use.
+type Debouncer struct {
+	bridge      *Bridge
+	handlerName string
+	window      time.Duration
+
+	mu      sync.Mutex
+	calls   uint64
+	pending *debouncedCall
+}
+
+// debouncedCall is a call waiting for its window to pass
+type debouncedCall struct {
+	id     uint64
+	input  map[string]interface{}
+	result chan Result
+	timer  *time.Timer
+}
+
+// Debounce returns a Debouncer calling handlerName once its calls have been
+// quiet for window
+func (b *Bridge) Debounce(handlerName string, window time.Duration) *Debouncer {
+	return &Debouncer{bridge: b, handlerName: handlerName, window: window}
+}
+
+// Call schedules a call with input and returns a channel that receives its
+// Result once and is then closed. The handler runs window after the latest
+// Call; if another Call comes first, this one receives ErrSuperseded
+// instead. Result.ID numbers the calls from 1.
+//
+// A call that has started is not superseded, so calls further apart than
+// the window may run concurrently and finish in any order.
+func (d *Debouncer) Call(input map[string]interface{}) <-chan Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.drop(ErrSuperseded)
+	d.calls++
+	call := &debouncedCall{
+		id:     d.calls,
+		input:  input,
+		result: make(chan Result, 1),
+	}
+	call.timer = time.AfterFunc(d.window, func() { d.fire(call) })
+	d.pending = call
+	return call.result
+}
+
+// Stop drops the waiting call, if any, delivering ErrCallCancelled to it.
+// A call that has already started still runs.
+func (d *Debouncer) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.drop(ErrCallCancelled)
+}
+
+// drop fails the waiting call with err. d.mu must be held.
+func (d *Debouncer) drop(err error) {
+	call := d.pending
+	if call == nil {
+		return
+	}
+	d.pending = nil
+	// A timer that already fired finds pending changed and does nothing
+	call.timer.Stop()
+	call.result <- Result{ID: call.id, Err: err}
+	close(call.result)
+}
+
+// fire runs call when its window has passed, unless it was dropped
+func (d *Debouncer) fire(call *debouncedCall) {
+	d.mu.Lock()
+	if d.pending != call {
+		d.mu.Unlock()
+		return
+	}
+	d.pending = nil
+	d.mu.Unlock()
+
+	output, err := d.bridge.ExecuteHandler(d.handlerName, call.input)
+	call.result <- Result{ID: call.id, Output: output, Err: err}
+	close(call.result)
+}
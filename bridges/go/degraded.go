@@ -0,0 +1,36 @@
+package pforge
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Available reports whether the Bridge has a usable native library: false
+// when it is running degraded (see WithDegradedMode) or has been closed
+func (b *Bridge) Available() bool {
+	return b.unavailable == nil && !b.life.closed.Load()
+}
+
+// degrade handles a failure to load the native library. Without
+// WithDegradedMode it releases the Bridge and returns err; otherwise it
+// marks the Bridge unavailable and returns it.
+func (b *Bridge) degrade(err error) (*Bridge, error) {
+	b.unload()
+	if !b.degradedMode {
+		return nil, err
+	}
+
+	b.unavailable = fmt.Errorf("%w: %v", ErrBridgeUnavailable, err)
+	// Unlike per-call lines this warning must not be lost, so without
+	// WithLogger it goes to the default logger
+	logger := b.log
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.LogAttrs(context.Background(), slog.LevelWarn,
+		"pforge native library unavailable, running degraded: every handler call will fail",
+		slog.String("error", err.Error()),
+	)
+	return b, nil
+}
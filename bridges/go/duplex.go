@@ -0,0 +1,249 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// DuplexMaxInFlight is the default duplex window: how many inputs may await
+// a result at once. Change it per Bridge with WithDuplexWindow.
+const DuplexMaxInFlight = 64
+
+// Result is a single handler result delivered on a channel, by a duplex
+// stream or a Debouncer
+type Result struct {
+	// ID identifies the input that produced this result: its correlation ID
+	// on a duplex stream, its call number for a Debouncer
+	ID uint64
+	// Output is the decoded handler result
+	Output map[string]interface{}
+	// Err is set when the handler failed for this input
+	Err error
+}
+
+// duplexStream tracks one open native duplex stream
+type duplexStream struct {
+	bridge      *Bridge
+	handlerName string
+	syms        *C.PforgeSymbols
+	stream      unsafe.Pointer
+	send        chan map[string]interface{}
+	results     chan Result
+	credits     chan struct{}
+	recvEnd     chan struct{}
+}
+
+// ExecuteHandlerDuplex opens a bidirectional stream to a handler. Inputs
+// written to send are forwarded as they arrive and results are delivered on
+// recv as the handler produces them, possibly out of order.
+//
+// Each input is assigned a correlation ID, starting at 1 and increasing in
+// send order, which the native side echoes back in CorrelationField.
+//
+// Flow control is credit based. The window is the smaller of the Bridge's
+// duplex window and the input window the native side advertises through
+// pforge_duplex_window. Each send consumes a credit and each result returns
+// one, so sends block once the window is full and neither side buffers more
+// than window messages, whichever side is slower.
+//
+// Close send to finish the stream; recv is closed once all results are
+// delivered. Cancelling ctx aborts the stream, so callers should select on
+// ctx.Done() when sending. Close also aborts the stream if it is still open
+// after the grace period; recv is then closed early.
+func (b *Bridge) ExecuteHandlerDuplex(ctx context.Context, handlerName string) (send chan<- map[string]interface{}, recv <-chan Result, err error) {
+	if err := b.enter(); err != nil {
+		return nil, nil, err
+	}
+	if !b.supports(FeatureDuplex) {
+		b.leave()
+		return nil, nil, fmt.Errorf("%w: duplex streaming", ErrNotSupported)
+	}
+	if b.thread != nil {
+		// Send and receive block concurrently, so they cannot share one thread
+		b.leave()
+		return nil, nil, fmt.Errorf("%w: duplex streaming with WithLockedThread", ErrNotSupported)
+	}
+
+	cHandlerName := C.CString(handlerName)
+	syms := b.symbols()
+	stream := C.pforge_call_duplex_open(syms, cHandlerName)
+	C.free(unsafe.Pointer(cHandlerName))
+	if stream == nil {
+		b.leave()
+		return nil, nil, fmt.Errorf("failed to open duplex stream for handler %s", handlerName)
+	}
+
+	b.settings.RLock()
+	window := b.duplexWindow
+	b.settings.RUnlock()
+	if window < 1 {
+		window = DuplexMaxInFlight
+	}
+	if b.supports(FeatureDuplexWindow) {
+		if advertised := int(C.pforge_call_duplex_window(syms, stream)); advertised > 0 && advertised < window {
+			window = advertised
+		}
+	}
+
+	d := &duplexStream{
+		bridge:      b,
+		handlerName: handlerName,
+		syms:        syms,
+		stream:      stream,
+		send:        make(chan map[string]interface{}),
+		results:     make(chan Result, window),
+		credits:     make(chan struct{}, window),
+		recvEnd:     make(chan struct{}),
+	}
+
+	ctx, cancel := b.scope(ctx)
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		d.sendLoop(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		d.recvLoop(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		select {
+		case <-ctx.Done():
+			C.pforge_call_duplex_cancel(d.syms, d.stream)
+		case <-d.recvEnd:
+		}
+	}()
+	start := time.Now()
+	go func() {
+		wg.Wait()
+		C.pforge_call_duplex_free(d.syms, d.stream)
+		close(d.results)
+		b.finishStream(ctx, "duplex stream", handlerName, start, context.Cause(ctx))
+		b.audit(ctx, "duplex stream", handlerName, start, nil, nil, context.Cause(ctx))
+		cancel()
+		releaseCallHandle(ctx)
+		b.leave()
+	}()
+
+	return d.send, d.results, nil
+}
+
+// sendLoop forwards caller inputs to the native stream until send is closed
+func (d *duplexStream) sendLoop(ctx context.Context) {
+	defer C.pforge_call_duplex_close(d.syms, d.stream)
+
+	var id uint64
+	for {
+		var input map[string]interface{}
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-d.send:
+			if !ok {
+				return
+			}
+			input = msg
+		}
+
+		// Block until the window has a free credit
+		select {
+		case d.credits <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		id++
+		input, err := d.bridge.reserveFields(d.handlerName, input, CorrelationField)
+		var payload []byte
+		if err == nil {
+			payload, err = d.bridge.marshalInput(d.handlerName, withField(input, CorrelationField, id))
+		}
+		if err == nil {
+			payload, err = d.bridge.wireInput(d.handlerName, payload)
+		}
+		if err != nil {
+			d.release()
+			d.deliver(ctx, Result{ID: id, Err: err})
+			continue
+		}
+
+		rc := C.pforge_call_duplex_send(
+			d.syms,
+			d.stream,
+			(*C.uchar)(unsafe.Pointer(&payload[0])),
+			C.size_t(len(payload)),
+		)
+		if rc != 0 {
+			d.release()
+			d.deliver(ctx, Result{ID: id, Err: fmt.Errorf("duplex send failed with code %d", rc)})
+			continue
+		}
+		d.bridge.stats.bytesIn.Add(uint64(len(payload)))
+	}
+}
+
+// recvLoop delivers native results until the stream signals its end
+func (d *duplexStream) recvLoop(ctx context.Context) {
+	defer close(d.recvEnd)
+
+	for {
+		result := C.pforge_call_duplex_recv(d.syms, d.stream)
+		if result.code == 0 && result.data == nil {
+			C.pforge_call_free_result(d.syms, result)
+			return
+		}
+
+		id := correlationID(d.bridge.wireCodec(d.handlerName), result)
+		d.bridge.stats.codes.observe(d.handlerName, int(result.code))
+		output, err := d.bridge.observe(d.bridge.decodeResult(d.handlerName, result))
+		C.pforge_call_free_result(d.syms, result)
+		if output != nil {
+			delete(output, CorrelationField)
+		}
+
+		d.release()
+		d.deliver(ctx, Result{ID: id, Output: output, Err: err})
+	}
+}
+
+// deliver hands a result to the caller unless the stream was cancelled
+func (d *duplexStream) deliver(ctx context.Context, r Result) {
+	select {
+	case d.results <- r:
+	case <-ctx.Done():
+	}
+}
+
+// release returns a credit to the window
+func (d *duplexStream) release() {
+	select {
+	case <-d.credits:
+	default:
+	}
+}
+
+// correlationID reads the correlation ID from a native result without copying it.
+// Failed results may still carry the ID in their data.
+func correlationID(codec JSONCodec, result C.FfiResult) uint64 {
+	if result.data == nil || result.data_len == 0 {
+		return 0
+	}
+
+	data := unsafe.Slice((*byte)(unsafe.Pointer(result.data)), int(result.data_len))
+	var envelope struct {
+		ID uint64 `json:"_correlation_id"`
+	}
+	if err := codec.Unmarshal(data, &envelope); err != nil {
+		return 0
+	}
+	return envelope.ID
+}
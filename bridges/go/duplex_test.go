@@ -0,0 +1,85 @@
+package pforge
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDuplexFlowControl runs duplex streams where one side is much slower
+// than the other and checks neither side ever holds more than the window
+func TestDuplexFlowControl(t *testing.T) {
+	const inputs = 40
+
+	tests := []struct {
+		name    string
+		handler string
+		// readDelay slows the Go consumer of results
+		readDelay time.Duration
+	}{
+		{name: "slow native consumer", handler: "slow"},
+		{name: "slow Go consumer", handler: "echo", readDelay: 2 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The stub advertises a window of 4, below the Bridge's 8
+			const window = 4
+			b := newStubBridge(t, nil, WithDuplexWindow(8))
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			send, recv, err := b.ExecuteHandlerDuplex(ctx, tt.handler)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var sent, received atomic.Int64
+			go func() {
+				defer close(send)
+				for i := 0; i < inputs; i++ {
+					select {
+					case send <- map[string]interface{}{"n": i}:
+						sent.Add(1)
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			seen := make(map[uint64]bool)
+			var maxQueued float64
+			for result := range recv {
+				if result.Err != nil {
+					t.Fatalf("result %d: %v", result.ID, result.Err)
+				}
+				queued, _ := result.Output["_queued"].(float64)
+				if queued > window {
+					t.Errorf("native side held %v inputs, window is %d", queued, window)
+				}
+				maxQueued = max(maxQueued, queued)
+				// Up to window inputs wait on the native side and window
+				// results wait in recv, plus one result being delivered and
+				// one input counted as sent while it waits for a credit
+				if inFlight := sent.Load() - received.Load(); inFlight > 2*window+2 {
+					t.Errorf("%d inputs in flight, window is %d", inFlight, window)
+				}
+				seen[result.ID] = true
+				received.Add(1)
+				time.Sleep(tt.readDelay)
+			}
+
+			if tt.readDelay == 0 && maxQueued < window {
+				t.Errorf("native side held at most %v inputs; the producer never filled the window", maxQueued)
+			}
+			if len(seen) != inputs {
+				t.Fatalf("got %d distinct results, want %d", len(seen), inputs)
+			}
+			for id := uint64(1); id <= inputs; id++ {
+				if !seen[id] {
+					t.Errorf("no result for correlation ID %d", id)
+				}
+			}
+		})
+	}
+}
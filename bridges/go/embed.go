@@ -0,0 +1,91 @@
+package pforge
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// NewBridgeFromFS loads a native library embedded in fsys, typically an
+// embed.FS, for self-contained binaries. The library is written to a
+// private temp file (mode 0600, in a fresh 0700 directory), its SHA-256 is
+// checked against the embedded bytes to catch a truncated or altered
+// write, and it is then loaded as with NewBridgeWithLibrary, including the
+// WithLibraryChecksum check against the expected digest. The temp file
+// is removed by Close, or right away if loading fails. With
+// WithDegradedMode, failing to extract the library also degrades the
+// Bridge rather than failing.
+func NewBridgeFromFS(fsys fs.FS, name string, opts ...Option) (*Bridge, error) {
+	b := newBridge(opts...)
+
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return b.degrade(fmt.Errorf("failed to read embedded library: %w", err))
+	}
+
+	dir, err := os.MkdirTemp("", "pforge-")
+	if err != nil {
+		return b.degrade(fmt.Errorf("failed to extract embedded library: %w", err))
+	}
+	libPath, err := extractLibrary(dir, path.Base(name), data)
+	if err != nil {
+		os.RemoveAll(dir)
+		return b.degrade(err)
+	}
+
+	if err := b.load(libPath); err != nil {
+		os.RemoveAll(dir)
+		return b.degrade(err)
+	}
+	b.extractDir = dir
+	return b, nil
+}
+
+// extractLibrary writes data to dir/name and verifies the written file
+func extractLibrary(dir, name string, data []byte) (string, error) {
+	libPath := filepath.Join(dir, name)
+	f, err := os.OpenFile(libPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract embedded library: %w", err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to extract embedded library: %w", err)
+	}
+
+	want := sha256.Sum256(data)
+	got, err := fileSHA256(libPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify extracted library: %w", err)
+	}
+	if !bytes.Equal(got, want[:]) {
+		return "", fmt.Errorf("extracted library %s does not match the embedded copy", libPath)
+	}
+	return libPath, nil
+}
+
+// fileSHA256 returns the SHA-256 digest of a file's contents
+func fileSHA256(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,110 @@
+package pforge
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Reserved envelope fields injected into handler input by the Bridge
+const (
+	// DeadlineField carries the caller's remaining time budget in milliseconds
+	DeadlineField = "_deadline_ms"
+	// CorrelationField pairs duplex stream inputs with their results
+	CorrelationField = "_correlation_id"
+	// ClientField identifies the calling service as {"name", "version"}
+	ClientField = "_client"
+	// SchemaVersionField requests a result schema version in the input, and
+	// reports the version the handler used in its result
+	SchemaVersionField = "_schema_version"
+	// SeedField carries an RNG seed set with ContextWithSeed, and is echoed
+	// in the result by handlers that honored it
+	SeedField = "_seed"
+	// ProjectionField lists the result fields requested with
+	// ContextWithProjection, and is echoed in the result by handlers that
+	// honored it
+	ProjectionField = "_fields"
+	// IdempotencyKeyField carries a key set with ContextWithIdempotencyKey
+	// so handlers can deduplicate retried requests
+	IdempotencyKeyField = "_idempotency_key"
+	// DuplicateField is set to true in the result by a handler that
+	// recognized the idempotency key of an earlier call
+	DuplicateField = "_duplicate"
+	// LocaleField carries the BCP 47 tag set with WithLocale or
+	// ContextWithLocale, and reports the locale the handler used in its
+	// result
+	LocaleField = "_locale"
+)
+
+// ResultSchemaVersion returns the SchemaVersionField a handler reported in
+// its result. Compare it with the version requested through
+// WithResultSchemaVersion to detect a handler that answered with an older
+// shape. ok is false if the handler did not report a version.
+func ResultSchemaVersion(output map[string]interface{}) (version int, ok bool) {
+	v, ok := output[SchemaVersionField].(float64)
+	if !ok || v != float64(int(v)) {
+		return 0, false
+	}
+	return int(v), true
+}
+
+// withField returns a shallow copy of input with key set to value,
+// leaving the caller's map untouched
+func withField(input map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	envelope := make(map[string]interface{}, len(input)+1)
+	for k, v := range input {
+		envelope[k] = v
+	}
+	envelope[key] = value
+	return envelope
+}
+
+// encodeField pre-encodes a single "key":value pair for spliceFields
+func encodeField(key string, value interface{}) ([]byte, error) {
+	k, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(k, ':'), v...), nil
+}
+
+// appendField adds a pre-encoded field to a list of pre-encoded fields
+func appendField(fields, field []byte) []byte {
+	if len(fields) == 0 {
+		return field
+	}
+	return append(append(fields, ','), field...)
+}
+
+// jsonSpace is the whitespace JSON allows between tokens
+const jsonSpace = " \t\r\n"
+
+// spliceFields inserts pre-encoded fields at the start of a serialized JSON
+// object, avoiding a copy of the input map on every call. A null input
+// (from a nil map) becomes an object holding just the fields; any other
+// value that is not an object fails with an InputError rather than being
+// dropped.
+func spliceFields(object, fields []byte) ([]byte, error) {
+	object = bytes.TrimLeft(object, jsonSpace)
+	if bytes.Equal(bytes.TrimRight(object, jsonSpace), []byte("null")) {
+		out := make([]byte, 0, len(fields)+2)
+		out = append(out, '{')
+		out = append(out, fields...)
+		return append(out, '}'), nil
+	}
+	if len(object) == 0 || object[0] != '{' {
+		return nil, &InputError{Path: "$", Message: "input must encode to a JSON object"}
+	}
+
+	rest := bytes.TrimLeft(object[1:], jsonSpace)
+	out := make([]byte, 0, len(rest)+len(fields)+2)
+	out = append(out, '{')
+	out = append(out, fields...)
+	if len(rest) > 0 && rest[0] != '}' {
+		out = append(out, ',')
+	}
+	return append(out, rest...), nil
+}
@@ -0,0 +1,39 @@
+package pforge
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSpliceFields(t *testing.T) {
+	field := []byte(`"_f":1`)
+	tests := []struct {
+		name   string
+		object string
+		want   string
+	}{
+		{name: "object", object: `{"a":2}`, want: `{"_f":1,"a":2}`},
+		{name: "empty object", object: `{}`, want: `{"_f":1}`},
+		{name: "leading whitespace", object: " \n\t{\"a\":2}", want: `{"_f":1,"a":2}`},
+		{name: "padded empty object", object: `{ }`, want: `{"_f":1}`},
+		{name: "null", object: `null`, want: `{"_f":1}`},
+		{name: "padded null", object: " null\n", want: `{"_f":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := spliceFields([]byte(tt.object), field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("spliceFields(%q) = %s, want %s", tt.object, got, tt.want)
+			}
+		})
+	}
+
+	for _, object := range []string{``, `[1,2]`, `"s"`, `42`} {
+		if got, err := spliceFields([]byte(object), field); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("spliceFields(%q) = %s, %v; want ErrInvalidInput", object, got, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package pforge
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors shared by the FFI bridge and the exec adapter.
+// Use errors.Is to classify failures returned by either.
+var (
+	// ErrNotSupported is returned when the native library lacks an optional entry point
+	ErrNotSupported = errors.New("operation not supported by native library")
+	// ErrHandlerNotFound is returned when no handler is registered under the name
+	ErrHandlerNotFound = errors.New("handler not found")
+	// ErrBadInput is returned when the handler rejected its input
+	ErrBadInput = errors.New("bad handler input")
+	// ErrHandlerFailed is returned when the handler ran and reported an error
+	ErrHandlerFailed = errors.New("handler failed")
+	// ErrHandlerPanic is returned when the handler panicked
+	ErrHandlerPanic = errors.New("handler panicked")
+	// ErrResultTooLarge is returned when a subprocess handler writes more
+	// output than the exec adapter accepts
+	ErrResultTooLarge = errors.New("handler result too large")
+	// ErrBridgeClosed is returned for calls made after Close, and for
+	// in-flight calls that Close stopped waiting for
+	ErrBridgeClosed = errors.New("bridge closed")
+	// ErrCallCancelled is returned when a call is cancelled through its CallHandle
+	ErrCallCancelled = errors.New("call cancelled")
+	// ErrCallerCancelled is returned when the caller's context was cancelled.
+	// The error also wraps context.Canceled.
+	ErrCallerCancelled = errors.New("caller cancelled")
+	// ErrCallerDeadlineExceeded is returned when the caller's context deadline
+	// passed. The error also wraps context.DeadlineExceeded.
+	ErrCallerDeadlineExceeded = errors.New("caller deadline exceeded")
+	// ErrMissingResultField is returned by ExecuteHandlerRequire when a
+	// required result key is absent or null
+	ErrMissingResultField = errors.New("missing result field")
+	// ErrInvalidInput is returned, as an *InputError naming the path, when
+	// handler input cannot be serialized or fails the strict UTF-8 check
+	ErrInvalidInput = errors.New("invalid handler input")
+	// ErrNoCommonCodec is returned when the Bridge and the native library
+	// support no codec in common
+	ErrNoCommonCodec = errors.New("no codec supported by both bridge and native library")
+	// ErrReservedField is returned with WithStrictReservedFields when input
+	// sets a field the Bridge injects, such as DeadlineField
+	ErrReservedField = errors.New("input sets a reserved field")
+
+	// ErrBridgeUnavailable is returned by every call on a Bridge running
+	// without its native library; see WithDegradedMode
+	ErrBridgeUnavailable = errors.New("native library unavailable")
+
+	// ErrOverloaded is returned when a call is shed by admission control;
+	// see WithAdmissionControl
+	ErrOverloaded = errors.New("bridge overloaded")
+
+	// ErrStreamIdle is returned when a stream goes quiet for longer than
+	// WithStreamIdleTimeout
+	ErrStreamIdle = errors.New("stream idle timeout")
+
+	// ErrInvalidConfig is returned by ApplyConfig and Config.Validate for a
+	// Config that cannot be applied
+	ErrInvalidConfig = errors.New("invalid bridge config")
+
+	// ErrABIMismatch is returned when the native library reports an FFI ABI
+	// version other than ABIVersion
+	ErrABIMismatch = errors.New("native library ABI version mismatch")
+
+	// ErrInputTooLarge is returned by ExecuteHandlerFromRequest when the
+	// request body exceeds WithRequestBodyLimit; HTTP adapters map it to
+	// 413 Request Entity Too Large
+	ErrInputTooLarge = errors.New("handler input too large")
+
+	// ErrSuperseded is delivered by a Debouncer to a call replaced by a
+	// later one within the window
+	ErrSuperseded = errors.New("call superseded")
+
+	// ErrChecksumMismatch is returned when a native library's SHA-256 does
+	// not match the one set with WithLibraryChecksum
+	ErrChecksumMismatch = errors.New("native library checksum mismatch")
+
+	// ErrInvalidLocale is returned for a locale that is not a well-formed
+	// BCP 47 language tag
+	ErrInvalidLocale = errors.New("invalid locale tag")
+
+	// ErrCircuitOpen is returned without calling the handler while its
+	// circuit breaker is open (see WithCircuitBreaker)
+	ErrCircuitOpen = errors.New("circuit breaker open")
+)
+
+// Native result codes reported in FfiResult.code, as defined by the
+// pforge-bridge crate
+const (
+	CodeOK            = 0
+	CodeNullPointer   = -1
+	CodeInvalidName   = -2
+	CodeSerialization = -3
+	CodeInvalidInput  = -4
+	CodeHandlerPanic  = -5
+
+	// CodeKeepalive is returned by pforge_stream_next, with no data, by a
+	// streaming handler that is still working but has nothing to send yet
+	CodeKeepalive = 1
+)
+
+// HandlerError describes a failed handler call. Code is the native result
+// code for FFI calls or the exit status for subprocess handlers.
+type HandlerError struct {
+	Code    int
+	Message string
+	kind    error
+}
+
+func newHandlerError(code int, message string, kind error) *HandlerError {
+	return &HandlerError{Code: code, Message: message, kind: kind}
+}
+
+func (e *HandlerError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("handler execution failed with code %d", e.Code)
+	}
+	return fmt.Sprintf("handler execution failed (code %d): %s", e.Code, e.Message)
+}
+
+// Unwrap returns the sentinel error classifying the failure
+func (e *HandlerError) Unwrap() error {
+	return e.kind
+}
+
+// nativeErrorKind maps a native result code to its sentinel error
+func nativeErrorKind(code int) error {
+	switch code {
+	case CodeNullPointer, CodeInvalidName, CodeInvalidInput:
+		return ErrBadInput
+	case CodeHandlerPanic:
+		return ErrHandlerPanic
+	default:
+		return ErrHandlerFailed
+	}
+}
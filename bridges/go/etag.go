@@ -0,0 +1,53 @@
+package pforge
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ExecuteHandlerWithETag calls a handler like ExecuteHandler and also
+// returns an HTTP entity tag for its result, for caching handler output
+// behind an HTTP endpoint. etag is empty when the call fails.
+func (b *Bridge) ExecuteHandlerWithETag(handlerName string, input map[string]interface{}) (output map[string]interface{}, etag string, err error) {
+	output, err = b.ExecuteHandler(handlerName, input)
+	if err != nil {
+		return nil, "", err
+	}
+	etag, err = ETag(output)
+	if err != nil {
+		return nil, "", err
+	}
+	return output, etag, nil
+}
+
+// ETag returns a strong HTTP entity tag for a handler result: the quoted
+// HashInput digest of its canonical JSON encoding. Results with the same
+// JSON content get the same tag whatever their key order or Go types, so
+// it is stable across calls and processes.
+func ETag(output any) (string, error) {
+	hash, err := HashInput(output)
+	if err != nil {
+		return "", err
+	}
+	return `"` + hash + `"`, nil
+}
+
+// ETagMatches reports whether r's If-None-Match header matches etag, in
+// which case an HTTP adapter should reply 304 Not Modified without a body.
+// It uses the weak comparison RFC 9110 requires for If-None-Match, so a
+// tag the client has as W/"..." still matches, and "*" matches any tag.
+func ETagMatches(r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, header := range r.Header.Values("If-None-Match") {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
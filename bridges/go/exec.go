@@ -0,0 +1,239 @@
+package pforge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"time"
+
+	"example/pforgehandler"
+)
+
+// DefaultExecMaxOutput is the stdout limit used when ExecHandler.MaxOutputBytes is zero
+const DefaultExecMaxOutput = 16 << 20
+
+const (
+	// stderrTailSize is how much trailing stderr is kept for error messages
+	stderrTailSize = 2048
+	// execWaitDelay bounds how long a handler has to exit after it is
+	// interrupted before it is killed, and how long Wait keeps reading its
+	// pipes after it exited, e.g. when a grandchild holds them open
+	execWaitDelay = time.Second
+)
+
+// ExecHandler runs subprocess handlers built with pforgehandler, starting
+// one process per call. Exit codes are mapped to the same sentinel errors
+// the FFI bridge returns.
+type ExecHandler struct {
+	// Binaries maps handler names to executable paths
+	Binaries map[string]string
+	// MaxOutputBytes caps the stdout captured per call; a handler writing
+	// more is killed and the call fails with ErrResultTooLarge.
+	// Zero means DefaultExecMaxOutput.
+	MaxOutputBytes int64
+	// Stderr, if set, receives each handler's stderr as it is written, for
+	// following handler logs live. The tail is still kept for error
+	// messages, and stdout stays reserved for the result. Writes from
+	// concurrent calls interleave, so the writer must be safe for
+	// concurrent use; a slow writer stalls handlers that log heavily, and
+	// after a write error the rest of that call's stderr is dropped.
+	Stderr io.Writer
+}
+
+// NewExecHandler creates an exec adapter for the given handler binaries
+func NewExecHandler(binaries map[string]string) *ExecHandler {
+	return &ExecHandler{Binaries: binaries}
+}
+
+// ExecuteHandler runs a subprocess handler with JSON input
+func (h *ExecHandler) ExecuteHandler(handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	return h.ExecuteHandlerContext(context.Background(), handlerName, input)
+}
+
+// ExecuteHandlerContext runs a subprocess handler, interrupting it if ctx is
+// done so it can stop cleanly and killing it if it has not exited a second
+// later. Reading its output stops by then even if the handler left its pipes
+// open. Failures include the tail of the handler's stderr.
+func (h *ExecHandler) ExecuteHandlerContext(ctx context.Context, handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	path, ok := h.Binaries[handlerName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrHandlerNotFound, handlerName)
+	}
+
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := h.MaxOutputBytes
+	if limit <= 0 {
+		limit = DefaultExecMaxOutput
+	}
+	stdout := &cappedBuffer{limit: limit, overflow: cancel}
+	stderr := &tailBuffer{size: stderrTailSize}
+
+	cmd := exec.CommandContext(runCtx, path)
+	cmd.Stdin = bytes.NewReader(inputJSON)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderrWriter(stderr, h.Stderr)
+	cmd.Cancel = interruptProcess(cmd)
+	cmd.WaitDelay = execWaitDelay
+
+	err = cmd.Run()
+	if stdout.exceeded {
+		return nil, fmt.Errorf("%w: %s wrote more than %d bytes", ErrResultTooLarge, handlerName, limit)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, contextError(ctx)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, execError(exitErr.ExitCode(), stdout.Bytes(), stderr.String())
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s: %v", ErrHandlerNotFound, handlerName, err)
+		}
+		return nil, fmt.Errorf("failed to run handler %s: %w%s", handlerName, err, stderrSuffix(stderr.String()))
+	}
+
+	var output map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal result: %w%s", err, stderrSuffix(stderr.String()))
+	}
+
+	return output, nil
+}
+
+// interruptProcess is a Cancel func for cmd that sends os.Interrupt, so a
+// handler watching for it can stop cleanly, and falls back to killing it
+// where interrupts are not supported. cmd.WaitDelay kills it if it lingers.
+func interruptProcess(cmd *exec.Cmd) func() error {
+	return func() error {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+}
+
+// execError builds a HandlerError from a subprocess exit status, the
+// {"error": "..."} envelope it wrote to stdout and the tail of its stderr
+func execError(code int, stdout []byte, stderr string) error {
+	var envelope struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(stdout, &envelope)
+
+	message := envelope.Error
+	if stderr != "" {
+		if message != "" {
+			message += " "
+		}
+		message += "(stderr: " + stderr + ")"
+	}
+	return newHandlerError(code, message, exitErrorKind(code))
+}
+
+// stderrSuffix formats a stderr tail for appending to an error message
+func stderrSuffix(stderr string) string {
+	if stderr == "" {
+		return ""
+	}
+	return " (stderr: " + stderr + ")"
+}
+
+// cappedBuffer collects output up to limit bytes. Past the limit it calls
+// overflow once and discards the rest, so the writer is never blocked.
+type cappedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	exceeded bool
+	overflow func()
+}
+
+func (c *cappedBuffer) Write(p []byte) (int, error) {
+	if c.exceeded {
+		return len(p), nil
+	}
+	if int64(c.buf.Len()+len(p)) > c.limit {
+		c.exceeded = true
+		c.overflow()
+		return len(p), nil
+	}
+	return c.buf.Write(p)
+}
+
+func (c *cappedBuffer) Bytes() []byte {
+	return c.buf.Bytes()
+}
+
+// tailBuffer keeps the last size bytes written to it
+type tailBuffer struct {
+	buf       []byte
+	size      int
+	truncated bool
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.size; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+		t.truncated = true
+	}
+	return len(p), nil
+}
+
+// String returns the trimmed tail, marked with "..." if earlier output was dropped
+func (t *tailBuffer) String() string {
+	tail := string(bytes.TrimSpace(t.buf))
+	if t.truncated && tail != "" {
+		return "..." + tail
+	}
+	return tail
+}
+
+// stderrWriter sends a handler's stderr to tail and, if set, to sink
+func stderrWriter(tail *tailBuffer, sink io.Writer) io.Writer {
+	if sink == nil {
+		return tail
+	}
+	return io.MultiWriter(tail, &stderrSink{w: sink})
+}
+
+// stderrSink forwards stderr to a caller's writer. It swallows write
+// errors, dropping later output, so a failing sink never fails the call.
+type stderrSink struct {
+	w      io.Writer
+	failed bool
+}
+
+func (s *stderrSink) Write(p []byte) (int, error) {
+	if !s.failed {
+		if _, err := s.w.Write(p); err != nil {
+			s.failed = true
+		}
+	}
+	return len(p), nil
+}
+
+// exitErrorKind maps a pforgehandler exit code to its sentinel error
+func exitErrorKind(code int) error {
+	switch code {
+	case pforgehandler.ExitBadInput:
+		return ErrBadInput
+	case pforgehandler.ExitPanic:
+		return ErrHandlerPanic
+	default:
+		return ErrHandlerFailed
+	}
+}
@@ -0,0 +1,110 @@
+package pforge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+
+	"example/pforgehandler"
+)
+
+// ExecuteHandlerNDJSONFunc runs a subprocess handler built with
+// pforgehandler.ServeStreamResponse and calls fn for each record as the
+// handler writes it, using up to concurrency workers, like the Bridge
+// method of the same name. fn may be called concurrently and in any order.
+//
+// MaxOutputBytes caps each record rather than the whole output. The first
+// error from fn interrupts the handler, as cancelling ctx does, and is
+// returned. A handler that fails after writing records reports its error in
+// a trailer (see pforgehandler.StreamErrorField), returned as a HandlerError
+// once the records before it have been passed to fn.
+func (h *ExecHandler) ExecuteHandlerNDJSONFunc(ctx context.Context, handlerName string, input map[string]interface{}, concurrency int, fn func(record json.RawMessage) error) error {
+	path, ok := h.Binaries[handlerName]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrHandlerNotFound, handlerName)
+	}
+
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := h.MaxOutputBytes
+	if limit <= 0 {
+		limit = DefaultExecMaxOutput
+	}
+	stderr := &tailBuffer{size: stderrTailSize}
+
+	cmd := exec.CommandContext(runCtx, path)
+	cmd.Stdin = bytes.NewReader(inputJSON)
+	cmd.Stderr = stderrWriter(stderr, h.Stderr)
+	cmd.Cancel = interruptProcess(cmd)
+	cmd.WaitDelay = execWaitDelay
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to run handler %s: %w", handlerName, err)
+	}
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %s: %v", ErrHandlerNotFound, handlerName, err)
+		}
+		return fmt.Errorf("failed to run handler %s: %w", handlerName, err)
+	}
+
+	var trailer *pforgehandler.StreamError
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, int(limit))
+	next := func() (json.RawMessage, error) {
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			if streamErr, ok := pforgehandler.IsStreamError(line); ok {
+				trailer = &streamErr
+				return nil, io.EOF
+			}
+			return append(json.RawMessage(nil), line...), nil
+		}
+		if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+			return nil, fmt.Errorf("%w: %s wrote a record over %d bytes", ErrResultTooLarge, handlerName, limit)
+		}
+		if scanner.Err() != nil {
+			return nil, scanner.Err()
+		}
+		return nil, io.EOF
+	}
+
+	// A failure cancels runCtx, stopping the handler so Wait does not block
+	// on output nobody reads
+	err = dispatchRecords(runCtx, cancel, concurrency, next, fn)
+	waitErr := cmd.Wait()
+
+	switch {
+	case ctx.Err() != nil:
+		return contextError(ctx)
+	case err != nil:
+		return err
+	case trailer != nil:
+		envelope, _ := json.Marshal(trailer)
+		return execError(trailer.Status, envelope, stderr.String())
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) {
+		return execError(exitErr.ExitCode(), nil, stderr.String())
+	}
+	if waitErr != nil {
+		return fmt.Errorf("failed to run handler %s: %w%s", handlerName, waitErr, stderrSuffix(stderr.String()))
+	}
+	return nil
+}
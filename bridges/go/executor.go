@@ -0,0 +1,88 @@
+package pforge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Executor runs handlers by name. Bridge, ExecHandler, ExecPool and
+// InMemoryExecutor all implement it, so pipelines and middleware can be
+// written once and exercised against any of them.
+type Executor interface {
+	ExecuteHandler(handlerName string, input map[string]interface{}) (map[string]interface{}, error)
+	ExecuteHandlerContext(ctx context.Context, handlerName string, input map[string]interface{}) (map[string]interface{}, error)
+}
+
+var (
+	_ Executor = (*Bridge)(nil)
+	_ Executor = (*ExecHandler)(nil)
+	_ Executor = (*ExecPool)(nil)
+	_ Executor = (*InMemoryExecutor)(nil)
+)
+
+// InMemoryExecutor dispatches calls to Go functions registered by name,
+// for testing handler composition without FFI or subprocesses.
+//
+// Inputs and results are round-tripped through JSON so functions see the
+// same shapes a native handler would (numbers as float64, and so on).
+type InMemoryExecutor struct {
+	handlers map[string]func(map[string]interface{}) (map[string]interface{}, error)
+}
+
+// NewInMemoryExecutor creates an executor over the given handler functions
+func NewInMemoryExecutor(handlers map[string]func(map[string]interface{}) (map[string]interface{}, error)) *InMemoryExecutor {
+	return &InMemoryExecutor{handlers: handlers}
+}
+
+// ExecuteHandler calls the function registered under handlerName
+func (e *InMemoryExecutor) ExecuteHandler(handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	return e.ExecuteHandlerContext(context.Background(), handlerName, input)
+}
+
+// ExecuteHandlerContext calls the function registered under handlerName
+// unless ctx is already done
+func (e *InMemoryExecutor) ExecuteHandlerContext(ctx context.Context, handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	fn, ok := e.handlers[handlerName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrHandlerNotFound, handlerName)
+	}
+
+	decoded, err := roundTrip(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	output, err := fn(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := roundTrip(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal result: %w", err)
+	}
+	if result == nil {
+		result = make(map[string]interface{})
+	}
+
+	return result, nil
+}
+
+// roundTrip passes a map through JSON encoding and decoding
+func roundTrip(m map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package pforge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultFanOutConcurrency is how many handlers FanOut calls at once
+const DefaultFanOutConcurrency = 8
+
+// FanOutError collects the handlers that failed in a FanOut call
+type FanOutError struct {
+	// Errors holds each failed handler's error, keyed by handler name
+	Errors map[string]error
+}
+
+func (e *FanOutError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s: %v", name, e.Errors[name])
+	}
+	return fmt.Sprintf("fan-out: %d failed: %s", len(names), strings.Join(parts, "; "))
+}
+
+// Unwrap returns the handlers' errors, so errors.Is matches any of them
+func (e *FanOutError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// FanOut calls each handler with the same input concurrently and returns
+// the successful results keyed by handler name. If any handler fails, the
+// error is a *FanOutError holding each failure, and the results still hold
+// the handlers that succeeded. A handler named twice is called once.
+//
+// At most WithFanOutConcurrency handlers (default DefaultFanOutConcurrency)
+// run at once. Calls are ExecuteHandlerContext calls, so cancelling ctx
+// stops those in flight, and handlers not yet started fail with the
+// context error. A ctx from errgroup.WithContext therefore stops the
+// fan-out when another goroutine of the group fails.
+//
+// With WithFanOutCancelOnFirstError, FanOut behaves like an errgroup: the
+// first failure cancels the calls still in flight or waiting, and the
+// *FanOutError holds only that first failure, not the cancellations it
+// caused. The results hold the handlers that succeeded before it.
+func (b *Bridge) FanOut(ctx context.Context, input map[string]interface{}, handlers ...string) (map[string]map[string]interface{}, error) {
+	b.settings.RLock()
+	limit := b.fanOutConcurrency
+	b.settings.RUnlock()
+	if limit < 1 {
+		limit = DefaultFanOutConcurrency
+	}
+	sem := make(chan struct{}, limit)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]map[string]interface{}, len(handlers))
+		errs    = make(map[string]error)
+		seen    = make(map[string]bool, len(handlers))
+	)
+	for _, handlerName := range handlers {
+		if seen[handlerName] {
+			continue
+		}
+		seen[handlerName] = true
+
+		wg.Add(1)
+		go func(handlerName string) {
+			defer wg.Done()
+
+			output, err := b.fanOutCall(ctx, sem, handlerName, input)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if !b.fanOutCancelOnError {
+					errs[handlerName] = err
+				} else if len(errs) == 0 {
+					errs[handlerName] = err
+					cancel()
+				}
+				return
+			}
+			results[handlerName] = output
+		}(handlerName)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return results, &FanOutError{Errors: errs}
+	}
+	return results, nil
+}
+
+// fanOutCall calls one FanOut handler once a slot in sem is free
+func (b *Bridge) fanOutCall(ctx context.Context, sem chan struct{}, handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	}
+	defer func() { <-sem }()
+
+	if ctx.Err() != nil {
+		return nil, contextError(ctx)
+	}
+	return b.ExecuteHandlerContext(ctx, handlerName, input)
+}
@@ -0,0 +1,47 @@
+package pforge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// HashInput returns the hex SHA-256 of input's canonical JSON encoding, a
+// stable content hash for cache, dedup and idempotency keys. The Bridge
+// uses the same scheme for audit hashes, so keys computed by callers match
+// AuditEvent.InputHash.
+//
+// The canonical encoding is compact JSON with every object's keys sorted
+// and numbers as encoding/json formats a float64, so a map and a struct
+// with the same JSON content hash alike, and json.RawMessage hashes like
+// the value it holds. For example, both {"b": 1, "a": "x"} and its struct
+// equivalent hash the bytes {"a":"x","b":1}:
+//
+//	cdab067e9f3beb32d1252cfd63e492592fecbf591b0d08cadb24bb17f3864246
+//
+// Integers beyond ±2^53 lose precision in this encoding and may collide.
+func HashInput(input any) (string, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash input: %w", err)
+	}
+
+	// Struct fields encode in declaration order, so re-encode the decoded
+	// value to sort every object's keys
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return "", fmt.Errorf("failed to hash input: %w", err)
+	}
+	canonical, err := json.Marshal(decoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash input: %w", err)
+	}
+	return hashBytes(canonical), nil
+}
+
+// hashBytes returns the hex SHA-256 of data
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package pforge
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestHashInputVectors pins HashInput to fixed digests, each the SHA-256
+// of the canonical encoding shown, so any change to the scheme is caught
+func TestHashInputVectors(t *testing.T) {
+	type pair struct {
+		B int    `json:"b"`
+		A string `json:"a"`
+	}
+
+	tests := []struct {
+		name  string
+		input any
+		// canonical is the encoding that is hashed
+		canonical string
+		want      string
+	}{
+		{
+			name:      "map",
+			input:     map[string]interface{}{"b": 1, "a": "x"},
+			canonical: `{"a":"x","b":1}`,
+			want:      "cdab067e9f3beb32d1252cfd63e492592fecbf591b0d08cadb24bb17f3864246",
+		},
+		{
+			name:      "struct",
+			input:     pair{B: 1, A: "x"},
+			canonical: `{"a":"x","b":1}`,
+			want:      "cdab067e9f3beb32d1252cfd63e492592fecbf591b0d08cadb24bb17f3864246",
+		},
+		{
+			name:      "raw message",
+			input:     json.RawMessage(`{ "b": 1.0, "a": "x" }`),
+			canonical: `{"a":"x","b":1}`,
+			want:      "cdab067e9f3beb32d1252cfd63e492592fecbf591b0d08cadb24bb17f3864246",
+		},
+		{
+			name:      "empty object",
+			input:     map[string]interface{}{},
+			canonical: `{}`,
+			want:      "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+		},
+		{
+			name:      "nil",
+			input:     nil,
+			canonical: `null`,
+			want:      "74234e98afe7498fb5daf1f36ac2d78acc339464f950703b8c019892f982b90b",
+		},
+		{
+			name: "nested",
+			input: map[string]interface{}{
+				"nested": map[string]interface{}{"z": nil, "y": true},
+				"list":   []int{3, 1, 2},
+			},
+			canonical: `{"list":[3,1,2],"nested":{"y":true,"z":null}}`,
+			want:      "176d7fc51865ff06125304b382d3d24bf3bdfbeab138e5e7820497652790b309",
+		},
+		{
+			name:      "numbers",
+			input:     map[string]interface{}{"n": 0.5, "big": int64(1e10)},
+			canonical: `{"big":10000000000,"n":0.5}`,
+			want:      "8ce6ce7fa09f7cf7bea7d781731cdaa6763144e87b3cc47808d1f3e5fab93e2f",
+		},
+		{
+			name:      "html escaping",
+			input:     map[string]interface{}{"s": "café <tag>"},
+			canonical: `{"s":"café \u003ctag\u003e"}`,
+			want:      "f4d17daa08cd76a1582f1602083120b1f66423a827666cf1dcf798c555eb7582",
+		},
+		{
+			name:      "array",
+			input:     []interface{}{1, "two", map[string]interface{}{"a": []string{}}},
+			canonical: `[1,"two",{"a":[]}]`,
+			want:      "9c3470d8664ef052d31c59cb0a6f1ed86f4e4e08c95b751a242c3550d4650232",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HashInput(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("HashInput = %s, want %s", got, tt.want)
+			}
+			if canonical := hashBytes([]byte(tt.canonical)); canonical != tt.want {
+				t.Errorf("vector is not the hash of %s: %s", tt.canonical, canonical)
+			}
+		})
+	}
+}
+
+func TestHashInputError(t *testing.T) {
+	if _, err := HashInput(map[string]interface{}{"f": func() {}}); err == nil {
+		t.Fatal("HashInput of a func succeeded")
+	}
+}
@@ -0,0 +1,66 @@
+package pforge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultRequestBodyLimit is the request body limit used by
+// ExecuteHandlerFromRequest without WithRequestBodyLimit
+const DefaultRequestBodyLimit = 1 << 20
+
+// ExecuteHandlerFromRequest calls a handler with the JSON object in the body
+// of r, for HTTP endpoints backed by a handler. The call uses r.Context(),
+// as ExecuteHandlerContext, so it stops when the client goes away.
+//
+// The body is read through http.MaxBytesReader, up to WithRequestBodyLimit
+// bytes (default DefaultRequestBodyLimit); a larger body fails with an error
+// wrapping ErrInputTooLarge and *http.MaxBytesError. A body that is not a
+// single JSON object fails with an *InputError. Numbers are decoded as
+// json.Number, so they reach the handler unchanged. The Content-Type header
+// is not checked.
+func (b *Bridge) ExecuteHandlerFromRequest(r *http.Request, handlerName string) (map[string]interface{}, error) {
+	input, err := b.readRequestInput(r)
+	if err != nil {
+		return nil, err
+	}
+	return b.ExecuteHandlerContext(r.Context(), handlerName, input)
+}
+
+// readRequestInput reads and decodes the body of r as handler input
+func (b *Bridge) readRequestInput(r *http.Request) (map[string]interface{}, error) {
+	if r.Body == nil {
+		return nil, &InputError{Path: "$", Message: "request has no body"}
+	}
+
+	limit := b.requestBodyLimit
+	if limit <= 0 {
+		limit = DefaultRequestBodyLimit
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("%w: request body over %d bytes: %w", ErrInputTooLarge, limit, tooLarge)
+		}
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var input map[string]interface{}
+	if err := dec.Decode(&input); err != nil {
+		return nil, &InputError{Path: "$", Message: fmt.Sprintf("request body is not a JSON object: %v", err)}
+	}
+	if input == nil {
+		return nil, &InputError{Path: "$", Message: "request body is null"}
+	}
+	if dec.More() {
+		return nil, &InputError{Path: "$", Message: "request body has data after the JSON object"}
+	}
+	return input, nil
+}
@@ -0,0 +1,50 @@
+package pforge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"example/pforgectx"
+)
+
+// ContextWithIdempotencyKey returns a copy of ctx that makes
+// ExecuteHandlerContext and PreparedCall.Execute add key to the input as
+// IdempotencyKeyField, so a side-effecting handler can recognize a retried
+// request and skip repeating its effect. Retrying with the same ctx sends
+// the same key, so a retry loop around either method reuses it
+// automatically; use a fresh key (see NewIdempotencyKey) for each logical
+// operation. An empty key leaves ctx unchanged.
+//
+// Deduplication is handler-dependent: only handlers that read
+// IdempotencyKeyField and remember the keys they have seen provide it, and
+// for how long they remember is up to them. A handler that recognized a
+// duplicate reports it in its result; see ResultDuplicate.
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return pforgectx.WithIdempotencyKey(ctx, key)
+}
+
+// IdempotencyKeyFromContext returns the key stored by
+// ContextWithIdempotencyKey
+func IdempotencyKeyFromContext(ctx context.Context) (key string, ok bool) {
+	return pforgectx.IdempotencyKey(ctx)
+}
+
+// NewIdempotencyKey returns a random 128-bit key in hex
+func NewIdempotencyKey() string {
+	var key [16]byte
+	rand.Read(key[:])
+	return hex.EncodeToString(key[:])
+}
+
+// ResultDuplicate reports whether a handler flagged its result with
+// DuplicateField, meaning it recognized the idempotency key of an earlier
+// call and did not repeat the side effect. False also means the handler
+// does not report duplicates.
+func ResultDuplicate(output map[string]interface{}) bool {
+	duplicate, _ := output[DuplicateField].(bool)
+	return duplicate
+}
@@ -0,0 +1,89 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// idleTimer cancels a native stream that delivers neither data nor a
+// keepalive within the idle timeout. It only runs while next is waiting on
+// the handler, so rate limiting and a slow reader never count as idle.
+type idleTimer struct {
+	stream  *nativeStream
+	timeout time.Duration
+
+	mu    sync.Mutex
+	timer *time.Timer
+	// gen tells the current timer apart from ones disarmed after their
+	// callback had already started
+	gen uint64
+	// fired is set once the timer has cancelled the stream; stopped once
+	// the stream is about to be freed and must not be touched
+	fired   bool
+	stopped bool
+}
+
+// newIdleTimer returns a disarmed timer for s, or nil if timeout is not
+// positive
+func newIdleTimer(s *nativeStream, timeout time.Duration) *idleTimer {
+	if timeout <= 0 {
+		return nil
+	}
+	return &idleTimer{stream: s, timeout: timeout}
+}
+
+// arm starts the timeout before waiting on the handler
+func (t *idleTimer) arm() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fired || t.stopped {
+		return
+	}
+	t.gen++
+	gen := t.gen
+	t.timer = time.AfterFunc(t.timeout, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.stopped || t.gen != gen {
+			return
+		}
+		t.fired = true
+		// Called directly like the context watcher, to unblock next
+		C.pforge_call_stream_cancel(t.stream.syms, t.stream.handle)
+	})
+}
+
+// disarm stops the timeout once the handler has shown signs of life
+func (t *idleTimer) disarm() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.gen++
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// err reports the idle timeout if it ended the stream
+func (t *idleTimer) err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.fired {
+		return nil
+	}
+	return fmt.Errorf("%w: no data or keepalive for %v", ErrStreamIdle, t.timeout)
+}
+
+// stop disarms the timer before the stream is freed
+func (t *idleTimer) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
@@ -0,0 +1,97 @@
+package pforge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestStreamKeepalive checks keepalives sent during a pause longer than
+// the idle timeout keep the stream alive without being delivered, while a
+// handler that goes silent for as long fails with ErrStreamIdle
+func TestStreamKeepalive(t *testing.T) {
+	b := newStubBridge(t, nil, WithStreamIdleTimeout(50*time.Millisecond))
+
+	t.Run("keepalives", func(t *testing.T) {
+		start := time.Now()
+		chunks, err := b.ExecuteHandlerStream(context.Background(), "pause", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data []string
+		for chunk := range chunks {
+			if chunk.Err != nil {
+				t.Fatalf("stream failed after %v: %v", time.Since(start), chunk.Err)
+			}
+			data = append(data, string(chunk.Data))
+		}
+
+		// The pause alone is twice the idle timeout
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("stream ended after %v, before the pause", elapsed)
+		}
+		if len(data) != 3 {
+			t.Fatalf("got %d chunks %q, want the 3 data chunks only", len(data), data)
+		}
+	})
+
+	t.Run("silence", func(t *testing.T) {
+		chunks, err := b.ExecuteHandlerStream(context.Background(), "stall", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var last StreamChunk
+		for chunk := range chunks {
+			last = chunk
+		}
+		if !errors.Is(last.Err, ErrStreamIdle) {
+			t.Fatalf("stream ended with %v, want ErrStreamIdle", last.Err)
+		}
+	})
+}
+
+// TestStreamIdleWhileWaiting checks the idle timeout only counts time spent
+// waiting on the handler, so a stream paced by a rate limit or read slowly
+// never fails with ErrStreamIdle
+func TestStreamIdleWhileWaiting(t *testing.T) {
+	const timeout = 30 * time.Millisecond
+
+	t.Run("rate limit", func(t *testing.T) {
+		// Each 1000-byte "bulk" chunk is paced to 50ms, longer than the timeout
+		b := newStubBridge(t, nil, WithStreamIdleTimeout(timeout), WithStreamRateLimit(20000))
+		chunks, err := b.ExecuteHandlerStream(context.Background(), "bulk", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for chunk := range chunks {
+			if chunk.Err != nil {
+				t.Fatalf("stream failed after %d bytes: %v", n, chunk.Err)
+			}
+			n += len(chunk.Data)
+		}
+		if n != 10000 {
+			t.Fatalf("streamed %d bytes, want 10000", n)
+		}
+	})
+
+	t.Run("slow reader", func(t *testing.T) {
+		b := newStubBridge(t, nil, WithStreamIdleTimeout(timeout))
+		chunks, err := b.ExecuteHandlerStream(context.Background(), "echo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for chunk := range chunks {
+			if chunk.Err != nil {
+				t.Fatalf("stream failed after %d chunks: %v", n, chunk.Err)
+			}
+			n++
+			time.Sleep(3 * timeout)
+		}
+		if n != 3 {
+			t.Fatalf("got %d chunks, want 3", n)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package pforge
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// InputFrom converts a struct (or pointer to struct) into a handler input map.
+//
+// Field names are taken from the `pforge:"name"` tag, then the `json:"name"`
+// tag, then the Go field name. Either tag may use "-" to skip a field and
+// ",omitempty" to drop zero values; a pforge tag overrides the json tag
+// entirely, so `pforge:"-"` hides a field that JSON serialization keeps.
+//
+// Embedded structs without a name in their tag are flattened into the parent,
+// with the parent's own fields winning on name conflicts. Embedded structs
+// with a tag name, and all other nested structs, become nested maps using the
+// same rules. Types implementing json.Marshaler or encoding.TextMarshaler,
+// such as time.Time and netip.Addr, are passed through unchanged.
+func InputFrom(v any) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("InputFrom: nil %s", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("InputFrom: expected struct, got %s", rv.Type())
+	}
+
+	return structToMap(rv), nil
+}
+
+// structToMap converts a struct value field by field
+func structToMap(rv reflect.Value) map[string]interface{} {
+	out := make(map[string]interface{})
+	flattenInto(out, rv)
+	return out
+}
+
+// flattenInto adds a struct's fields to out. Fields already present were set
+// by a shallower struct and are not overwritten.
+func flattenInto(out map[string]interface{}, rv reflect.Value) {
+	rt := rv.Type()
+	var embedded []reflect.Value
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, omitEmpty, skip := fieldName(field)
+		if skip {
+			continue
+		}
+
+		value := rv.Field(i)
+		if field.Anonymous && name == "" {
+			if inner, ok := embeddedStruct(value); ok {
+				embedded = append(embedded, inner)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if omitEmpty && value.IsZero() {
+			continue
+		}
+		out[name] = toInputValue(value)
+	}
+
+	for _, inner := range embedded {
+		fields := make(map[string]interface{})
+		flattenInto(fields, inner)
+		for name, value := range fields {
+			if _, exists := out[name]; !exists {
+				out[name] = value
+			}
+		}
+	}
+}
+
+// fieldName resolves a field's input name from its pforge or json tag
+func fieldName(field reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag, ok := field.Tag.Lookup("pforge")
+	if !ok {
+		tag, ok = field.Tag.Lookup("json")
+	}
+	if !ok {
+		return "", false, false
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, strings.Contains(","+opts+",", ",omitempty,"), false
+}
+
+// embeddedStruct dereferences an embedded field if it is a struct
+func embeddedStruct(value reflect.Value) (reflect.Value, bool) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return reflect.Value{}, false
+		}
+		value = value.Elem()
+	}
+	return value, value.Kind() == reflect.Struct
+}
+
+// toInputValue converts nested values, recursing into structs, slices and
+// maps. Values that marshal themselves are left for the JSON encoder.
+func toInputValue(value reflect.Value) interface{} {
+	if value.Type().Implements(jsonMarshalerType) || value.Type().Implements(textMarshalerType) {
+		return value.Interface()
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return toInputValue(value.Elem())
+	case reflect.Struct:
+		return structToMap(value)
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface()
+		}
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			items[i] = toInputValue(value.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if value.IsNil() {
+			return nil
+		}
+		if value.Type().Key().Kind() != reflect.String {
+			return value.Interface()
+		}
+		m := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = toInputValue(iter.Value())
+		}
+		return m
+	default:
+		return value.Interface()
+	}
+}
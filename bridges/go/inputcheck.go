@@ -0,0 +1,133 @@
+package pforge
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// InputError locates a value in handler input that cannot cross the FFI
+type InputError struct {
+	// Path locates the offending value, e.g. "$.items[2].name"
+	Path    string
+	Message string
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("%v at %s: %s", ErrInvalidInput, e.Path, e.Message)
+}
+
+// Unwrap returns ErrInvalidInput
+func (e *InputError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// checkInput walks input looking for values JSON cannot encode, and for
+// invalid UTF-8 in strings and keys when strictUTF8 is set. It returns the
+// first problem in a deterministic order, or nil.
+func checkInput(input map[string]interface{}, strictUTF8 bool) *InputError {
+	return checkValue(reflect.ValueOf(input), "$", strictUTF8)
+}
+
+func checkValue(v reflect.Value, path string, strictUTF8 bool) *InputError {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		// Custom encodings are opaque; the marshal error is reported as is
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return checkValue(v.Elem(), path, strictUTF8)
+	case reflect.String:
+		if strictUTF8 && !utf8.ValidString(v.String()) {
+			return &InputError{Path: path, Message: "invalid UTF-8 in string"}
+		}
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return &InputError{Path: path, Message: fmt.Sprintf("unsupported number %v", f)}
+		}
+	case reflect.Map:
+		return checkMap(v, path, strictUTF8)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := checkValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), strictUTF8); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		return checkStruct(v, path, strictUTF8)
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return &InputError{Path: path, Message: fmt.Sprintf("unsupported type %s", v.Type())}
+	}
+	return nil
+}
+
+func checkMap(v reflect.Value, path string, strictUTF8 bool) *InputError {
+	if v.Type().Key().Kind() != reflect.String {
+		// Integer and TextMarshaler keys are encoded by encoding/json
+		for _, key := range v.MapKeys() {
+			if err := checkValue(v.MapIndex(key), fmt.Sprintf("%s[%v]", path, key), strictUTF8); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	keys := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child := path + "." + key
+		if strictUTF8 && !utf8.ValidString(key) {
+			return &InputError{Path: child, Message: "invalid UTF-8 in key"}
+		}
+		if err := checkValue(v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key())), child, strictUTF8); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkStruct(v reflect.Value, path string, strictUTF8 bool) *InputError {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tagName, _, _ := strings.Cut(tag, ","); tagName != "" {
+				name = tagName
+			}
+		}
+
+		child := path + "." + name
+		if field.Anonymous {
+			child = path
+		}
+		if err := checkValue(v.Field(i), child, strictUTF8); err != nil {
+			return err
+		}
+	}
+	return nil
+}
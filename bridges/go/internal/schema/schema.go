@@ -0,0 +1,205 @@
+// Package schema implements the subset of JSON Schema used by pforge
+// handler schemas: type, properties, required, items, enum, and numeric
+// and string length bounds.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"unicode/utf8"
+)
+
+// Schema is a decoded JSON Schema document
+type Schema struct {
+	Type       interface{}        `json:"type,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Enum       []interface{}      `json:"enum,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Maximum    *float64           `json:"maximum,omitempty"`
+	MinLength  *int               `json:"minLength,omitempty"`
+	MaxLength  *int               `json:"maxLength,omitempty"`
+	Default    interface{}        `json:"default,omitempty"`
+}
+
+// ValidationError reports the first place a value violates its schema
+type ValidationError struct {
+	// Path locates the offending value, e.g. "$.items[2].name"
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Parse decodes a JSON Schema document
+func Parse(data json.RawMessage) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &s, nil
+}
+
+// Validate checks a value decoded from JSON against a schema document
+func Validate(data json.RawMessage, value interface{}) error {
+	s, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	return s.Validate(value)
+}
+
+// Validate checks a value decoded from JSON against the schema
+func (s *Schema) Validate(value interface{}) error {
+	return s.validate("$", value)
+}
+
+// Types returns the allowed type names, which may be given as a string or a list
+func (s *Schema) Types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			if name, ok := v.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+func (s *Schema) validate(path string, value interface{}) error {
+	if types := s.Types(); len(types) > 0 && !matchesAny(types, value) {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("expected %v, got %s", s.Type, typeName(value))}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("value %v not in enum %v", value, s.Enum)}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+			}
+		}
+		for _, name := range s.propertyNames() {
+			if field, ok := v[name]; ok {
+				if err := s.Properties[name].validate(path+"."+name, field); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("%v is less than minimum %v", v, *s.Minimum)}
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("%v is greater than maximum %v", v, *s.Maximum)}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("length %d is less than minLength %d", length, *s.MinLength)}
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("length %d is greater than maxLength %d", length, *s.MaxLength)}
+		}
+	}
+
+	return nil
+}
+
+// propertyNames returns declared property names in sorted order,
+// so the reported violation is deterministic
+func (s *Schema) propertyNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func matchesAny(types []string, value interface{}) bool {
+	for _, t := range types {
+		if matches(t, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return true
+	}
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, candidate := range enum {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,105 @@
+package schema
+
+// Skeleton builds a starting value for the schema: declared defaults are
+// copied in, and required properties without a default get a placeholder
+// of their type (the first enum value, the minimum for numbers, or the
+// zero value). Optional properties are included only when they, or
+// properties nested inside them, have defaults.
+func (s *Schema) Skeleton() interface{} {
+	value, _ := s.skeleton(true)
+	return value
+}
+
+// skeleton returns the value for the schema and whether it carries any
+// default. A placeholder is produced only when required is set.
+func (s *Schema) skeleton(required bool) (interface{}, bool) {
+	if s.Default != nil {
+		return copyValue(s.Default), true
+	}
+
+	types := s.Types()
+	if len(types) == 0 && s.Properties != nil {
+		types = []string{"object"}
+	}
+
+	if len(types) > 0 && types[0] == "object" {
+		object := make(map[string]interface{})
+		hasDefaults := false
+		for _, name := range s.propertyNames() {
+			value, ok := s.Properties[name].skeleton(s.requires(name))
+			if ok {
+				object[name] = value
+				hasDefaults = true
+			} else if s.requires(name) {
+				object[name] = value
+			}
+		}
+		if !required && !hasDefaults {
+			return nil, false
+		}
+		// Required properties may lack a declared schema
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				object[name] = nil
+			}
+		}
+		return object, hasDefaults
+	}
+
+	if !required {
+		return nil, false
+	}
+	if len(s.Enum) > 0 {
+		return copyValue(s.Enum[0]), false
+	}
+	if len(types) == 0 {
+		return nil, false
+	}
+
+	switch types[0] {
+	case "array":
+		return []interface{}{}, false
+	case "string":
+		return "", false
+	case "number", "integer":
+		if s.Minimum != nil {
+			return *s.Minimum, false
+		}
+		return float64(0), false
+	case "boolean":
+		return false, false
+	default:
+		return nil, false
+	}
+}
+
+// requires reports whether name is a required property
+func (s *Schema) requires(name string) bool {
+	for _, r := range s.Required {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// copyValue deep-copies a value decoded from JSON, so skeletons built from
+// a cached schema never share maps or slices with it
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = copyValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = copyValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
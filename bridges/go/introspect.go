@@ -0,0 +1,289 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"unsafe"
+
+	"example/internal/schema"
+)
+
+// DefaultSchemaCacheSize is the number of handler schemas cached by default
+const DefaultSchemaCacheSize = 128
+
+// HandlerInfo describes a handler registered with the native library
+type HandlerInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// HandlerOrder selects how ListHandlers orders its result
+type HandlerOrder int
+
+const (
+	// HandlerOrderName sorts handlers by name (the default)
+	HandlerOrderName HandlerOrder = iota
+	// HandlerOrderDescription sorts handlers by description, then name
+	HandlerOrderDescription
+	// HandlerOrderNative keeps the order reported by the native library
+	HandlerOrderNative
+)
+
+// ListHandlers returns the handlers registered with the native library,
+// sorted by name unless WithHandlerOrder selects another order
+func (b *Bridge) ListHandlers() ([]HandlerInfo, error) {
+	if err := b.enter(); err != nil {
+		return nil, err
+	}
+	defer b.leave()
+
+	if !b.supports(FeatureListHandlers) {
+		return nil, fmt.Errorf("%w: handler listing", ErrNotSupported)
+	}
+
+	syms := b.symbols()
+	var data []byte
+	var err error
+	b.native(func() {
+		result := C.pforge_call_list_handlers(syms)
+		data, err = resultData(result)
+		C.pforge_call_free_result(syms, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return []HandlerInfo{}, nil
+	}
+
+	var handlers []HandlerInfo
+	if err := b.jsonCodec.Unmarshal(data, &handlers); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal handler list: %w", err)
+	}
+
+	sortHandlers(handlers, b.handlerOrder)
+	return handlers, nil
+}
+
+// ListHandlersMatching returns the handlers whose names match the glob
+// pattern, in ListHandlers order. The syntax is that of path.Match: "*"
+// matches any run of characters other than "/", "?" any single one, and
+// "[a-z]" a class, so "hash.*" selects "hash.sha256" and "hash.md5". An
+// invalid pattern fails with an error wrapping path.ErrBadPattern before
+// the native library is asked.
+func (b *Bridge) ListHandlersMatching(pattern string) ([]HandlerInfo, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid handler pattern %q: %w", pattern, err)
+	}
+	return b.filterHandlers(func(name string) bool {
+		match, _ := path.Match(pattern, name)
+		return match
+	})
+}
+
+// ListHandlersMatchingRegexp is ListHandlersMatching with a regular
+// expression in regexp (RE2) syntax. It is unanchored, so "^hash\." is
+// needed to match only names starting with "hash.".
+func (b *Bridge) ListHandlersMatchingRegexp(expr string) ([]HandlerInfo, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid handler pattern: %w", err)
+	}
+	return b.filterHandlers(re.MatchString)
+}
+
+// filterHandlers returns the handlers whose names satisfy match
+func (b *Bridge) filterHandlers(match func(name string) bool) ([]HandlerInfo, error) {
+	handlers, err := b.ListHandlers()
+	if err != nil {
+		return nil, err
+	}
+
+	matched := handlers[:0]
+	for _, handler := range handlers {
+		if match(handler.Name) {
+			matched = append(matched, handler)
+		}
+	}
+	return matched, nil
+}
+
+// sortHandlers orders a handler list in place
+func sortHandlers(handlers []HandlerInfo, order HandlerOrder) {
+	sort.SliceStable(handlers, func(i, j int) bool {
+		return handlerLess(handlers[i], handlers[j], order)
+	})
+}
+
+// handlerLess reports whether a sorts before b in the given order
+func handlerLess(a, b HandlerInfo, order HandlerOrder) bool {
+	switch order {
+	case HandlerOrderName:
+		return a.Name < b.Name
+	case HandlerOrderDescription:
+		if a.Description != b.Description {
+			return a.Description < b.Description
+		}
+		return a.Name < b.Name
+	default:
+		return false
+	}
+}
+
+// HandlerSchema holds the JSON Schemas a handler declares for its input and output
+type HandlerSchema struct {
+	Input  json.RawMessage `json:"input"`
+	Output json.RawMessage `json:"output"`
+}
+
+// cachedSchema pairs a fetched schema with its parsed input schema
+type cachedSchema struct {
+	schema HandlerSchema
+	input  *schema.Schema
+}
+
+// Schema returns a handler's input and output schemas. Results are kept in
+// a bounded LRU, so repeated lookups do not cross the FFI; call
+// RefreshSchemas after reloading the native library.
+func (b *Bridge) Schema(handlerName string) (HandlerSchema, error) {
+	cached, err := b.cachedSchema(handlerName)
+	if err != nil {
+		return HandlerSchema{}, err
+	}
+	return cached.schema, nil
+}
+
+// RefreshSchemas drops all cached handler schemas and the cached Catalog
+func (b *Bridge) RefreshSchemas() {
+	if b.schemas != nil {
+		b.schemas.clear()
+	}
+	b.catalogMu.Lock()
+	b.catalog = nil
+	b.catalogMu.Unlock()
+}
+
+// ValidateInput checks input against the handler's declared input schema,
+// returning an error wrapping ErrBadInput on mismatch. Handlers without an
+// input schema accept any input.
+func (b *Bridge) ValidateInput(handlerName string, input map[string]interface{}) error {
+	cached, err := b.cachedSchema(handlerName)
+	if err != nil {
+		return err
+	}
+	if cached.input == nil {
+		return nil
+	}
+
+	// Round-trip so Go values are checked in the form the handler receives
+	decoded, err := roundTrip(input)
+	if err != nil {
+		return fmt.Errorf("failed to marshal input: %w", err)
+	}
+	if err := cached.input.Validate(decoded); err != nil {
+		return b.badInput(err)
+	}
+	return nil
+}
+
+// NewInput builds a starting input for a handler from its input schema:
+// schema defaults are filled in, including inside nested objects and
+// arrays, and required fields without a default get a placeholder of their
+// type (the first enum value, the minimum for numbers, or the zero value).
+// Override fields on the returned map before calling the handler.
+//
+// Handlers without an input schema get an empty map.
+func (b *Bridge) NewInput(handlerName string) (map[string]interface{}, error) {
+	cached, err := b.cachedSchema(handlerName)
+	if err != nil {
+		return nil, err
+	}
+	if cached.input == nil {
+		return make(map[string]interface{}), nil
+	}
+
+	input, ok := cached.input.Skeleton().(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("input schema of handler %s does not describe an object", handlerName)
+	}
+	return input, nil
+}
+
+func (b *Bridge) cachedSchema(handlerName string) (*cachedSchema, error) {
+	if b.schemas != nil {
+		if cached, ok := b.schemas.get(handlerName); ok {
+			b.stats.schemaHits.Add(1)
+			return cached, nil
+		}
+	}
+	b.stats.schemaMisses.Add(1)
+
+	fetched, err := b.fetchSchema(handlerName)
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := newCachedSchema(fetched)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.schemas != nil {
+		b.schemas.put(handlerName, cached)
+	}
+	return cached, nil
+}
+
+// newCachedSchema parses the input schema of a fetched schema
+func newCachedSchema(fetched HandlerSchema) (*cachedSchema, error) {
+	cached := &cachedSchema{schema: fetched}
+	if len(fetched.Input) > 0 && string(fetched.Input) != "null" {
+		var err error
+		if cached.input, err = schema.Parse(fetched.Input); err != nil {
+			return nil, err
+		}
+	}
+	return cached, nil
+}
+
+// fetchSchema asks the native library for a handler's schemas
+func (b *Bridge) fetchSchema(handlerName string) (HandlerSchema, error) {
+	if err := b.enter(); err != nil {
+		return HandlerSchema{}, err
+	}
+	defer b.leave()
+
+	if !b.supports(FeatureSchema) {
+		return HandlerSchema{}, fmt.Errorf("%w: schema introspection", ErrNotSupported)
+	}
+
+	cHandlerName := C.CString(handlerName)
+	defer C.free(unsafe.Pointer(cHandlerName))
+
+	syms := b.symbols()
+	var data []byte
+	var err error
+	b.native(func() {
+		result := C.pforge_call_handler_schema(syms, cHandlerName)
+		data, err = resultData(result)
+		C.pforge_call_free_result(syms, result)
+	})
+	if err != nil {
+		return HandlerSchema{}, err
+	}
+
+	var fetched HandlerSchema
+	if data != nil {
+		if err := b.jsonCodec.Unmarshal(data, &fetched); err != nil {
+			return HandlerSchema{}, fmt.Errorf("failed to unmarshal schema: %w", err)
+		}
+	}
+	return fetched, nil
+}
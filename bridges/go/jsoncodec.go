@@ -0,0 +1,34 @@
+package pforge
+
+import "encoding/json"
+
+// JSONCodec encodes and decodes the JSON the Bridge exchanges with the
+// native library, so a faster drop-in library can replace encoding/json;
+// set it with WithJSONCodec. Implementations must behave like encoding/json
+// for the types the Bridge passes: maps, slices and structs with json tags,
+// json.RawMessage, json.Number and types implementing json.Marshaler or
+// json.Unmarshaler. Libraries such as github.com/goccy/go-json and
+// github.com/bytedance/sonic do.
+//
+// The codec handles handler inputs and results, including stream and
+// duplex envelopes, and the metadata the native library reports; for a
+// handler using CodecMsgpack it only builds the input before conversion.
+// HashInput and audit hashes always use encoding/json, so they do not
+// change with the codec.
+type JSONCodec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// StdJSON is the JSONCodec backed by encoding/json, used by default
+var StdJSON JSONCodec = stdJSON{}
+
+type stdJSON struct{}
+
+func (stdJSON) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (stdJSON) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
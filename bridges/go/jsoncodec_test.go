@@ -0,0 +1,91 @@
+package pforge
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// pooledJSON is an alternative JSONCodec that reuses encoding buffers and
+// skips HTML escaping, standing in for a third-party library. It counts
+// its calls so tests can check which paths use it.
+type pooledJSON struct {
+	buffers              sync.Pool
+	marshals, unmarshals atomic.Int64
+}
+
+func (c *pooledJSON) Marshal(v any) ([]byte, error) {
+	c.marshals.Add(1)
+	buf, _ := c.buffers.Get().(*bytes.Buffer)
+	if buf == nil {
+		buf = new(bytes.Buffer)
+	}
+	defer c.buffers.Put(buf)
+
+	buf.Reset()
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	// Drop the newline Encode appends
+	return bytes.Clone(buf.Bytes()[:buf.Len()-1]), nil
+}
+
+func (c *pooledJSON) Unmarshal(data []byte, v any) error {
+	c.unmarshals.Add(1)
+	return json.Unmarshal(data, v)
+}
+
+// TestJSONCodecUsed checks a call encodes its input and decodes its result
+// with the configured codec
+func TestJSONCodecUsed(t *testing.T) {
+	codec := &pooledJSON{}
+	b := newStubBridge(t, nil, WithJSONCodec(codec))
+
+	output, err := b.ExecuteHandler("echo", map[string]interface{}{"html": "<b>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output["html"] != "<b>" {
+		t.Fatalf("output = %v", output)
+	}
+	if codec.marshals.Load() == 0 || codec.unmarshals.Load() == 0 {
+		t.Fatalf("codec used for %d marshals and %d unmarshals, want both", codec.marshals.Load(), codec.unmarshals.Load())
+	}
+}
+
+// BenchmarkJSONCodec compares a handler call using encoding/json with one
+// using an alternative codec
+func BenchmarkJSONCodec(b *testing.B) {
+	input := map[string]interface{}{
+		"name":  "benchmark",
+		"tags":  []string{"a", "b", "c", "<html>"},
+		"count": 1234,
+		"nested": map[string]interface{}{
+			"ratio": 0.75,
+			"items": []int{1, 2, 3, 4, 5, 6, 7, 8},
+		},
+	}
+
+	for _, bench := range []struct {
+		name  string
+		codec JSONCodec
+	}{
+		{name: "encoding/json", codec: StdJSON},
+		{name: "pooled", codec: &pooledJSON{}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			bridge := newStubBridge(b, nil, WithJSONCodec(bench.codec))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := bridge.ExecuteHandler("echo", input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
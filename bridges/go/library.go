@@ -0,0 +1,250 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// Feature names an optional capability of the native library
+type Feature string
+
+// Optional features, each backed by one or more native entry points
+const (
+	FeatureMultipart    Feature = "multipart"
+	FeatureDuplex       Feature = "duplex"
+	FeatureDuplexWindow Feature = "duplex_window"
+	FeatureListHandlers Feature = "list_handlers"
+	FeatureSchema       Feature = "schema"
+	FeatureStream       Feature = "stream"
+	FeatureCodecs       Feature = "codecs"
+	FeatureBulkFree     Feature = "bulk_free"
+	FeatureUpload       Feature = "upload"
+	FeatureHandlerCodec Feature = "handler_codec"
+	FeatureABIVersion   Feature = "abi_version"
+	FeatureCatalog      Feature = "catalog"
+	FeatureLimits       Feature = "limits"
+)
+
+// features lists every Feature in a stable order for error messages
+var features = []Feature{
+	FeatureMultipart, FeatureDuplex, FeatureDuplexWindow,
+	FeatureListHandlers, FeatureSchema, FeatureStream, FeatureCodecs,
+	FeatureBulkFree, FeatureUpload, FeatureHandlerCodec, FeatureABIVersion,
+	FeatureCatalog, FeatureLimits,
+}
+
+// featureSymbols lists the symbols each feature needs, all of which must be present
+var featureSymbols = map[Feature][]C.int{
+	FeatureMultipart: {C.SYM_EXECUTE_HANDLER_MULTIPART},
+	FeatureDuplex: {
+		C.SYM_DUPLEX_OPEN, C.SYM_DUPLEX_SEND, C.SYM_DUPLEX_RECV,
+		C.SYM_DUPLEX_CLOSE, C.SYM_DUPLEX_CANCEL, C.SYM_DUPLEX_FREE,
+	},
+	FeatureDuplexWindow: {C.SYM_DUPLEX_WINDOW},
+	FeatureListHandlers: {C.SYM_LIST_HANDLERS},
+	FeatureSchema:       {C.SYM_HANDLER_SCHEMA},
+	FeatureStream: {
+		C.SYM_STREAM_OPEN, C.SYM_STREAM_NEXT, C.SYM_STREAM_CANCEL, C.SYM_STREAM_FREE,
+	},
+	FeatureCodecs:       {C.SYM_CODECS, C.SYM_SELECT_CODEC},
+	FeatureBulkFree:     {C.SYM_FREE_RESULTS},
+	FeatureHandlerCodec: {C.SYM_HANDLER_CODECS, C.SYM_SELECT_HANDLER_CODEC},
+	FeatureABIVersion:   {C.SYM_ABI_VERSION},
+	FeatureCatalog:      {C.SYM_CATALOG},
+	FeatureLimits:       {C.SYM_LIMITS},
+	FeatureUpload: {
+		C.SYM_UPLOAD_OPEN, C.SYM_UPLOAD_WRITE, C.SYM_UPLOAD_FINISH, C.SYM_UPLOAD_ABORT,
+	},
+}
+
+var linked struct {
+	once sync.Once
+	syms *C.PforgeSymbols
+}
+
+// linkedSymbols returns the symbol table of the library linked at build time
+func linkedSymbols() *C.PforgeSymbols {
+	linked.once.Do(func() {
+		linked.syms = (*C.PforgeSymbols)(C.calloc(1, C.sizeof_PforgeSymbols))
+		C.pforge_link_symbols(linked.syms)
+	})
+	return linked.syms
+}
+
+// symbols returns the table every FFI call goes through
+func (b *Bridge) symbols() *C.PforgeSymbols {
+	if b.syms != nil {
+		return b.syms
+	}
+	return linkedSymbols()
+}
+
+// Supports reports whether the native library provides an optional feature.
+// Methods backed by an unsupported feature return ErrNotSupported. It
+// reports false for every feature once the Bridge is closed.
+func (b *Bridge) Supports(feature Feature) bool {
+	if b.life.closed.Load() {
+		return false
+	}
+	return b.supports(feature)
+}
+
+// supports is Supports for callers that hold an in-flight call
+func (b *Bridge) supports(feature Feature) bool {
+	symbols, ok := featureSymbols[feature]
+	if !ok || b.unavailable != nil {
+		return false
+	}
+
+	syms := b.symbols()
+	for _, sym := range symbols {
+		if C.pforge_has(syms, sym) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// NewBridgeWithLibrary loads the native library at path with dlopen instead
+// of using the one linked at build time. With WithLibraryChecksum the file
+// is hashed first and a mismatch fails with ErrChecksumMismatch before
+// anything is loaded.
+//
+// All required symbols are resolved up front, so a wrong or outdated library
+// fails here with the list of missing symbols rather than on first call.
+// Optional features whose symbols are entirely absent are reported through
+// Supports; a feature with only some of its symbols is treated as an error.
+// The library's ABI version is checked against ABIVersion, failing with
+// ErrABIMismatch, and codec negotiation (see WithDefaultCodec) happens
+// here too, failing with ErrNoCommonCodec if the library shares no codec
+// with the Bridge.
+//
+// With WithDegradedMode, any of these failures returns a Bridge whose
+// calls fail with ErrBridgeUnavailable instead of an error.
+func NewBridgeWithLibrary(path string, opts ...Option) (*Bridge, error) {
+	b := newBridge(opts...)
+	if err := b.load(path); err != nil {
+		return b.degrade(err)
+	}
+	return b, nil
+}
+
+// load opens the library at path and runs the handshake with it
+func (b *Bridge) load(path string) error {
+	if err := b.verifyChecksum(path); err != nil {
+		return err
+	}
+
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+
+	handle := C.dlopen(cPath, C.RTLD_NOW|C.RTLD_LOCAL)
+	if handle == nil {
+		return fmt.Errorf("failed to load native library %s: %s", path, C.GoString(C.dlerror()))
+	}
+
+	syms := (*C.PforgeSymbols)(C.calloc(1, C.sizeof_PforgeSymbols))
+	C.pforge_load_symbols(handle, syms)
+
+	if missing := missingSymbols(syms); len(missing) > 0 {
+		C.free(unsafe.Pointer(syms))
+		C.dlclose(handle)
+		return fmt.Errorf("native library %s is missing symbols: %s", path, strings.Join(missing, ", "))
+	}
+
+	b.syms = syms
+	b.handle = handle
+	if err := b.handshake(); err != nil {
+		return fmt.Errorf("native library %s: %w", path, err)
+	}
+	return nil
+}
+
+// verifyChecksum checks the library at path against WithLibraryChecksum
+func (b *Bridge) verifyChecksum(path string) error {
+	if b.libraryChecksum == "" {
+		return nil
+	}
+	if b.skipLibraryChecksum {
+		b.logger(context.Background()).Warn("loading native library without checksum verification", "path", path)
+		return nil
+	}
+
+	want, err := hex.DecodeString(b.libraryChecksum)
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("invalid library checksum %q: want %d hex-encoded bytes", b.libraryChecksum, sha256.Size)
+	}
+	got, err := fileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify native library %s: %w", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: %s has SHA-256 %x, want %x", ErrChecksumMismatch, path, got, want)
+	}
+	return nil
+}
+
+// missingSymbols lists required symbols that are absent, plus symbols of
+// optional features that are only partly present
+func missingSymbols(syms *C.PforgeSymbols) []string {
+	var missing []string
+	for sym := C.int(0); sym < C.SYM_REQUIRED_COUNT; sym++ {
+		if C.pforge_has(syms, sym) == 0 {
+			missing = append(missing, C.GoString(C.pforge_symbol_name(sym)))
+		}
+	}
+
+	for _, feature := range features {
+		symbols := featureSymbols[feature]
+		var absent []string
+		for _, sym := range symbols {
+			if C.pforge_has(syms, sym) == 0 {
+				absent = append(absent, C.GoString(C.pforge_symbol_name(sym)))
+			}
+		}
+		if len(absent) > 0 && len(absent) < len(symbols) {
+			missing = append(missing, absent...)
+		}
+	}
+	return missing
+}
+
+// unload releases a library opened by NewBridgeWithLibrary. It is a no-op
+// for bridges using the library linked at build time. The caller ensures no
+// calls are in flight.
+func (b *Bridge) unload() error {
+	if b.thread != nil {
+		b.thread.stop()
+		b.thread = nil
+	}
+	if b.handle == nil {
+		return nil
+	}
+
+	C.free(unsafe.Pointer(b.syms))
+	b.syms = nil
+	var err error
+	if C.dlclose(b.handle) != 0 {
+		err = fmt.Errorf("failed to unload native library: %s", C.GoString(C.dlerror()))
+	}
+	b.handle = nil
+
+	if b.extractDir != "" {
+		if removeErr := os.RemoveAll(b.extractDir); err == nil && removeErr != nil {
+			err = fmt.Errorf("failed to remove extracted library: %w", removeErr)
+		}
+		b.extractDir = ""
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+package pforge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCloseGracePeriod is how long Close waits for in-flight calls by default
+const DefaultCloseGracePeriod = 5 * time.Second
+
+// lifecycle tracks in-flight calls so Close can drain them before the
+// native library is unloaded
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   atomic.Bool
+	inFlight int
+	drained  chan struct{}
+	// unloadPending is set when Close gave up waiting; the last call to
+	// leave then unloads the library
+	unloadPending bool
+	// onClose holds the OnClose callbacks in registration order
+	onClose []func() error
+
+	// abort is cancelled when the grace period expires, failing calls
+	// that can be interrupted
+	abort     context.Context
+	abortFunc context.CancelFunc
+}
+
+func newLifecycle() lifecycle {
+	abort, abortFunc := context.WithCancel(context.Background())
+	return lifecycle{drained: make(chan struct{}), abort: abort, abortFunc: abortFunc}
+}
+
+// enter registers an in-flight call, failing with ErrBridgeClosed once
+// Close has been called. Every successful enter must be paired with leave.
+func (b *Bridge) enter() error {
+	b.life.mu.Lock()
+	defer b.life.mu.Unlock()
+
+	if b.life.closed.Load() {
+		return ErrBridgeClosed
+	}
+	if b.unavailable != nil {
+		return b.unavailable
+	}
+	if b.initErr != nil {
+		return b.initErr
+	}
+	b.life.inFlight++
+	b.stats.inFlight.Add(1)
+	return nil
+}
+
+// leave ends an in-flight call, finishing a pending Close if it was the last
+func (b *Bridge) leave() {
+	b.life.mu.Lock()
+	defer b.life.mu.Unlock()
+
+	b.life.inFlight--
+	b.stats.inFlight.Add(-1)
+	if b.life.inFlight > 0 || !b.life.closed.Load() {
+		return
+	}
+	close(b.life.drained)
+	if b.life.unloadPending {
+		b.life.unloadPending = false
+		b.unload()
+	}
+}
+
+// scope derives a context that is also cancelled, with cause
+// ErrBridgeClosed, when Close stops waiting for in-flight calls, and by
+// CancelAll
+func (b *Bridge) scope(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(b.life.abort, func() {
+		cancel(ErrBridgeClosed)
+	})
+	stopAll := context.AfterFunc(b.cancelAllContext(), func() {
+		cancel(errCancelledAll)
+	})
+	return ctx, func() {
+		stop()
+		stopAll()
+		cancel(nil)
+	}
+}
+
+// Close rejects new calls with ErrBridgeClosed and waits up to the grace
+// period (see WithCloseGracePeriod) for in-flight calls to finish before
+// unloading a library opened by NewBridgeWithLibrary.
+//
+// When the grace period expires, context-aware calls and streams still in
+// flight fail with ErrBridgeClosed and Close returns an error. Native code
+// that is still running cannot be interrupted, so the library is unloaded
+// only after the last such call returns.
+//
+// Callbacks registered with OnClose run last, and their errors are
+// returned along with any error from closing the Bridge itself.
+func (b *Bridge) Close() error {
+	b.life.mu.Lock()
+	if b.life.closed.Swap(true) {
+		b.life.mu.Unlock()
+		return nil
+	}
+	if b.life.inFlight == 0 {
+		close(b.life.drained)
+	}
+	hooks := b.life.onClose
+	b.life.onClose = nil
+	b.life.mu.Unlock()
+
+	err := b.drain()
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if hookErr := hooks[i](); hookErr != nil {
+			errs = append(errs, hookErr)
+		}
+	}
+	if len(errs) == 0 {
+		return err
+	}
+	return errors.Join(append([]error{err}, errs...)...)
+}
+
+// OnClose registers fn to run when the Bridge is closed, for releasing
+// resources tied to its lifetime. Callbacks run in reverse order of
+// registration (last registered, first run), after in-flight calls have
+// drained and the library has been unloaded, or after the grace period if
+// calls are still running. Each callback runs even if an earlier one
+// failed. If the Bridge is already closed, fn runs immediately and its
+// error is discarded.
+func (b *Bridge) OnClose(fn func() error) {
+	b.life.mu.Lock()
+	if !b.life.closed.Load() {
+		b.life.onClose = append(b.life.onClose, fn)
+		b.life.mu.Unlock()
+		return
+	}
+	b.life.mu.Unlock()
+	fn()
+}
+
+// drain waits for in-flight calls for up to the grace period, then unloads
+// the library or leaves that to the last call
+func (b *Bridge) drain() error {
+	b.settings.RLock()
+	gracePeriod := b.closeGracePeriod
+	b.settings.RUnlock()
+
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-b.life.drained:
+		return b.unload()
+	case <-timer.C:
+	}
+
+	b.life.abortFunc()
+
+	b.life.mu.Lock()
+	defer b.life.mu.Unlock()
+	if b.life.inFlight == 0 {
+		return b.unload()
+	}
+	b.life.unloadPending = true
+	return fmt.Errorf("%w: %d calls still in flight after %v", ErrBridgeClosed, b.life.inFlight, gracePeriod)
+}
@@ -0,0 +1,127 @@
+package pforge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCloseConcurrentCalls closes a Bridge while calls of every kind are
+// running on other goroutines. Run it with -race: every call must either
+// succeed or fail with ErrBridgeClosed, and none may touch the library
+// after Close unloads it.
+func TestCloseConcurrentCalls(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		b := newStubBridge(t, nil, WithCloseGracePeriod(time.Second))
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 64)
+		call := func(fn func() error) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 10; i++ {
+					if err := fn(); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}()
+		}
+		for i := 0; i < 4; i++ {
+			call(func() error {
+				_, err := b.ExecuteHandler("echo", map[string]interface{}{"n": 1})
+				return err
+			})
+			call(func() error {
+				_, err := b.ExecuteHandlerContext(context.Background(), "echo", nil)
+				return err
+			})
+			call(func() error {
+				_, errs := b.ExecuteBatch(context.Background(), "echo", []map[string]interface{}{{}, {}})
+				return errs.First()
+			})
+		}
+
+		time.Sleep(time.Duration(round) * 50 * time.Microsecond)
+		if err := b.Close(); err != nil {
+			t.Fatalf("round %d: Close: %v", round, err)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if !errors.Is(err, ErrBridgeClosed) {
+				t.Errorf("round %d: call failed with %v, want nil or ErrBridgeClosed", round, err)
+			}
+		}
+
+		if _, err := b.ExecuteHandler("echo", nil); !errors.Is(err, ErrBridgeClosed) {
+			t.Errorf("round %d: call after Close: %v, want ErrBridgeClosed", round, err)
+		}
+	}
+}
+
+// TestCloseGracePeriod checks Close waits for a call that finishes within
+// the grace period, and fails a context-aware call that outlasts it
+func TestCloseGracePeriod(t *testing.T) {
+	t.Run("drains", func(t *testing.T) {
+		b := newStubBridge(t, nil, WithCloseGracePeriod(time.Second))
+		done := make(chan error, 1)
+		go func() {
+			_, err := b.ExecuteHandler("slow", nil)
+			done <- err
+		}()
+		time.Sleep(5 * time.Millisecond)
+
+		if err := b.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Fatalf("in-flight call: %v", err)
+		}
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		b := newStubBridge(t, nil, WithCloseGracePeriod(time.Millisecond))
+		done := make(chan error, 1)
+		go func() {
+			_, err := b.ExecuteHandlerContext(context.Background(), "slow", nil)
+			done <- err
+		}()
+		time.Sleep(5 * time.Millisecond)
+
+		if err := b.Close(); err == nil {
+			t.Error("Close returned nil with a call still in flight")
+		}
+		if err := <-done; !errors.Is(err, ErrBridgeClosed) {
+			t.Fatalf("in-flight call: %v, want ErrBridgeClosed", err)
+		}
+	})
+}
+
+// TestDegradedModeWarns checks a Bridge that could not load its library
+// warns through slog.Default when no logger was set
+func TestDegradedModeWarns(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	b, err := NewBridgeWithLibrary(filepath.Join(t.TempDir(), "missing.so"), WithDegradedMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if b.Available() {
+		t.Error("Available() = true for a Bridge without a library")
+	}
+	if !strings.Contains(buf.String(), "running degraded") {
+		t.Errorf("default logger got %q, want the degraded warning", buf.String())
+	}
+}
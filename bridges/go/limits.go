@@ -0,0 +1,72 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import "fmt"
+
+// Limits are the maxima the native library enforces. A zero field means
+// the library reports no limit for it.
+type Limits struct {
+	// MaxInputBytes is the largest serialized input a handler accepts
+	MaxInputBytes int64 `json:"max_input_bytes"`
+	// MaxResultBytes is the largest serialized result a handler returns
+	MaxResultBytes int64 `json:"max_result_bytes"`
+	// MaxBatchSize is the most calls one native batch may hold
+	MaxBatchSize int `json:"max_batch_size"`
+}
+
+// Limits returns the payload and batch limits the native library reports,
+// so callers can validate inputs and size batches before sending them
+// rather than discovering the limits by hitting them. The Bridge does not
+// enforce them itself. The result is fetched once and cached; a library
+// without FeatureLimits fails with ErrNotSupported.
+func (b *Bridge) Limits() (Limits, error) {
+	b.limitsMu.Lock()
+	defer b.limitsMu.Unlock()
+
+	if b.limits == nil {
+		limits, err := b.fetchLimits()
+		if err != nil {
+			return Limits{}, err
+		}
+		b.limits = &limits
+	}
+	return *b.limits, nil
+}
+
+// fetchLimits asks the native library for its limits
+func (b *Bridge) fetchLimits() (Limits, error) {
+	if err := b.enter(); err != nil {
+		return Limits{}, err
+	}
+	defer b.leave()
+
+	if !b.supports(FeatureLimits) {
+		return Limits{}, fmt.Errorf("%w: limits", ErrNotSupported)
+	}
+
+	syms := b.symbols()
+	var data []byte
+	var err error
+	b.native(func() {
+		result := C.pforge_call_limits(syms)
+		data, err = resultData(result)
+		C.pforge_call_free_result(syms, result)
+	})
+	if err != nil {
+		return Limits{}, err
+	}
+
+	var limits Limits
+	if data != nil {
+		if err := b.jsonCodec.Unmarshal(data, &limits); err != nil {
+			return Limits{}, fmt.Errorf("failed to unmarshal limits: %w", err)
+		}
+	}
+	if limits.MaxInputBytes < 0 || limits.MaxResultBytes < 0 || limits.MaxBatchSize < 0 {
+		return Limits{}, fmt.Errorf("native library reported negative limits: %+v", limits)
+	}
+	return limits, nil
+}
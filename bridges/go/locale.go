@@ -0,0 +1,139 @@
+package pforge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"example/pforgectx"
+)
+
+// ContextWithLocale returns a copy of ctx that makes ExecuteHandlerContext
+// and PreparedCall.Execute send tag as LocaleField in place of the Bridge's
+// WithLocale tag; an empty tag sends no locale. The tag is validated when
+// the call is made, and a malformed tag fails the call with an error
+// wrapping ErrInvalidLocale.
+func ContextWithLocale(ctx context.Context, tag string) context.Context {
+	return pforgectx.WithLocale(ctx, tag)
+}
+
+// LocaleFromContext returns the tag stored by ContextWithLocale
+func LocaleFromContext(ctx context.Context) (tag string, ok bool) {
+	return pforgectx.Locale(ctx)
+}
+
+// ResultLocale returns the LocaleField a handler reported in its result:
+// the locale it actually used, which may be a fallback from the one
+// requested. ok is false if the handler did not report one.
+func ResultLocale(output map[string]interface{}) (tag string, ok bool) {
+	tag, ok = output[LocaleField].(string)
+	return tag, ok
+}
+
+// callLocale picks the locale for a context-aware call: the context's,
+// then the Bridge's
+func (b *Bridge) callLocale(ctx context.Context) string {
+	if tag, ok := LocaleFromContext(ctx); ok {
+		return tag
+	}
+	return b.locale
+}
+
+// ValidateLocale checks that tag is a well-formed BCP 47 language tag, such
+// as "en", "pt-BR" or "zh-Hant-TW", returning an error wrapping
+// ErrInvalidLocale if not. It checks syntax only, case-insensitively, and
+// does not consult the subtag registry, so "xx-YY" passes.
+func ValidateLocale(tag string) error {
+	if !wellFormedLocale(tag) {
+		return fmt.Errorf("%w: %q", ErrInvalidLocale, tag)
+	}
+	return nil
+}
+
+// wellFormedLocale follows the langtag production of RFC 5646: language
+// (with up to three extlangs), then optional script, region, variants,
+// extensions and private use. Irregular grandfathered tags are rejected.
+func wellFormedLocale(tag string) bool {
+	subtags := strings.Split(tag, "-")
+	for _, s := range subtags {
+		if len(s) == 0 || len(s) > 8 || !isAlnum(s) {
+			return false
+		}
+	}
+	if strings.EqualFold(subtags[0], "x") {
+		return privateUse(subtags[1:])
+	}
+
+	lang := subtags[0]
+	if !isAlpha(lang) || len(lang) < 2 || len(lang) == 4 {
+		return false
+	}
+	i := 1
+	if len(lang) <= 3 {
+		for n := 0; n < 3 && i < len(subtags) && len(subtags[i]) == 3 && isAlpha(subtags[i]); n++ {
+			i++
+		}
+	}
+	if i < len(subtags) && len(subtags[i]) == 4 && isAlpha(subtags[i]) {
+		i++
+	}
+	if i < len(subtags) && (len(subtags[i]) == 2 && isAlpha(subtags[i]) || len(subtags[i]) == 3 && isDigit(subtags[i])) {
+		i++
+	}
+	for i < len(subtags) && (len(subtags[i]) >= 5 || len(subtags[i]) == 4 && isDigit(subtags[i][:1])) {
+		i++
+	}
+
+	seen := make(map[string]bool)
+	for i < len(subtags) && len(subtags[i]) == 1 && !strings.EqualFold(subtags[i], "x") {
+		singleton := strings.ToLower(subtags[i])
+		if seen[singleton] {
+			return false
+		}
+		seen[singleton] = true
+		i++
+		start := i
+		for i < len(subtags) && len(subtags[i]) >= 2 {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	if i < len(subtags) && strings.EqualFold(subtags[i], "x") {
+		return privateUse(subtags[i+1:])
+	}
+	return i == len(subtags)
+}
+
+// privateUse checks the subtags after an "x" singleton
+func privateUse(subtags []string) bool {
+	return len(subtags) > 0
+}
+
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isAlpha(s[i:i+1]) && !isDigit(s[i:i+1]) {
+			return false
+		}
+	}
+	return true
+}
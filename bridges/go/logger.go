@@ -0,0 +1,60 @@
+package pforge
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"example/pforgectx"
+)
+
+// ContextWithLogger returns a copy of ctx carrying logger. The Bridge's
+// context-aware methods write their per-call log lines to it in preference
+// to the logger set with WithLogger, so request-scoped fields are kept.
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return pforgectx.WithLogger(ctx, logger)
+}
+
+// LoggerFromContext returns the logger stored by ContextWithLogger, or nil
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	return pforgectx.Logger(ctx)
+}
+
+// logger picks the logger for a call: the context's, then the Bridge's,
+// then one that discards everything
+func (b *Bridge) logger(ctx context.Context) *slog.Logger {
+	if logger := LoggerFromContext(ctx); logger != nil {
+		return logger
+	}
+	if b.log != nil {
+		return b.log
+	}
+	return discardLogger
+}
+
+// logCall writes the completion line for a context-aware call
+func (b *Bridge) logCall(ctx context.Context, msg, handlerName string, start time.Time, err error) {
+	logger := b.logger(ctx)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelWarn, msg+" failed",
+			slog.String("handler", handlerName),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, msg,
+		slog.String("handler", handlerName),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+var discardLogger = slog.New(discardHandler{})
+
+// discardHandler is a slog.Handler that drops every record
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,467 @@
+package pforge
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// msgpackMaxDepth bounds nesting when decoding, as encoding/json does
+const msgpackMaxDepth = 10000
+
+var errMsgpackTruncated = errors.New("msgpack: unexpected end of data")
+
+// msgpackCodec encodes handler inputs and decodes handler results as
+// MessagePack for handlers using CodecMsgpack. It implements JSONCodec so
+// results go through the same decoding paths as JSON: values decode to the
+// types encoding/json produces for interface{}, except that binary values
+// decode to []byte, and other targets such as structs are filled by way of
+// encoding/json.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	var e msgpackEncoder
+	if err := e.encode(v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	d := msgpackDecoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return err
+	}
+	if d.pos != len(data) {
+		return fmt.Errorf("msgpack: %d trailing bytes", len(data)-d.pos)
+	}
+
+	switch target := v.(type) {
+	case *interface{}:
+		*target = value
+	case *map[string]interface{}:
+		if value == nil {
+			*target = nil
+			return nil
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("msgpack: cannot unmarshal %s into a map", typeNameOf(value))
+		}
+		*target = m
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("msgpack: %w", err)
+		}
+		return json.Unmarshal(data, v)
+	}
+	return nil
+}
+
+// typeNameOf names a decoded value's JSON type for error messages
+func typeNameOf(v interface{}) string {
+	switch v.(type) {
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []byte:
+		return "binary"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+type msgpackEncoder struct {
+	buf []byte
+}
+
+// encode appends v. Types without a direct MessagePack form, such as
+// structs and json.Marshaler implementations, are encoded as their JSON.
+func (e *msgpackEncoder) encode(v any) error {
+	switch v := v.(type) {
+	case nil:
+		e.buf = append(e.buf, 0xc0)
+	case bool:
+		if v {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case string:
+		e.encodeString(v)
+	case []byte:
+		e.encodeBinary(v)
+	case float64:
+		e.buf = append(e.buf, 0xcb)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v))
+	case float32:
+		e.buf = append(e.buf, 0xca)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(v))
+	case int:
+		e.encodeInt(int64(v))
+	case int8:
+		e.encodeInt(int64(v))
+	case int16:
+		e.encodeInt(int64(v))
+	case int32:
+		e.encodeInt(int64(v))
+	case int64:
+		e.encodeInt(v)
+	case uint:
+		e.encodeUint(uint64(v))
+	case uint8:
+		e.encodeUint(uint64(v))
+	case uint16:
+		e.encodeUint(uint64(v))
+	case uint32:
+		e.encodeUint(uint64(v))
+	case uint64:
+		e.encodeUint(v)
+	case json.Number:
+		return e.encodeNumber(v)
+	case json.RawMessage:
+		return e.encodeJSON(v)
+	case map[string]interface{}:
+		return e.encodeMap(v)
+	case []interface{}:
+		e.encodeHeader(len(v), 0x90, 0xdc)
+		for _, item := range v {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return e.encodeJSON(data)
+	}
+	return nil
+}
+
+// encodeJSON appends the MessagePack form of a JSON document, keeping its
+// numbers exact
+func (e *msgpackEncoder) encodeJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return err
+	}
+	return e.encode(value)
+}
+
+// encodeMap appends a map with its keys sorted, so equal maps encode to
+// equal bytes as they do with encoding/json
+func (e *msgpackEncoder) encodeMap(m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	e.encodeHeader(len(keys), 0x80, 0xde)
+	for _, key := range keys {
+		e.encodeString(key)
+		if err := e.encode(m[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeHeader appends an array or map header: the fix form below 16
+// entries, otherwise the 16-bit form or the 32-bit form that follows it
+func (e *msgpackEncoder) encodeHeader(n int, fix, wide byte) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, wide)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, wide+1)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) encodeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xda)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdb)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) encodeBinary(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xc5)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xc6)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+// encodeNumber appends a json.Number as an integer when it is one that
+// fits in 64 bits, and as a float64 otherwise
+func (e *msgpackEncoder) encodeNumber(n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		e.encodeInt(i)
+		return nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		e.encodeUint(u)
+		return nil
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return fmt.Errorf("msgpack: invalid number %q", n)
+	}
+	e.buf = append(e.buf, 0xcb)
+	e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(f))
+	return nil
+}
+
+// encodeInt appends i in its smallest form
+func (e *msgpackEncoder) encodeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.encodeUint(uint64(i))
+	case i >= -32:
+		e.buf = append(e.buf, byte(i))
+	case i >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		e.buf = append(e.buf, 0xd1)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(i))
+	case i >= math.MinInt32:
+		e.buf = append(e.buf, 0xd2)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(i))
+	default:
+		e.buf = append(e.buf, 0xd3)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(i))
+	}
+}
+
+// encodeUint appends u in its smallest form
+func (e *msgpackEncoder) encodeUint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		e.buf = append(e.buf, byte(u))
+	case u <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		e.buf = append(e.buf, 0xcd)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(u))
+	case u <= math.MaxUint32:
+		e.buf = append(e.buf, 0xce)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(u))
+	default:
+		e.buf = append(e.buf, 0xcf)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, u)
+	}
+}
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+// decode reads one value at the given nesting depth
+func (d *msgpackDecoder) decode(depth int) (interface{}, error) {
+	if depth > msgpackMaxDepth {
+		return nil, errors.New("msgpack: exceeded max depth")
+	}
+	tag, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tag[0]; {
+	case t <= 0x7f:
+		return float64(t), nil
+	case t >= 0xe0:
+		return float64(int8(t)), nil
+	case t&0xf0 == 0x80:
+		return d.decodeMap(int(t&0x0f), depth)
+	case t&0xf0 == 0x90:
+		return d.decodeArray(int(t&0x0f), depth)
+	case t&0xe0 == 0xa0:
+		return d.decodeString(int(t & 0x1f))
+	}
+
+	switch tag[0] {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.length(tag[0] - 0xc4)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xca:
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 0xcb:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (tag[0] - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return float64(u), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (tag[0] - 0xd0)
+		u, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend from the encoded width
+		shift := 64 - 8*size
+		return float64(int64(u<<shift) >> shift), nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.length(tag[0] - 0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xdc, 0xdd:
+		n, err := d.length(tag[0] - 0xdc + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n, depth)
+	case 0xde, 0xdf:
+		n, err := d.length(tag[0] - 0xde + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n, depth)
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type 0x%02x", tag[0])
+	}
+}
+
+// length reads a length of 1, 2 or 4 bytes, for width 0, 1 or 2
+func (d *msgpackDecoder) length(width byte) (int, error) {
+	u, err := d.uint(1 << width)
+	if err != nil {
+		return 0, err
+	}
+	if u > uint64(len(d.data)-d.pos) {
+		return 0, errMsgpackTruncated
+	}
+	return int(u), nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errMsgpackTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) decodeString(n int) (string, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (d *msgpackDecoder) decodeArray(n int, depth int) ([]interface{}, error) {
+	// Every element takes at least a byte, so n is bounded by the data left
+	if n > len(d.data)-d.pos {
+		return nil, errMsgpackTruncated
+	}
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n int, depth int) (map[string]interface{}, error) {
+	if n > (len(d.data)-d.pos)/2 {
+		return nil, errMsgpackTruncated
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack: map key is a %s, not a string", typeNameOf(key))
+		}
+		value, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		m[name] = value
+	}
+	return m, nil
+}
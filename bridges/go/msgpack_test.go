@@ -0,0 +1,122 @@
+package pforge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestMsgpackEncoding checks values encode to the MessagePack forms the
+// spec gives and decode back to the types encoding/json would produce
+func TestMsgpackEncoding(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  []byte
+		// decoded is the value read back, when it differs from value
+		decoded any
+	}{
+		{name: "spec example", value: map[string]interface{}{"compact": true, "schema": 0},
+			want:    append(append([]byte{0x82, 0xa7}, "compact"...), append([]byte{0xc3, 0xa6}, "schema\x00"...)...),
+			decoded: map[string]interface{}{"compact": true, "schema": float64(0)}},
+		{name: "nil", value: nil, want: []byte{0xc0}},
+		{name: "negative fixint", value: -5, want: []byte{0xfb}, decoded: float64(-5)},
+		{name: "int16", value: -300, want: []byte{0xd1, 0xfe, 0xd4}, decoded: float64(-300)},
+		{name: "uint16", value: 300, want: []byte{0xcd, 0x01, 0x2c}, decoded: float64(300)},
+		{name: "float", value: 1.5, want: []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{name: "array", value: []interface{}{"a", false}, want: []byte{0x92, 0xa1, 'a', 0xc2}},
+		{name: "binary", value: []byte{1, 2}, want: []byte{0xc4, 2, 1, 2}},
+		{name: "number", value: json.Number("9007199254740993"),
+			want:    []byte{0xcf, 0, 0x20, 0, 0, 0, 0, 0, 1},
+			decoded: float64(9007199254740993)},
+		{name: "struct", value: struct {
+			N int `json:"n"`
+		}{7}, want: []byte{0x81, 0xa1, 'n', 0x07}, decoded: map[string]interface{}{"n": float64(7)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := msgpackCodec{}.Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, tt.want) {
+				t.Fatalf("encoded % x, want % x", data, tt.want)
+			}
+
+			var decoded interface{}
+			if err := (msgpackCodec{}).Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			want := tt.decoded
+			if want == nil {
+				want = tt.value
+			}
+			if !reflect.DeepEqual(decoded, want) {
+				t.Errorf("decoded %#v, want %#v", decoded, want)
+			}
+		})
+	}
+}
+
+// TestMsgpackMalformed checks truncated or unsupported input fails instead
+// of panicking or allocating for lengths the data cannot hold
+func TestMsgpackMalformed(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":          {},
+		"short string":   {0xa5, 'a'},
+		"huge array":     {0xdd, 0xff, 0xff, 0xff, 0xff},
+		"huge map":       {0xdf, 0x7f, 0xff, 0xff, 0xff, 0xc0},
+		"non-string key": {0x81, 0x01, 0x02},
+		"extension":      {0xd4, 0x01, 0x00},
+		"trailing":       {0xc0, 0xc0},
+	} {
+		var v interface{}
+		if err := (msgpackCodec{}).Unmarshal(data, &v); err == nil {
+			t.Errorf("%s: decoded %#v, want an error", name, v)
+		}
+	}
+}
+
+// TestHandlerCodecMsgpack checks a handler overridden to msgpack gets its
+// input, envelope fields included, as MessagePack and has its result
+// decoded from it, while other handlers keep JSON
+func TestHandlerCodecMsgpack(t *testing.T) {
+	b := newStubBridge(t, []string{"CODECS"},
+		WithDefaultCodec(CodecJSON),
+		WithHandlerCodec("echo", CodecMsgpack),
+		WithClientIdentity("svc", "1.0"),
+	)
+	if got := b.HandlerCodec("echo"); got != CodecMsgpack {
+		t.Fatalf("HandlerCodec(echo) = %s, want msgpack", got)
+	}
+
+	input := map[string]interface{}{"id": json.Number("9007199254740993"), "tags": []interface{}{"a"}}
+	raw, output, err := b.ExecuteHandlerRawAndMap("echo", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) == 0 || raw[0]&0xf0 != 0x80 {
+		t.Fatalf("echo result % x is not a MessagePack map", raw)
+	}
+	if output["tags"].([]interface{})[0] != "a" || output[ClientField] == nil {
+		t.Errorf("output = %v, want the input with the client envelope", output)
+	}
+	// The stub echoes the encoded input, so the integer arrives exactly
+	if !bytes.Contains(raw, []byte{0xcf, 0, 0x20, 0, 0, 0, 0, 0, 1}) {
+		t.Errorf("echo result % x lost the exact integer", raw)
+	}
+
+	raw, _, err = b.ExecuteHandlerRawAndMap("other", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(raw) {
+		t.Errorf("other handler got % x, want JSON", raw)
+	}
+
+	if _, err := NewBridgeWithLibrary(stubLibrary(t, "CODECS"), WithHandlerCodec("echo", "cbor")); !errors.Is(err, ErrNoCommonCodec) {
+		t.Errorf("cbor override: got %v, want ErrNoCommonCodec", err)
+	}
+}
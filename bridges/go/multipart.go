@@ -0,0 +1,97 @@
+package pforge
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// multipartHeader is the JSON header at the start of a multipart frame
+type multipartHeader struct {
+	Meta        map[string]interface{} `json:"meta"`
+	Attachments []attachmentRef        `json:"attachments"`
+}
+
+// attachmentRef describes one raw blob following the header
+type attachmentRef struct {
+	Key  string `json:"key"`
+	Size int    `json:"size"`
+}
+
+// ExecuteHandlerMultipart calls a pforge handler with JSON metadata plus raw
+// binary attachments, avoiding base64 overhead for mixed payloads.
+//
+// The frame sent across the FFI is a 4-byte big-endian header length, the
+// JSON header ({"meta": ..., "attachments": [{"key", "size"}]}), then the
+// attachment bytes back to back in header order. Attachments are ordered by
+// key. The audit input hash covers the whole frame, with meta redacted.
+func (b *Bridge) ExecuteHandlerMultipart(handlerName string, meta map[string]interface{}, attachments map[string][]byte) (map[string]interface{}, error) {
+	start := time.Now()
+	output, err := b.observe(b.executeMultipart(handlerName, meta, attachments))
+	b.finishCall(context.Background(), "multipart call", handlerName, start, err)
+	if b.auditSink != nil {
+		b.audit(context.Background(), "multipart call", handlerName, start, b.multipartDigest(meta, attachments), output, err)
+	}
+	return output, err
+}
+
+func (b *Bridge) executeMultipart(handlerName string, meta map[string]interface{}, attachments map[string][]byte) (map[string]interface{}, error) {
+	release, err := b.acquireHandler(context.Background(), handlerName)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	frame, err := encodeMultipart(b.jsonCodec, meta, attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.call(entryMultipart, handlerName, frame)
+}
+
+// multipartDigest is the audit hash of a multipart frame, framed with
+// encoding/json like other audit hashes so it does not change with the codec
+func (b *Bridge) multipartDigest(meta map[string]interface{}, attachments map[string][]byte) auditDigest {
+	frame, err := encodeMultipart(StdJSON, b.Redact(meta), attachments)
+	if err != nil {
+		return ""
+	}
+	return auditDigest(hashBytes(frame))
+}
+
+// encodeMultipart frames metadata and attachments into a single buffer,
+// encoding the header with codec
+func encodeMultipart(codec JSONCodec, meta map[string]interface{}, attachments map[string][]byte) ([]byte, error) {
+	keys := make([]string, 0, len(attachments))
+	total := 0
+	for key, data := range attachments {
+		keys = append(keys, key)
+		total += len(data)
+	}
+	sort.Strings(keys)
+
+	header := multipartHeader{
+		Meta:        meta,
+		Attachments: make([]attachmentRef, len(keys)),
+	}
+	for i, key := range keys {
+		header.Attachments[i] = attachmentRef{Key: key, Size: len(attachments[key])}
+	}
+
+	headerJSON, err := codec.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal multipart header: %w", err)
+	}
+
+	frame := make([]byte, 4, 4+len(headerJSON)+total)
+	binary.BigEndian.PutUint32(frame, uint32(len(headerJSON)))
+	frame = append(frame, headerJSON...)
+	for _, key := range keys {
+		frame = append(frame, attachments[key]...)
+	}
+
+	return frame, nil
+}
@@ -0,0 +1,83 @@
+package pforge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Reserved result fields for handlers that return auxiliary payloads
+const (
+	// PrimaryField holds the main result in a multi-result envelope
+	PrimaryField = "_primary"
+	// AuxField holds named side outputs such as warnings or metrics
+	AuxField = "_aux"
+)
+
+// CallResult is a handler result split into its primary payload and
+// named auxiliary payloads
+type CallResult struct {
+	Primary map[string]interface{}
+	Aux     map[string]json.RawMessage
+}
+
+// ExecuteHandlerMulti calls a handler that may return side outputs.
+//
+// A handler opts in by returning {"_primary": {...}, "_aux": {"name": ...}}.
+// Results without these fields are returned whole as Primary with no Aux,
+// so single-result handlers work unchanged.
+func (b *Bridge) ExecuteHandlerMulti(handlerName string, input map[string]interface{}) (CallResult, error) {
+	start := time.Now()
+	result, err := b.executeMulti(handlerName, input)
+	b.record(err)
+	b.finishCall(context.Background(), "handler call", handlerName, start, err)
+	b.audit(context.Background(), "handler call", handlerName, start, input, result.Primary, err)
+	return result, err
+}
+
+func (b *Bridge) executeMulti(handlerName string, input map[string]interface{}) (CallResult, error) {
+	release, err := b.acquireHandler(context.Background(), handlerName)
+	if err != nil {
+		return CallResult{}, err
+	}
+	defer release()
+
+	inputJSON, err := b.marshalInput(handlerName, input)
+	if err != nil {
+		return CallResult{}, err
+	}
+
+	data, err := b.callRaw(entryExecute, handlerName, inputJSON)
+	if err != nil {
+		return CallResult{}, err
+	}
+
+	return decodeCallResult(b.wireCodec(handlerName), data)
+}
+
+// decodeCallResult splits raw result bytes into primary and auxiliary payloads
+func decodeCallResult(codec JSONCodec, data []byte) (CallResult, error) {
+	var envelope struct {
+		Primary map[string]interface{}     `json:"_primary"`
+		Aux     map[string]json.RawMessage `json:"_aux"`
+	}
+	if data != nil {
+		if err := codec.Unmarshal(data, &envelope); err != nil {
+			return CallResult{}, fmt.Errorf("failed to unmarshal result: %w", err)
+		}
+	}
+
+	if envelope.Primary == nil && envelope.Aux == nil {
+		primary, err := decodeOutput(codec, data)
+		if err != nil {
+			return CallResult{}, err
+		}
+		return CallResult{Primary: primary}, nil
+	}
+
+	if envelope.Primary == nil {
+		envelope.Primary = make(map[string]interface{})
+	}
+	return CallResult{Primary: envelope.Primary, Aux: envelope.Aux}, nil
+}
@@ -0,0 +1,116 @@
+package pforge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// ExecuteHandlerNDJSONFunc streams a handler's newline-delimited JSON result
+// and calls fn for each record as it arrives, using up to concurrency
+// workers. Records are not buffered beyond what the workers can absorb.
+//
+// The first error from fn stops the stream and is returned; otherwise any
+// stream error or the context error (see ExecuteHandlerContext) is
+// returned. fn may be called concurrently and in any order.
+func (b *Bridge) ExecuteHandlerNDJSONFunc(ctx context.Context, handlerName string, input map[string]interface{}, concurrency int, fn func(record json.RawMessage) error) error {
+	defer releaseCallHandle(ctx)
+
+	start := time.Now()
+	err := b.record(b.executeNDJSONFunc(ctx, handlerName, input, concurrency, fn))
+	b.finishStream(ctx, "handler stream", handlerName, start, err)
+	b.audit(ctx, "handler stream", handlerName, start, input, nil, err)
+	return err
+}
+
+func (b *Bridge) executeNDJSONFunc(ctx context.Context, handlerName string, input map[string]interface{}, concurrency int, fn func(record json.RawMessage) error) error {
+	ctx, cancelTimeout := b.handlerTimeout(ctx, handlerName)
+	defer cancelTimeout()
+
+	stream, err := b.openStream(handlerName, input)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	release := stream.watch(ctx)
+	defer release()
+
+	reader := bufio.NewReader(&streamReader{stream: stream})
+	next := func() (json.RawMessage, error) {
+		line, err := reader.ReadBytes('\n')
+		if record := bytes.TrimSpace(line); len(record) > 0 {
+			return json.RawMessage(record), err
+		}
+		return nil, err
+	}
+	return dispatchRecords(ctx, cancel, concurrency, next, fn)
+}
+
+// dispatchRecords calls fn for each record returned by next, using up to
+// concurrency workers, until next reports io.EOF or an error, fn fails or
+// ctx is done. next may return a final record together with its error.
+// cancel must cancel ctx; it is called on the first failure so the record
+// source can stop. The first error from fn or next is returned, otherwise
+// the context error.
+func dispatchRecords(ctx context.Context, cancel context.CancelFunc, concurrency int, next func() (json.RawMessage, error), fn func(record json.RawMessage) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		once     sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	records := make(chan json.RawMessage)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range records {
+				if err := fn(record); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	for ctx.Err() == nil {
+		record, err := next()
+		if record != nil {
+			select {
+			case records <- record:
+			case <-ctx.Done():
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				fail(err)
+			}
+			break
+		}
+	}
+	close(records)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return contextError(ctx)
+}
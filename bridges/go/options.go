@@ -0,0 +1,434 @@
+package pforge
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Option configures a Bridge
+type Option func(*Bridge)
+
+// WithProfilingLabels wraps each FFI call in pprof.Do with a "handler" label,
+// so CPU profiles attribute native time to the handler that was called.
+func WithProfilingLabels() Option {
+	return func(b *Bridge) {
+		b.profilingLabels = true
+	}
+}
+
+// WithLogger sets the logger for per-call log lines. A logger attached to
+// the call's context with ContextWithLogger takes precedence; without
+// either, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(b *Bridge) {
+		b.log = logger
+	}
+}
+
+// WithLockedThread runs every native call on one dedicated goroutine locked
+// to its OS thread with runtime.LockOSThread, for libraries with
+// thread-local state or other thread-affinity requirements.
+//
+// Calls are serialized through that thread, so throughput is limited to one
+// native call at a time and each call pays a goroutine handoff; leave it
+// off unless the library requires it. Stream cancellation still runs on the
+// caller's thread so it can interrupt a blocked read, and duplex streams
+// are not supported.
+func WithLockedThread() Option {
+	return func(b *Bridge) {
+		b.lockThread = true
+	}
+}
+
+// WithSpanWriter writes a JSON span record (see SpanRecord) per call to w,
+// one per line, for shipping to a trace collector without a full tracing
+// SDK. Calls made with a context from ContextWithSpan nest under that span.
+// Writes are serialized; w should not block.
+func WithSpanWriter(w io.Writer) Option {
+	return func(b *Bridge) {
+		b.spans = &spanWriter{w: w}
+	}
+}
+
+// WithTraceRecorder writes a TraceRecord per handler call to w, one per
+// line, producing the trace VerifyTrace replays. Inputs and results are
+// redacted as by Redact, so a trace holds RedactedValue in place of
+// WithSensitiveFields values. Calls whose input or result is not a map,
+// such as uploads and borrowed results, are not recorded. Writes are
+// serialized; w should not block.
+func WithTraceRecorder(w io.Writer) Option {
+	return func(b *Bridge) {
+		b.traces = &traceWriter{w: w}
+	}
+}
+
+// WithStrictUTF8 rejects input containing invalid UTF-8 in any string or
+// key with an *InputError, instead of letting JSON encoding replace the
+// bad bytes with U+FFFD. Every input is walked before serialization, so
+// this is off by default.
+func WithStrictUTF8() Option {
+	return func(b *Bridge) {
+		b.strictUTF8 = true
+	}
+}
+
+// WithSchemaCacheSize sets how many handler schemas are kept in the LRU
+// cache (default DefaultSchemaCacheSize)
+func WithSchemaCacheSize(size int) Option {
+	return func(b *Bridge) {
+		b.schemaCacheSize = size
+	}
+}
+
+// WithSchemaValidation validates every input against the handler's cached
+// input schema before crossing the FFI
+func WithSchemaValidation() Option {
+	return func(b *Bridge) {
+		b.validateInput = true
+	}
+}
+
+// WithClientIdentity adds {"name", "version"} as ClientField to every
+// handler input so the native side can attribute traffic. The field is
+// encoded once and spliced into each serialized input.
+func WithClientIdentity(name, version string) Option {
+	return func(b *Bridge) {
+		field, err := encodeField(ClientField, map[string]string{"name": name, "version": version})
+		if err != nil {
+			return
+		}
+		b.envelope = appendField(b.envelope, field)
+		b.envelopeKeys = append(b.envelopeKeys, ClientField)
+		b.clientIdentity = name + "/" + version
+	}
+}
+
+// WithResultSchemaVersion requests a result schema version from one handler
+// by adding SchemaVersionField to its inputs. The handler reports the
+// version it actually used in the same field of its result; read it with
+// ResultSchemaVersion.
+func WithResultSchemaVersion(handlerName string, version int) Option {
+	return func(b *Bridge) {
+		field, err := encodeField(SchemaVersionField, version)
+		if err != nil {
+			return
+		}
+		if b.handlerEnvelopes == nil {
+			b.handlerEnvelopes = make(map[string][]byte)
+		}
+		b.handlerEnvelopes[handlerName] = field
+		if b.handlerEnvelopeKeys == nil {
+			b.handlerEnvelopeKeys = make(map[string][]string)
+		}
+		b.handlerEnvelopeKeys[handlerName] = []string{SchemaVersionField}
+	}
+}
+
+// WithHandlerConcurrency limits how many calls to one handler run at once.
+// Further calls block until a call finishes, or until their context is done
+// for context-aware methods, and are let in by priority (see
+// ContextWithPriority), first come first served within a priority. A slot
+// is held until the native call returns, even if the caller's context
+// ended first. Handlers without a limit run unbounded.
+func WithHandlerConcurrency(handlerName string, max int) Option {
+	return func(b *Bridge) {
+		if max < 1 {
+			return
+		}
+		if b.handlerLimits == nil {
+			b.handlerLimits = make(map[string]*handlerSlots)
+		}
+		b.handlerLimits[handlerName] = newHandlerSlots(max)
+	}
+}
+
+// WithHandlerOrder sets how ListHandlers orders its result (default
+// HandlerOrderName). HandlerOrderNative keeps the native library's order.
+func WithHandlerOrder(order HandlerOrder) Option {
+	return func(b *Bridge) {
+		b.handlerOrder = order
+	}
+}
+
+// WithCloseGracePeriod sets how long Close waits for in-flight calls before
+// failing them (default DefaultCloseGracePeriod)
+func WithCloseGracePeriod(d time.Duration) Option {
+	return func(b *Bridge) {
+		b.closeGracePeriod = d
+	}
+}
+
+// WithDuplexWindow sets the duplex flow-control window, the number of inputs
+// that may await a result before sends block (default DuplexMaxInFlight)
+func WithDuplexWindow(size int) Option {
+	return func(b *Bridge) {
+		b.duplexWindow = size
+	}
+}
+
+// WithDefaultCodec sets the codec preference for the startup handshake,
+// most preferred first (default DefaultCodecPreference). The first codec
+// that both the Bridge and the native library support becomes ActiveCodec.
+func WithDefaultCodec(preference ...Codec) Option {
+	return func(b *Bridge) {
+		b.codecs = preference
+	}
+}
+
+// WithHandlerCodec makes one handler use codec instead of the negotiated
+// ActiveCodec. The override is checked at construction against the codecs
+// the native handler reports (pforge_handler_codecs), and fails like the
+// global handshake if either side lacks the codec. An override equal to
+// ActiveCodec needs no native support. Later calls for the same handler win.
+func WithHandlerCodec(handlerName string, codec Codec) Option {
+	return func(b *Bridge) {
+		if b.handlerCodecs == nil {
+			b.handlerCodecs = make(map[string]Codec)
+		}
+		b.handlerCodecs[handlerName] = codec
+	}
+}
+
+// WithBatchSize sets how many inputs ExecuteBatch sends per native batch
+// (default DefaultBatchSize)
+func WithBatchSize(size int) Option {
+	return func(b *Bridge) {
+		b.batchSize = size
+	}
+}
+
+// WithAdaptiveBatching makes ExecuteBatch tune its batch size toward a
+// target latency window, overriding WithBatchSize. Stats reports the
+// current size. See AdaptiveBatching.
+func WithAdaptiveBatching(config AdaptiveBatching) Option {
+	return func(b *Bridge) {
+		b.batchTuner = newBatchTuner(config)
+	}
+}
+
+// WithStrictReservedFields makes calls fail with ErrReservedField when the
+// input sets a field the Bridge injects (DeadlineField, SeedField,
+// ProjectionField, IdempotencyKeyField, LocaleField, CorrelationField or an
+// envelope field such as ClientField). By default the Bridge's value
+// replaces the caller's and a warning is logged.
+func WithStrictReservedFields() Option {
+	return func(b *Bridge) {
+		b.strictReserved = true
+	}
+}
+
+// WithDefaultTimeout sets the timeout applied to ExecuteHandler and to
+// context-aware calls whose context has no deadline, for handlers without
+// their own timeout from WithHandlerTimeout. Zero, the default, means no
+// timeout.
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(b *Bridge) {
+		b.defaultTimeout = timeout
+	}
+}
+
+// WithHandlerTimeout sets per-handler default timeouts, applied like
+// WithDefaultTimeout when the caller's context has no deadline. A zero
+// timeout exempts a handler from the default. Calls to the option merge,
+// with later entries winning.
+func WithHandlerTimeout(timeouts map[string]time.Duration) Option {
+	return func(b *Bridge) {
+		if b.handlerTimeouts == nil {
+			b.handlerTimeouts = make(map[string]time.Duration, len(timeouts))
+		}
+		for name, timeout := range timeouts {
+			b.handlerTimeouts[name] = timeout
+		}
+	}
+}
+
+// WithRecoverHandler sets the function that decides the error returned when
+// a panic is recovered around a native call. By default the call fails with
+// an error wrapping ErrHandlerPanic. Panics inside native handlers are
+// caught by the native library and always reported as ErrHandlerPanic.
+func WithRecoverHandler(fn RecoverFunc) Option {
+	return func(b *Bridge) {
+		b.recoverHandler = fn
+	}
+}
+
+// WithStreamRateLimit caps the rate at which streamed results are
+// delivered, in bytes per second, so a fast handler cannot saturate a slow
+// downstream link. Chunks are held back, not split, and waiting stops when
+// the call's context is done. Override it per stream with
+// ContextWithStreamRateLimit.
+func WithStreamRateLimit(bytesPerSec int) Option {
+	return func(b *Bridge) {
+		b.streamRateLimit = bytesPerSec
+	}
+}
+
+// WithFanOutConcurrency sets how many handlers one FanOut call runs at once
+// (default DefaultFanOutConcurrency)
+func WithFanOutConcurrency(n int) Option {
+	return func(b *Bridge) {
+		b.fanOutConcurrency = n
+	}
+}
+
+// WithAuditSink calls sink with an AuditEvent after every handler call,
+// including failed and rejected ones. Batch calls report one event per
+// input. sink runs on the calling goroutine before the call returns, so it
+// should hand events off rather than block.
+func WithAuditSink(sink func(AuditEvent)) Option {
+	return func(b *Bridge) {
+		b.auditSink = sink
+	}
+}
+
+// WithDegradedMode lets a Bridge be constructed when its native library
+// cannot be loaded, so a service can start and report itself unhealthy
+// instead of failing. The constructor then returns a Bridge whose calls all
+// fail with ErrBridgeUnavailable, logs a warning with the reason to the
+// WithLogger logger or else slog.Default, and Available reports false. See
+// NewBridgeWithLibrary and NewBridge.
+func WithDegradedMode() Option {
+	return func(b *Bridge) {
+		b.degradedMode = true
+	}
+}
+
+// WithAdmissionControl sheds load instead of queueing it: once maxQueue
+// handler calls are running or waiting for a concurrency slot, new calls
+// fail immediately with ErrOverloaded. Streams and duplex calls are not
+// counted. Stats reports the current depth as Queued; change the limit at
+// runtime with SetAdmissionLimit. Zero, the default, means no limit.
+func WithAdmissionControl(maxQueue int) Option {
+	return func(b *Bridge) {
+		b.SetAdmissionLimit(maxQueue)
+	}
+}
+
+// WithStreamIdleTimeout fails a streamed call with ErrStreamIdle when the
+// handler sends neither a chunk nor a keepalive for timeout, telling a
+// stuck handler apart from a slow one that keeps the stream alive. Only
+// time spent waiting on the handler counts, not rate limiting or a slow
+// reader. It is separate from the call's overall deadline. Zero, the
+// default, means no idle timeout.
+func WithStreamIdleTimeout(timeout time.Duration) Option {
+	return func(b *Bridge) {
+		b.streamIdleTimeout = timeout
+	}
+}
+
+// WithRequestBodyLimit sets the largest request body, in bytes, that
+// ExecuteHandlerFromRequest reads (default DefaultRequestBodyLimit)
+func WithRequestBodyLimit(limit int64) Option {
+	return func(b *Bridge) {
+		b.requestBodyLimit = limit
+	}
+}
+
+// WithJSONCodec makes the Bridge encode inputs and decode results with codec
+// instead of encoding/json (default StdJSON), e.g. to use a faster library.
+// See JSONCodec for what the codec must support.
+func WithJSONCodec(codec JSONCodec) Option {
+	return func(b *Bridge) {
+		b.jsonCodec = codec
+	}
+}
+
+// WithLibraryChecksum makes NewBridgeWithLibrary and NewBridgeFromFS
+// compute the SHA-256 of the library file and refuse to load it, failing
+// with ErrChecksumMismatch, unless it matches sha256hex. This guards
+// against loading a tampered or wrong binary; the file is hashed just
+// before dlopen, so it must not be writable by anyone untrusted in between.
+// NewBridge uses the library linked at build time and ignores the option.
+func WithLibraryChecksum(sha256hex string) Option {
+	return func(b *Bridge) {
+		b.libraryChecksum = sha256hex
+	}
+}
+
+// WithUnsafeSkipLibraryChecksum disables the WithLibraryChecksum check,
+// e.g. for local builds of the library, and logs a warning on the Bridge
+// logger each time a library is loaded unverified. Never use it in
+// production.
+func WithUnsafeSkipLibraryChecksum() Option {
+	return func(b *Bridge) {
+		b.skipLibraryChecksum = true
+	}
+}
+
+// WithFanOutCancelOnFirstError makes FanOut cancel its remaining calls as
+// soon as one handler fails and report only that first failure, as an
+// errgroup would. By default every handler runs to completion and all
+// failures are reported.
+func WithFanOutCancelOnFirstError() Option {
+	return func(b *Bridge) {
+		b.fanOutCancelOnError = true
+	}
+}
+
+// WithLocale adds tag, a BCP 47 language tag such as "de-CH", to every
+// handler input as LocaleField, so handlers can localize their messages
+// and errors; ContextWithLocale overrides it per call. A malformed tag makes
+// calls fail with an error wrapping ErrInvalidLocale, so check tags from
+// configuration with ValidateLocale first. Localization is up to each
+// handler; see ResultLocale for the locale one actually used.
+func WithLocale(tag string) Option {
+	return func(b *Bridge) {
+		b.locale = tag
+	}
+}
+
+// WithCircuitBreaker gives every handler a circuit breaker: after threshold
+// consecutive failures the handler's calls fail fast with ErrCircuitOpen
+// for cooldown, then one trial call is let through, closing the circuit if
+// it succeeds and reopening it if not. Handler errors, panics and missed
+// deadlines count as failures; cancellations, bad input and rejections by
+// the Bridge do not. Streams are not rejected while the circuit is open
+// and do not count as the trial call, but their failures are counted while
+// it is closed. See BreakerStates.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(b *Bridge) {
+		if threshold < 1 {
+			return
+		}
+		b.breakers = &breakers{threshold: threshold, cooldown: cooldown, handlers: make(map[string]*breaker)}
+	}
+}
+
+// WithSensitiveFields marks input and output fields holding secrets or
+// personal data, as dotted paths such as "token" or "auth.password"; a
+// path through an array applies to each element. The Bridge then never
+// lets their values reach observability: audit hashes are computed over
+// inputs and outputs with the values replaced by RedactedValue, and schema
+// validation errors about them, which are logged, omit the value. Handlers
+// still receive the real values. Use Redact to apply the same redaction
+// before logging inputs or outputs yourself.
+func WithSensitiveFields(paths []string) Option {
+	return func(b *Bridge) {
+		for _, path := range paths {
+			if path != "" {
+				b.sensitive = append(b.sensitive, strings.Split(path, "."))
+			}
+		}
+	}
+}
+
+// WithStub makes calls to handlerName return response, or fail with err if
+// it is non-nil, without calling the native library, for testing routing
+// and middleware against a real Bridge with some handlers stubbed. The stub
+// takes precedence over a native handler of the same name, which is never
+// called, and the last WithStub for a name wins. Everything else on the
+// call path still applies: input is validated and marshalled, admission,
+// priorities and circuit breakers are checked, and the call is counted,
+// logged and audited. Single calls and batches are stubbed; streams are
+// not. A stubbed err is returned as is, so it can be a sentinel such as
+// ErrHandlerFailed or a *HandlerError.
+func WithStub(handlerName string, response map[string]interface{}, err error) Option {
+	return func(b *Bridge) {
+		if b.stubs == nil {
+			b.stubs = make(map[string]stub)
+		}
+		b.stubs[handlerName] = stub{response: response, err: err}
+	}
+}
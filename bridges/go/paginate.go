@@ -0,0 +1,134 @@
+package pforge
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Pager iterates over the items of a cursor-paginated handler, fetching
+// pages as needed. Create one with Bridge.Paginate:
+//
+//	it := bridge.Paginate(ctx, "list_users", input, "next_cursor", "users")
+//	for it.Next() {
+//		user := it.Item()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Pager struct {
+	bridge      *Bridge
+	ctx         context.Context
+	handlerName string
+	input       map[string]interface{}
+	cursorField string
+	itemsField  string
+
+	items  []interface{}
+	item   interface{}
+	cursor interface{}
+	page   int
+	last   bool
+	err    error
+}
+
+// Paginate calls a handler and yields the items in each result's itemsField,
+// re-calling it with the result's cursorField copied into the input until a
+// result has no cursor (missing, null or ""). The first call uses input as
+// given, so a caller can resume from a known cursor by setting it there.
+//
+// Each page is a separate ExecuteHandlerContext call, so ctx cancellation
+// stops iteration between pages as well as during one. An error on any page
+// ends iteration and is reported by Err, wrapped with the page number.
+func (b *Bridge) Paginate(ctx context.Context, handlerName string, input map[string]interface{}, cursorField, itemsField string) *Pager {
+	return &Pager{
+		bridge:      b,
+		ctx:         ctx,
+		handlerName: handlerName,
+		input:       input,
+		cursorField: cursorField,
+		itemsField:  itemsField,
+	}
+}
+
+// Next advances to the next item, fetching further pages when the current
+// one is used up. It returns false when the items are exhausted or an error
+// occurs.
+func (p *Pager) Next() bool {
+	for len(p.items) == 0 {
+		if p.err != nil || p.last {
+			p.item = nil
+			return false
+		}
+		p.fetch()
+	}
+
+	p.item, p.items = p.items[0], p.items[1:]
+	return true
+}
+
+// Item returns the current item
+func (p *Pager) Item() interface{} {
+	return p.item
+}
+
+// Page returns how many pages have been fetched so far
+func (p *Pager) Page() int {
+	return p.page
+}
+
+// Cursor returns the cursor that will be sent for the next page, or nil
+// once the last page has been fetched. Together with Page it lets a caller
+// checkpoint iteration and resume it later.
+func (p *Pager) Cursor() interface{} {
+	return p.cursor
+}
+
+// Err returns the error that stopped iteration, if any
+func (p *Pager) Err() error {
+	return p.err
+}
+
+// fetch calls the handler for the next page
+func (p *Pager) fetch() {
+	if err := p.ctx.Err(); err != nil {
+		p.err = fmt.Errorf("page %d: %w", p.page+1, contextError(p.ctx))
+		return
+	}
+
+	input := p.input
+	if p.page > 0 {
+		input = withField(input, p.cursorField, p.cursor)
+	}
+
+	output, err := p.bridge.ExecuteHandlerContext(p.ctx, p.handlerName, input)
+	p.page++
+	if err != nil {
+		p.err = fmt.Errorf("page %d: %w", p.page, err)
+		return
+	}
+
+	switch items := output[p.itemsField].(type) {
+	case []interface{}:
+		p.items = items
+	case nil:
+		p.items = nil
+	default:
+		p.err = fmt.Errorf("page %d: field %q is %T, not an array", p.page, p.itemsField, items)
+		return
+	}
+
+	cursor := output[p.cursorField]
+	if cursor == nil || cursor == "" {
+		p.cursor = nil
+		p.last = true
+		return
+	}
+	if p.page > 1 && reflect.DeepEqual(cursor, p.cursor) {
+		// A handler repeating its cursor would otherwise loop forever
+		p.err = fmt.Errorf("page %d: handler %s returned the same cursor again", p.page, p.handlerName)
+		return
+	}
+	p.cursor = cursor
+}
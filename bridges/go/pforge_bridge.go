@@ -1,43 +1,343 @@
 package pforge
 
 /*
-#cgo LDFLAGS: -L../../target/release -lpforge_bridge
-#include <stdlib.h>
-
-typedef struct {
-    int code;
-    unsigned char* data;
-    size_t data_len;
-    const char* error;
-} FfiResult;
-
-extern const char* pforge_version();
-extern FfiResult pforge_execute_handler(const char* handler_name, const unsigned char* input_json, size_t input_len);
-extern void pforge_free_result(FfiResult result);
+#cgo LDFLAGS: -L../../target/release -lpforge_bridge -ldl
+#include "pforge_bridge.h"
 */
 import "C"
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
+	"log/slog"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
 	"unsafe"
 )
 
+// nativeEntry selects which FFI entry point receives a payload
+type nativeEntry int
+
+const (
+	entryExecute nativeEntry = iota
+	entryMultipart
+)
+
 // Bridge provides Go interface to pforge FFI
-type Bridge struct{}
+type Bridge struct {
+	stats           counters
+	admission       admission
+	profilingLabels bool
+	lockThread      bool
+	log             *slog.Logger
+	auditSink       func(AuditEvent)
+	clientIdentity  string
+	recoverHandler  RecoverFunc
+	spans           *spanWriter
+	traces          *traceWriter
+	jsonCodec       JSONCodec
+
+	schemas         *lru[string, *cachedSchema]
+	schemaCacheSize int
+	validateInput   bool
+	strictUTF8      bool
+	strictReserved  bool
+
+	// catalog caches the result of Catalog until RefreshSchemas
+	catalogMu sync.Mutex
+	catalog   Catalog
+	// limits caches the result of Limits
+	limitsMu sync.Mutex
+	limits   *Limits
+
+	// settings guards the fields ApplyConfig changes after construction:
+	// duplexWindow, streamRateLimit, streamIdleTimeout, batchSize,
+	// fanOutConcurrency, defaultTimeout, handlerTimeouts, handlerLimits and
+	// closeGracePeriod
+	settings          sync.RWMutex
+	duplexWindow      int
+	streamRateLimit   int
+	streamIdleTimeout time.Duration
+	batchSize         int
+	batchTuner        *batchTuner
+	fanOutConcurrency int
+	requestBodyLimit  int64
+	handlerOrder      HandlerOrder
+	defaultTimeout    time.Duration
+	// handlerTimeouts overrides defaultTimeout per handler
+	handlerTimeouts map[string]time.Duration
+	codecs          []Codec
+	// handlerCodecs holds the WithHandlerCodec overrides
+	handlerCodecs map[string]Codec
+	// codec is the negotiated codec; initErr fails every call if the
+	// handshake in NewBridge failed (see handshake)
+	codec   Codec
+	initErr error
+	// unavailable fails every call when the Bridge is running degraded
+	unavailable  error
+	degradedMode bool
+	// handlerLimits holds the slots of each handler with a concurrency limit
+	handlerLimits map[string]*handlerSlots
+	// thread serializes native calls when WithLockedThread is set
+	thread *lockedThread
+
+	// syms and handle are set when the library was loaded with dlopen
+	syms   *C.PforgeSymbols
+	handle unsafe.Pointer
+	// extractDir holds the library extracted by NewBridgeFromFS
+	extractDir string
+	// libraryChecksum is the hex SHA-256 a loaded library must match
+	libraryChecksum     string
+	skipLibraryChecksum bool
 
-// Version returns the pforge version
+	life             lifecycle
+	calls            callRegistry
+	closeGracePeriod time.Duration
+
+	// envelope holds pre-encoded fields added to every input, and
+	// handlerEnvelopes those added to one handler's inputs; the Keys
+	// fields name them for reserveFields
+	envelope            []byte
+	handlerEnvelopes    map[string][]byte
+	envelopeKeys        []string
+	handlerEnvelopeKeys map[string][]string
+	// locale is the WithLocale tag, sent unless a call overrides it
+	locale string
+	// breakers is set by WithCircuitBreaker
+	breakers *breakers
+	// sensitive holds the WithSensitiveFields paths, split on "."
+	sensitive [][]string
+
+	// fanOutCancelOnError is set by WithFanOutCancelOnFirstError
+	fanOutCancelOnError bool
+
+	// stubs holds the WithStub results, by handler name
+	stubs map[string]stub
+}
+
+// Version returns the pforge version, or "" once the Bridge is closed
 func (b *Bridge) Version() string {
-	cVersion := C.pforge_version()
-	return C.GoString(cVersion)
+	if b.enter() != nil {
+		return ""
+	}
+	defer b.leave()
+
+	var version string
+	b.native(func() {
+		version = C.GoString(C.pforge_call_version(b.symbols()))
+	})
+	return version
 }
 
-// ExecuteHandler calls a pforge handler with JSON input
+// VersionContext is Version bounded by ctx, for health checks that must not
+// hang on a stuck native library. The native call runs on its own goroutine;
+// if ctx is done first, VersionContext returns the context error (see
+// ExecuteHandlerContext) and the call is left to finish in the background.
+// Unlike Version it reports a closed Bridge as ErrBridgeClosed.
+func (b *Bridge) VersionContext(ctx context.Context) (string, error) {
+	if ctx.Err() != nil {
+		return "", contextError(ctx)
+	}
+	if err := b.enter(); err != nil {
+		return "", err
+	}
+	ctx, cancel := b.scope(ctx)
+	defer cancel()
+
+	done := make(chan string, 1)
+	go func() {
+		defer b.leave()
+		var version string
+		b.native(func() {
+			version = C.GoString(C.pforge_call_version(b.symbols()))
+		})
+		done <- version
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", contextError(ctx)
+	case version := <-done:
+		return version, nil
+	}
+}
+
+// ExecuteHandler calls a pforge handler with JSON input. If the handler has
+// a default timeout (see WithHandlerTimeout) the call is made as by
+// ExecuteHandlerContext with a background context, so the timeout applies.
 func (b *Bridge) ExecuteHandler(handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	if b.timeoutFor(handlerName) > 0 {
+		return b.ExecuteHandlerContext(context.Background(), handlerName, input)
+	}
+
+	start := time.Now()
+	output, err := b.observe(b.executeHandler(handlerName, input))
+	b.finishCall(context.Background(), "handler call", handlerName, start, err)
+	b.audit(context.Background(), "handler call", handlerName, start, input, output, err)
+	return output, err
+}
+
+func (b *Bridge) executeHandler(handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	release, err := b.acquireHandler(context.Background(), handlerName)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return b.executeAcquired(handlerName, input)
+}
+
+// executeAcquired is executeHandler for callers already holding the
+// handler's concurrency slot
+func (b *Bridge) executeAcquired(handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	inputJSON, err := b.marshalInput(handlerName, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.call(entryExecute, handlerName, inputJSON)
+}
+
+// marshalInput validates and serializes handler input, adding the
+// Bridge-level envelope fields
+func (b *Bridge) marshalInput(handlerName string, input map[string]interface{}) ([]byte, error) {
+	return b.marshalInputLocale(handlerName, input, b.locale)
+}
+
+// marshalInputLocale is marshalInput sending locale as LocaleField in place
+// of the Bridge's locale; an empty locale sends none
+func (b *Bridge) marshalInputLocale(handlerName string, input map[string]interface{}, locale string) ([]byte, error) {
+	var injected []string
+	if locale != "" {
+		if err := ValidateLocale(locale); err != nil {
+			return nil, err
+		}
+		injected = append(injected, LocaleField)
+	}
+
+	input, err := b.reserveFields(handlerName, input, injected...)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.strictUTF8 {
+		if err := checkInput(input, true); err != nil {
+			return nil, err
+		}
+	}
+
 	// Serialize input to JSON
-	inputJSON, err := json.Marshal(input)
+	inputJSON, err := b.jsonCodec.Marshal(input)
+	if err != nil {
+		if inputErr := checkInput(input, false); inputErr != nil {
+			return nil, inputErr
+		}
+		return nil, &InputError{Path: "$", Message: err.Error()}
+	}
+
+	if b.validateInput {
+		if err := b.ValidateInput(handlerName, input); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.envelope != nil {
+		if inputJSON, err = spliceFields(inputJSON, b.envelope); err != nil {
+			return nil, err
+		}
+	}
+	if fields, ok := b.handlerEnvelopes[handlerName]; ok {
+		if inputJSON, err = spliceFields(inputJSON, fields); err != nil {
+			return nil, err
+		}
+	}
+	if locale != "" {
+		field, err := encodeField(LocaleField, locale)
+		if err != nil {
+			return nil, err
+		}
+		if inputJSON, err = spliceFields(inputJSON, field); err != nil {
+			return nil, err
+		}
+	}
+	return inputJSON, nil
+}
+
+// observe records the outcome of a public call in the Bridge counters
+func (b *Bridge) observe(output map[string]interface{}, err error) (map[string]interface{}, error) {
+	return output, b.record(err)
+}
+
+// record counts a call, and its error if any, returning err unchanged
+func (b *Bridge) record(err error) error {
+	b.stats.calls.Add(1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal input: %w", err)
+		b.stats.errors.Add(1)
+	}
+	return err
+}
+
+// call passes a serialized payload to a native entry point and decodes the result
+func (b *Bridge) call(entry nativeEntry, handlerName string, payload []byte) (map[string]interface{}, error) {
+	var output map[string]interface{}
+	err := b.invoke(entry, handlerName, payload, func(result C.FfiResult) error {
+		var err error
+		output, err = b.decodeResult(handlerName, result)
+		return err
+	})
+	return output, err
+}
+
+// callRaw is like call but returns the undecoded result bytes
+func (b *Bridge) callRaw(entry nativeEntry, handlerName string, payload []byte) ([]byte, error) {
+	var data []byte
+	err := b.invoke(entry, handlerName, payload, func(result C.FfiResult) error {
+		var err error
+		data, err = resultData(result)
+		b.stats.bytesOut.Add(uint64(len(data)))
+		return err
+	})
+	return data, err
+}
+
+// invoke passes a payload to a native entry point and hands the result to
+// consume before it is freed
+func (b *Bridge) invoke(entry nativeEntry, handlerName string, payload []byte, consume func(C.FfiResult) error) error {
+	if err := b.enter(); err != nil {
+		return err
+	}
+	defer b.leave()
+
+	var err error
+	run := func() {
+		defer b.recoverPanic(handlerName, &err)
+		err = b.invokeNative(entry, handlerName, payload, consume)
+	}
+	if b.profilingLabels {
+		// Labels are per goroutine, so apply them where the call runs
+		unlabelled := run
+		run = func() {
+			pprof.Do(context.Background(), pprof.Labels("handler", handlerName), func(context.Context) {
+				unlabelled()
+			})
+		}
+	}
+
+	b.native(run)
+	return err
+}
+
+func (b *Bridge) invokeNative(entry nativeEntry, handlerName string, payload []byte, consume func(C.FfiResult) error) error {
+	if s, ok := b.stubs[handlerName]; ok {
+		return b.invokeStub(s, handlerName, payload, consume)
+	}
+
+	if entry != entryMultipart {
+		var err error
+		if payload, err = b.wireInput(handlerName, payload); err != nil {
+			return err
+		}
 	}
 
 	// Convert Go string to C string
@@ -45,38 +345,131 @@ func (b *Bridge) ExecuteHandler(handlerName string, input map[string]interface{}
 	defer C.free(unsafe.Pointer(cHandlerName))
 
 	// Call FFI
-	result := C.pforge_execute_handler(
-		cHandlerName,
-		(*C.uchar)(unsafe.Pointer(&inputJSON[0])),
-		C.size_t(len(inputJSON)),
-	)
-	defer C.pforge_free_result(result)
-
-	// Check for errors
-	if result.code != 0 {
-		if result.error != nil {
-			errorMsg := C.GoString(result.error)
-			return nil, fmt.Errorf("handler execution failed (code %d): %s", result.code, errorMsg)
+	syms := b.symbols()
+	var result C.FfiResult
+	switch entry {
+	case entryMultipart:
+		if !b.supports(FeatureMultipart) {
+			return fmt.Errorf("%w: multipart input", ErrNotSupported)
 		}
-		return nil, fmt.Errorf("handler execution failed with code %d", result.code)
+		result = C.pforge_call_execute_handler_multipart(
+			syms,
+			cHandlerName,
+			(*C.uchar)(unsafe.Pointer(&payload[0])),
+			C.size_t(len(payload)),
+		)
+	default:
+		result = C.pforge_call_execute_handler(
+			syms,
+			cHandlerName,
+			(*C.uchar)(unsafe.Pointer(&payload[0])),
+			C.size_t(len(payload)),
+		)
+	}
+	defer C.pforge_call_free_result(syms, result)
+	b.stats.bytesIn.Add(uint64(len(payload)))
+	b.stats.codes.observe(handlerName, int(result.code))
+
+	return consume(result)
+}
+
+// decodeResult converts a native result from handlerName into a Go map.
+// The caller remains responsible for freeing the result.
+func (b *Bridge) decodeResult(handlerName string, result C.FfiResult) (map[string]interface{}, error) {
+	resultBytes, err := resultData(result)
+	if err != nil {
+		return nil, err
 	}
+	b.stats.bytesOut.Add(uint64(len(resultBytes)))
 
+	return decodeOutput(b.wireCodec(handlerName), resultBytes)
+}
+
+// decodeOutput unmarshals result bytes with codec, treating an empty result
+// as an empty map
+func decodeOutput(codec JSONCodec, resultBytes []byte) (map[string]interface{}, error) {
 	// Extract result data
-	if result.data == nil || result.data_len == 0 {
+	if resultBytes == nil {
 		return make(map[string]interface{}), nil
 	}
 
-	resultBytes := C.GoBytes(unsafe.Pointer(result.data), C.int(result.data_len))
-
 	var output map[string]interface{}
-	if err := json.Unmarshal(resultBytes, &output); err != nil {
+	if err := codec.Unmarshal(resultBytes, &output); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal result: %w", err)
 	}
 
 	return output, nil
 }
 
-// NewBridge creates a new pforge bridge instance
-func NewBridge() *Bridge {
-	return &Bridge{}
+// resultData checks a native result for errors and copies out its data.
+// It returns nil data when the result is empty.
+func resultData(result C.FfiResult) ([]byte, error) {
+	if err := resultError(result); err != nil {
+		return nil, err
+	}
+
+	if result.data == nil || result.data_len == 0 {
+		return nil, nil
+	}
+
+	return C.GoBytes(unsafe.Pointer(result.data), C.int(result.data_len)), nil
+}
+
+// resultError returns the error a native result reports, if any
+func resultError(result C.FfiResult) error {
+	if result.code == 0 {
+		return nil
+	}
+	var errorMsg string
+	if result.error != nil {
+		errorMsg = C.GoString(result.error)
+	}
+	return newHandlerError(int(result.code), errorMsg, nativeErrorKind(int(result.code)))
+}
+
+// NewBridge creates a new pforge bridge instance.
+//
+// If the linked library reports a different ABI version (see ABIVersion)
+// or codec negotiation fails (see WithDefaultCodec), every call on the
+// returned Bridge fails with ErrABIMismatch or ErrNoCommonCodec; use
+// NewBridgeWithLibrary to get the error at construction instead.
+//
+// With WithDegradedMode, a linked library lacking required symbols, or a
+// failed handshake, makes calls fail with ErrBridgeUnavailable instead.
+// A linked library missing entirely stops the process before NewBridge
+// runs; use NewBridgeWithLibrary to survive that.
+func NewBridge(opts ...Option) *Bridge {
+	b := newBridge(opts...)
+	if b.degradedMode {
+		if missing := missingSymbols(linkedSymbols()); len(missing) > 0 {
+			b.degrade(fmt.Errorf("linked native library is missing symbols: %s", strings.Join(missing, ", ")))
+			return b
+		}
+		if err := b.handshake(); err != nil {
+			b.degrade(err)
+		}
+		return b
+	}
+	b.initErr = b.handshake()
+	return b
+}
+
+// newBridge applies options without touching the native library
+func newBridge(opts ...Option) *Bridge {
+	b := &Bridge{
+		schemaCacheSize:  DefaultSchemaCacheSize,
+		closeGracePeriod: DefaultCloseGracePeriod,
+		life:             newLifecycle(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.lockThread {
+		b.thread = newLockedThread()
+	}
+	if b.jsonCodec == nil {
+		b.jsonCodec = StdJSON
+	}
+	b.schemas = newLRU[string, *cachedSchema](b.schemaCacheSize)
+	return b
 }
@@ -0,0 +1,287 @@
+#ifndef PFORGE_BRIDGE_H
+#define PFORGE_BRIDGE_H
+
+#include <dlfcn.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+typedef struct {
+    int code;
+    unsigned char* data;
+    size_t data_len;
+    const char* error;
+} FfiResult;
+
+extern const char* pforge_version();
+extern FfiResult pforge_execute_handler(const char* handler_name, const unsigned char* input_json, size_t input_len);
+extern void pforge_free_result(FfiResult result);
+
+// Optional entry points are declared weak so older native libraries still link
+extern FfiResult pforge_execute_handler_multipart(const char* handler_name, const unsigned char* frame, size_t frame_len) __attribute__((weak));
+
+extern void* pforge_duplex_open(const char* handler_name) __attribute__((weak));
+extern int pforge_duplex_send(void* stream, const unsigned char* input_json, size_t input_len) __attribute__((weak));
+extern FfiResult pforge_duplex_recv(void* stream) __attribute__((weak));
+extern void pforge_duplex_close(void* stream) __attribute__((weak));
+extern void pforge_duplex_cancel(void* stream) __attribute__((weak));
+extern void pforge_duplex_free(void* stream) __attribute__((weak));
+extern size_t pforge_duplex_window(void* stream) __attribute__((weak));
+
+extern FfiResult pforge_list_handlers() __attribute__((weak));
+extern FfiResult pforge_handler_schema(const char* handler_name) __attribute__((weak));
+extern FfiResult pforge_catalog() __attribute__((weak));
+extern FfiResult pforge_limits() __attribute__((weak));
+
+extern void* pforge_stream_open(const char* handler_name, const unsigned char* input_json, size_t input_len) __attribute__((weak));
+extern FfiResult pforge_stream_next(void* stream) __attribute__((weak));
+extern void pforge_stream_cancel(void* stream) __attribute__((weak));
+extern void pforge_stream_free(void* stream) __attribute__((weak));
+
+extern FfiResult pforge_codecs() __attribute__((weak));
+extern int pforge_select_codec(const char* codec) __attribute__((weak));
+extern FfiResult pforge_handler_codecs(const char* handler_name) __attribute__((weak));
+extern int pforge_select_handler_codec(const char* handler_name, const char* codec) __attribute__((weak));
+
+extern void pforge_free_results(FfiResult* results, size_t count) __attribute__((weak));
+
+extern void* pforge_upload_open(const char* handler_name) __attribute__((weak));
+extern int pforge_upload_write(void* upload, const unsigned char* chunk, size_t chunk_len) __attribute__((weak));
+extern FfiResult pforge_upload_finish(void* upload) __attribute__((weak));
+extern void pforge_upload_abort(void* upload) __attribute__((weak));
+
+extern uint32_t pforge_abi_version() __attribute__((weak));
+
+// Symbol table shared by linked and dynamically loaded libraries.
+// Required symbols come first; optional symbols may be NULL.
+enum {
+    SYM_VERSION,
+    SYM_EXECUTE_HANDLER,
+    SYM_FREE_RESULT,
+    SYM_REQUIRED_COUNT,
+    SYM_EXECUTE_HANDLER_MULTIPART = SYM_REQUIRED_COUNT,
+    SYM_DUPLEX_OPEN,
+    SYM_DUPLEX_SEND,
+    SYM_DUPLEX_RECV,
+    SYM_DUPLEX_CLOSE,
+    SYM_DUPLEX_CANCEL,
+    SYM_DUPLEX_FREE,
+    SYM_DUPLEX_WINDOW,
+    SYM_LIST_HANDLERS,
+    SYM_HANDLER_SCHEMA,
+    SYM_STREAM_OPEN,
+    SYM_STREAM_NEXT,
+    SYM_STREAM_CANCEL,
+    SYM_STREAM_FREE,
+    SYM_CODECS,
+    SYM_SELECT_CODEC,
+    SYM_HANDLER_CODECS,
+    SYM_SELECT_HANDLER_CODEC,
+    SYM_FREE_RESULTS,
+    SYM_UPLOAD_OPEN,
+    SYM_UPLOAD_WRITE,
+    SYM_UPLOAD_FINISH,
+    SYM_UPLOAD_ABORT,
+    SYM_ABI_VERSION,
+    SYM_CATALOG,
+    SYM_LIMITS,
+    SYM_COUNT
+};
+
+typedef struct {
+    void* fn[SYM_COUNT];
+} PforgeSymbols;
+
+static const char* const pforge_symbol_names[SYM_COUNT] = {
+    "pforge_version",
+    "pforge_execute_handler",
+    "pforge_free_result",
+    "pforge_execute_handler_multipart",
+    "pforge_duplex_open",
+    "pforge_duplex_send",
+    "pforge_duplex_recv",
+    "pforge_duplex_close",
+    "pforge_duplex_cancel",
+    "pforge_duplex_free",
+    "pforge_duplex_window",
+    "pforge_list_handlers",
+    "pforge_handler_schema",
+    "pforge_stream_open",
+    "pforge_stream_next",
+    "pforge_stream_cancel",
+    "pforge_stream_free",
+    "pforge_codecs",
+    "pforge_select_codec",
+    "pforge_handler_codecs",
+    "pforge_select_handler_codec",
+    "pforge_free_results",
+    "pforge_upload_open",
+    "pforge_upload_write",
+    "pforge_upload_finish",
+    "pforge_upload_abort",
+    "pforge_abi_version",
+    "pforge_catalog",
+    "pforge_limits",
+};
+
+static inline const char* pforge_symbol_name(int sym) { return pforge_symbol_names[sym]; }
+static inline int pforge_has(const PforgeSymbols* s, int sym) { return s->fn[sym] != NULL; }
+
+// pforge_link_symbols fills the table from the library linked at build time
+static inline void pforge_link_symbols(PforgeSymbols* s) {
+    s->fn[SYM_VERSION] = (void*)pforge_version;
+    s->fn[SYM_EXECUTE_HANDLER] = (void*)pforge_execute_handler;
+    s->fn[SYM_FREE_RESULT] = (void*)pforge_free_result;
+    s->fn[SYM_EXECUTE_HANDLER_MULTIPART] = (void*)pforge_execute_handler_multipart;
+    s->fn[SYM_DUPLEX_OPEN] = (void*)pforge_duplex_open;
+    s->fn[SYM_DUPLEX_SEND] = (void*)pforge_duplex_send;
+    s->fn[SYM_DUPLEX_RECV] = (void*)pforge_duplex_recv;
+    s->fn[SYM_DUPLEX_CLOSE] = (void*)pforge_duplex_close;
+    s->fn[SYM_DUPLEX_CANCEL] = (void*)pforge_duplex_cancel;
+    s->fn[SYM_DUPLEX_FREE] = (void*)pforge_duplex_free;
+    s->fn[SYM_DUPLEX_WINDOW] = (void*)pforge_duplex_window;
+    s->fn[SYM_LIST_HANDLERS] = (void*)pforge_list_handlers;
+    s->fn[SYM_HANDLER_SCHEMA] = (void*)pforge_handler_schema;
+    s->fn[SYM_STREAM_OPEN] = (void*)pforge_stream_open;
+    s->fn[SYM_STREAM_NEXT] = (void*)pforge_stream_next;
+    s->fn[SYM_STREAM_CANCEL] = (void*)pforge_stream_cancel;
+    s->fn[SYM_STREAM_FREE] = (void*)pforge_stream_free;
+    s->fn[SYM_CODECS] = (void*)pforge_codecs;
+    s->fn[SYM_SELECT_CODEC] = (void*)pforge_select_codec;
+    s->fn[SYM_HANDLER_CODECS] = (void*)pforge_handler_codecs;
+    s->fn[SYM_SELECT_HANDLER_CODEC] = (void*)pforge_select_handler_codec;
+    s->fn[SYM_FREE_RESULTS] = (void*)pforge_free_results;
+    s->fn[SYM_UPLOAD_OPEN] = (void*)pforge_upload_open;
+    s->fn[SYM_UPLOAD_WRITE] = (void*)pforge_upload_write;
+    s->fn[SYM_UPLOAD_FINISH] = (void*)pforge_upload_finish;
+    s->fn[SYM_UPLOAD_ABORT] = (void*)pforge_upload_abort;
+    s->fn[SYM_ABI_VERSION] = (void*)pforge_abi_version;
+    s->fn[SYM_CATALOG] = (void*)pforge_catalog;
+    s->fn[SYM_LIMITS] = (void*)pforge_limits;
+}
+
+// pforge_load_symbols fills the table from a dlopen handle
+static inline void pforge_load_symbols(void* handle, PforgeSymbols* s) {
+    for (int i = 0; i < SYM_COUNT; i++) {
+        s->fn[i] = dlsym(handle, pforge_symbol_names[i]);
+    }
+}
+
+// Trampolines calling through the symbol table
+
+static inline const char* pforge_call_version(const PforgeSymbols* s) {
+    return ((const char* (*)(void))s->fn[SYM_VERSION])();
+}
+
+static inline FfiResult pforge_call_execute_handler(const PforgeSymbols* s, const char* handler_name, const unsigned char* input_json, size_t input_len) {
+    return ((FfiResult (*)(const char*, const unsigned char*, size_t))s->fn[SYM_EXECUTE_HANDLER])(handler_name, input_json, input_len);
+}
+
+static inline void pforge_call_free_result(const PforgeSymbols* s, FfiResult result) {
+    ((void (*)(FfiResult))s->fn[SYM_FREE_RESULT])(result);
+}
+
+static inline FfiResult pforge_call_execute_handler_multipart(const PforgeSymbols* s, const char* handler_name, const unsigned char* frame, size_t frame_len) {
+    return ((FfiResult (*)(const char*, const unsigned char*, size_t))s->fn[SYM_EXECUTE_HANDLER_MULTIPART])(handler_name, frame, frame_len);
+}
+
+static inline void* pforge_call_duplex_open(const PforgeSymbols* s, const char* handler_name) {
+    return ((void* (*)(const char*))s->fn[SYM_DUPLEX_OPEN])(handler_name);
+}
+
+static inline int pforge_call_duplex_send(const PforgeSymbols* s, void* stream, const unsigned char* input_json, size_t input_len) {
+    return ((int (*)(void*, const unsigned char*, size_t))s->fn[SYM_DUPLEX_SEND])(stream, input_json, input_len);
+}
+
+static inline FfiResult pforge_call_duplex_recv(const PforgeSymbols* s, void* stream) {
+    return ((FfiResult (*)(void*))s->fn[SYM_DUPLEX_RECV])(stream);
+}
+
+static inline void pforge_call_duplex_close(const PforgeSymbols* s, void* stream) {
+    ((void (*)(void*))s->fn[SYM_DUPLEX_CLOSE])(stream);
+}
+
+static inline void pforge_call_duplex_cancel(const PforgeSymbols* s, void* stream) {
+    ((void (*)(void*))s->fn[SYM_DUPLEX_CANCEL])(stream);
+}
+
+static inline void pforge_call_duplex_free(const PforgeSymbols* s, void* stream) {
+    ((void (*)(void*))s->fn[SYM_DUPLEX_FREE])(stream);
+}
+
+static inline size_t pforge_call_duplex_window(const PforgeSymbols* s, void* stream) {
+    return ((size_t (*)(void*))s->fn[SYM_DUPLEX_WINDOW])(stream);
+}
+
+static inline FfiResult pforge_call_list_handlers(const PforgeSymbols* s) {
+    return ((FfiResult (*)(void))s->fn[SYM_LIST_HANDLERS])();
+}
+
+static inline FfiResult pforge_call_handler_schema(const PforgeSymbols* s, const char* handler_name) {
+    return ((FfiResult (*)(const char*))s->fn[SYM_HANDLER_SCHEMA])(handler_name);
+}
+
+static inline void* pforge_call_stream_open(const PforgeSymbols* s, const char* handler_name, const unsigned char* input_json, size_t input_len) {
+    return ((void* (*)(const char*, const unsigned char*, size_t))s->fn[SYM_STREAM_OPEN])(handler_name, input_json, input_len);
+}
+
+static inline FfiResult pforge_call_stream_next(const PforgeSymbols* s, void* stream) {
+    return ((FfiResult (*)(void*))s->fn[SYM_STREAM_NEXT])(stream);
+}
+
+static inline void pforge_call_stream_cancel(const PforgeSymbols* s, void* stream) {
+    ((void (*)(void*))s->fn[SYM_STREAM_CANCEL])(stream);
+}
+
+static inline void pforge_call_stream_free(const PforgeSymbols* s, void* stream) {
+    ((void (*)(void*))s->fn[SYM_STREAM_FREE])(stream);
+}
+
+static inline FfiResult pforge_call_codecs(const PforgeSymbols* s) {
+    return ((FfiResult (*)(void))s->fn[SYM_CODECS])();
+}
+
+static inline int pforge_call_select_codec(const PforgeSymbols* s, const char* codec) {
+    return ((int (*)(const char*))s->fn[SYM_SELECT_CODEC])(codec);
+}
+
+static inline FfiResult pforge_call_handler_codecs(const PforgeSymbols* s, const char* handler_name) {
+    return ((FfiResult (*)(const char*))s->fn[SYM_HANDLER_CODECS])(handler_name);
+}
+
+static inline int pforge_call_select_handler_codec(const PforgeSymbols* s, const char* handler_name, const char* codec) {
+    return ((int (*)(const char*, const char*))s->fn[SYM_SELECT_HANDLER_CODEC])(handler_name, codec);
+}
+
+static inline void pforge_call_free_results(const PforgeSymbols* s, FfiResult* results, size_t count) {
+    ((void (*)(FfiResult*, size_t))s->fn[SYM_FREE_RESULTS])(results, count);
+}
+
+static inline void* pforge_call_upload_open(const PforgeSymbols* s, const char* handler_name) {
+    return ((void* (*)(const char*))s->fn[SYM_UPLOAD_OPEN])(handler_name);
+}
+
+static inline int pforge_call_upload_write(const PforgeSymbols* s, void* upload, const unsigned char* chunk, size_t chunk_len) {
+    return ((int (*)(void*, const unsigned char*, size_t))s->fn[SYM_UPLOAD_WRITE])(upload, chunk, chunk_len);
+}
+
+static inline FfiResult pforge_call_upload_finish(const PforgeSymbols* s, void* upload) {
+    return ((FfiResult (*)(void*))s->fn[SYM_UPLOAD_FINISH])(upload);
+}
+
+static inline void pforge_call_upload_abort(const PforgeSymbols* s, void* upload) {
+    ((void (*)(void*))s->fn[SYM_UPLOAD_ABORT])(upload);
+}
+
+static inline uint32_t pforge_call_abi_version(const PforgeSymbols* s) {
+    return ((uint32_t (*)(void))s->fn[SYM_ABI_VERSION])();
+}
+
+static inline FfiResult pforge_call_catalog(const PforgeSymbols* s) {
+    return ((FfiResult (*)(void))s->fn[SYM_CATALOG])();
+}
+
+static inline FfiResult pforge_call_limits(const PforgeSymbols* s) {
+    return ((FfiResult (*)(void))s->fn[SYM_LIMITS])();
+}
+
+#endif
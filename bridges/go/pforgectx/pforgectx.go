@@ -0,0 +1,121 @@
+// Package pforgectx defines the context values propagated into pforge calls.
+//
+// Each value has one unexported key type and a typed setter and getter, so
+// every feature that reads or injects a value agrees on where it lives and
+// no two features can collide on an untyped string key. The pforge package
+// reads these values; its ContextWithLogger, ContextWithSpan,
+// ContextWithSeed, ContextWithProjection, ContextWithIdempotencyKey and
+// ContextWithLocale are thin wrappers around the setters here.
+package pforgectx
+
+import (
+	"context"
+	"log/slog"
+)
+
+type (
+	requestIDKey   struct{}
+	tenantKey      struct{}
+	loggerKey      struct{}
+	spanKey        struct{}
+	seedKey        struct{}
+	projectionKey  struct{}
+	idempotencyKey struct{}
+	localeKey      struct{}
+)
+
+// SpanContext identifies a span for nesting call spans under it
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// WithRequestID returns a copy of ctx carrying the caller's request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored by WithRequestID
+func RequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// WithTenant returns a copy of ctx carrying the tenant the call is made for
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns the tenant stored by WithTenant
+func Tenant(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+// WithLogger returns a copy of ctx carrying a request-scoped logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Logger returns the logger stored by WithLogger, or nil
+func Logger(ctx context.Context) *slog.Logger {
+	logger, _ := ctx.Value(loggerKey{}).(*slog.Logger)
+	return logger
+}
+
+// WithSpan returns a copy of ctx carrying the caller's span
+func WithSpan(ctx context.Context, span SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// Span returns the span stored by WithSpan
+func Span(ctx context.Context) (SpanContext, bool) {
+	span, ok := ctx.Value(spanKey{}).(SpanContext)
+	return span, ok
+}
+
+// WithSeed returns a copy of ctx carrying an RNG seed for handlers
+func WithSeed(ctx context.Context, seed int64) context.Context {
+	return context.WithValue(ctx, seedKey{}, seed)
+}
+
+// Seed returns the seed stored by WithSeed
+func Seed(ctx context.Context) (int64, bool) {
+	seed, ok := ctx.Value(seedKey{}).(int64)
+	return seed, ok
+}
+
+// WithProjection returns a copy of ctx carrying the result fields a call
+// asks for. fields is copied, so the caller may reuse it.
+func WithProjection(ctx context.Context, fields []string) context.Context {
+	return context.WithValue(ctx, projectionKey{}, append([]string(nil), fields...))
+}
+
+// Projection returns the fields stored by WithProjection
+func Projection(ctx context.Context) ([]string, bool) {
+	fields, ok := ctx.Value(projectionKey{}).([]string)
+	return fields, ok
+}
+
+// WithIdempotencyKey returns a copy of ctx carrying the idempotency key of
+// a logical operation
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKey returns the key stored by WithIdempotencyKey
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+	return key, ok
+}
+
+// WithLocale returns a copy of ctx carrying a BCP 47 locale tag
+func WithLocale(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, localeKey{}, tag)
+}
+
+// Locale returns the tag stored by WithLocale
+func Locale(ctx context.Context) (string, bool) {
+	tag, ok := ctx.Value(localeKey{}).(string)
+	return tag, ok
+}
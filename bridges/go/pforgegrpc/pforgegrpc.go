@@ -0,0 +1,76 @@
+// Package pforgegrpc maps pforge bridge errors to gRPC status codes. It is
+// a separate module so the bridge itself does not depend on gRPC.
+package pforgegrpc
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pforge "example"
+)
+
+// ToGRPCStatus converts an error returned by a pforge Executor into a gRPC
+// status. Errors that already carry a gRPC status keep it; nil maps to OK.
+//
+//	ErrHandlerNotFound          NotFound
+//	ErrBadInput, ErrInvalidInput InvalidArgument
+//	ErrInputTooLarge            InvalidArgument
+//	ErrReservedField            InvalidArgument
+//	ErrInvalidLocale            InvalidArgument
+//	ErrInvalidConfig            InvalidArgument
+//	ErrResultTooLarge           ResourceExhausted
+//	ErrNotSupported             Unimplemented
+//	ErrBridgeClosed             Unavailable
+//	ErrBridgeUnavailable        Unavailable
+//	ErrOverloaded               Unavailable
+//	ErrCircuitOpen              Unavailable
+//	ErrHandlerPanic             Internal
+//	ErrHandlerFailed            Unknown
+//	ErrCallCancelled, Canceled  Canceled
+//	ErrStreamIdle               DeadlineExceeded
+//	DeadlineExceeded            DeadlineExceeded
+//
+// Anything else maps to Unknown. The status message is err.Error().
+func ToGRPCStatus(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+	return status.New(Code(err), err.Error())
+}
+
+// Code returns the gRPC code ToGRPCStatus would use for err
+func Code(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, pforge.ErrHandlerNotFound):
+		return codes.NotFound
+	case errors.Is(err, pforge.ErrBadInput), errors.Is(err, pforge.ErrInvalidInput),
+		errors.Is(err, pforge.ErrInputTooLarge), errors.Is(err, pforge.ErrReservedField),
+		errors.Is(err, pforge.ErrInvalidLocale), errors.Is(err, pforge.ErrInvalidConfig):
+		return codes.InvalidArgument
+	case errors.Is(err, pforge.ErrResultTooLarge):
+		return codes.ResourceExhausted
+	case errors.Is(err, pforge.ErrNotSupported):
+		return codes.Unimplemented
+	case errors.Is(err, pforge.ErrBridgeClosed), errors.Is(err, pforge.ErrBridgeUnavailable),
+		errors.Is(err, pforge.ErrOverloaded), errors.Is(err, pforge.ErrCircuitOpen):
+		return codes.Unavailable
+	case errors.Is(err, pforge.ErrHandlerPanic):
+		return codes.Internal
+	case errors.Is(err, pforge.ErrHandlerFailed):
+		return codes.Unknown
+	case errors.Is(err, pforge.ErrCallCancelled), errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, pforge.ErrStreamIdle), errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	default:
+		return codes.Unknown
+	}
+}
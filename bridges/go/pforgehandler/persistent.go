@@ -0,0 +1,135 @@
+package pforgehandler
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ServeFlag makes Serve handle framed requests in a loop instead of a
+// single call.
+//
+// Each request is a frame holding one JSON input object; each reply is a
+// frame holding a Response. A frame is a 4-byte big-endian length followed
+// by that many bytes. The loop ends with ExitOK when stdin is closed
+// between frames.
+//
+// For a ServeStream handler each request frame is followed by stream
+// frames (see FrameData) up to FrameEnd or FrameAbort, before the reply.
+const ServeFlag = "--serve"
+
+// ErrFrameTooLarge is returned by ReadFrame when a frame exceeds its limit
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// Response is the reply frame for one request in --serve mode. Status uses
+// the same exit codes as a single-call handler.
+type Response struct {
+	Status int             `json:"status"`
+	Output json.RawMessage `json:"output,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// WriteFrame writes payload with its length prefix
+func WriteFrame(w io.Writer, payload []byte) error {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// ReadFrame reads one length-prefixed frame. It returns io.EOF if r ends
+// before a frame starts, and ErrFrameTooLarge if the frame is longer than
+// limit bytes; a limit of zero or less means no limit.
+func ReadFrame(r io.Reader, limit int) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("truncated frame header: %w", err)
+		}
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if limit > 0 && uint64(size) > uint64(limit) {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("truncated frame: %w", err)
+	}
+	return payload, nil
+}
+
+// serve answers framed requests until stdin is closed. With streaming set,
+// each request is followed by a stream passed to fn.
+func serve(manifest Manifest, fn StreamHandlerFunc, streaming bool, validate bool, stdin io.Reader, stdout io.Writer) int {
+	r := bufio.NewReader(stdin)
+	w := bufio.NewWriter(stdout)
+
+	for {
+		payload, err := ReadFrame(r, 0)
+		if err == io.EOF {
+			return ExitOK
+		}
+		if err != nil {
+			return ExitFailure
+		}
+
+		var data *StreamReader
+		if streaming {
+			data = NewStreamReader(r)
+		}
+		call := func(input map[string]interface{}) (map[string]interface{}, error) {
+			return fn(input, data)
+		}
+		response := respond(manifest, call, validate, payload)
+		// A stream that cannot be drained leaves no frame boundary to
+		// resume from, so the loop ends after replying
+		var drainErr error
+		if data != nil {
+			drainErr = data.drain()
+		}
+
+		reply, err := json.Marshal(response)
+		if err != nil {
+			return ExitFailure
+		}
+		if err := WriteFrame(w, reply); err != nil {
+			return ExitFailure
+		}
+		if err := w.Flush(); err != nil {
+			return ExitFailure
+		}
+		if drainErr != nil {
+			return ExitFailure
+		}
+	}
+}
+
+// respond handles one request frame
+func respond(manifest Manifest, fn HandlerFunc, validate bool, payload []byte) Response {
+	decode := func(v interface{}) error {
+		return json.Unmarshal(payload, v)
+	}
+
+	output, code, err := handle(manifest, fn, validate, decode)
+	if err != nil {
+		return Response{Status: code, Error: err.Error()}
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		return Response{Status: ExitHandlerError, Error: fmt.Sprintf("failed to marshal output: %v", err)}
+	}
+	return Response{Status: ExitOK, Output: data}
+}
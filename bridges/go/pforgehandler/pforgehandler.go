@@ -0,0 +1,199 @@
+// Package pforgehandler is a small SDK for writing pforge subprocess
+// handlers in Go.
+//
+// A handler binary reads one JSON input object on stdin and writes one JSON
+// result object to stdout. Failures are reported as {"error": "..."} with a
+// non-zero exit status from the table below. Running the binary with
+// --describe prints its Manifest instead, so the runtime can register it
+// without calling it.
+//
+// With --serve the binary stays alive and answers length-prefixed request
+// frames in a loop until stdin is closed; see ServeFlag. The exec adapter's
+// ExecPool uses this mode to avoid process startup on every call.
+//
+// Handlers that consume raw bytes, such as a streaming hash, use
+// ServeStream instead and read the data from a StreamReader.
+//
+// Handlers whose result is a long sequence of records use
+// ServeStreamResponse and write them one at a time to a RecordWriter; the
+// caller receives each record as it is written.
+//
+//	Exit  Meaning          pforge error
+//	0     success          -
+//	1     unclassified     ErrHandlerFailed
+//	2     bad input        ErrBadInput
+//	3     handler error    ErrHandlerFailed
+//	4     internal panic   ErrHandlerPanic
+//
+// When the manifest declares schemas, decoded input is validated before the
+// handler runs (failing with exit 2) and the result is validated before it is
+// written (failing with exit 3). Set PFORGE_SCHEMA_VALIDATION=off to skip
+// validation in production.
+package pforgehandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"example/internal/schema"
+)
+
+// Conventional exit codes, mapped back to typed errors by the exec adapter
+const (
+	ExitOK           = 0
+	ExitFailure      = 1
+	ExitBadInput     = 2
+	ExitHandlerError = 3
+	ExitPanic        = 4
+)
+
+// ErrBadInput can be wrapped by a HandlerFunc to report invalid input
+// with ExitBadInput instead of ExitHandlerError
+var ErrBadInput = errors.New("bad input")
+
+// DescribeFlag makes Serve print the handler manifest and exit
+const DescribeFlag = "--describe"
+
+// ValidationEnv disables schema validation when set to "off", "false" or "0"
+const ValidationEnv = "PFORGE_SCHEMA_VALIDATION"
+
+// ManifestSchema is the JSON Schema of the document printed by --describe
+const ManifestSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "pforge handler manifest",
+  "type": "object",
+  "required": ["name", "description"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1},
+    "description": {"type": "string"},
+    "input_schema": {"type": "object"},
+    "output_schema": {"type": "object"}
+  }
+}`
+
+// Manifest describes a subprocess handler to the pforge runtime,
+// mirroring what the FFI bridge reports through handler introspection
+type Manifest struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	InputSchema  json.RawMessage `json:"input_schema,omitempty"`
+	OutputSchema json.RawMessage `json:"output_schema,omitempty"`
+}
+
+// HandlerFunc processes one decoded input and returns the result
+type HandlerFunc func(input map[string]interface{}) (map[string]interface{}, error)
+
+// Serve runs a handler binary using the process arguments, stdin and
+// stdout, then exits with the resulting status
+func Serve(manifest Manifest, fn HandlerFunc) {
+	os.Exit(run(manifest, fn, validationEnabled(), os.Args[1:], os.Stdin, os.Stdout))
+}
+
+// validationEnabled reports whether ValidationEnv leaves validation on
+func validationEnabled() bool {
+	switch os.Getenv(ValidationEnv) {
+	case "off", "false", "0":
+		return false
+	default:
+		return true
+	}
+}
+
+// run executes one handler invocation and returns the exit status
+func run(manifest Manifest, fn HandlerFunc, validate bool, args []string, stdin io.Reader, stdout io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case DescribeFlag:
+			return describe(manifest, stdout)
+		case ServeFlag:
+			stream := func(input map[string]interface{}, _ *StreamReader) (map[string]interface{}, error) {
+				return fn(input)
+			}
+			return serve(manifest, stream, false, validate, stdin, stdout)
+		}
+	}
+
+	return reply(stdout, manifest, fn, validate, json.NewDecoder(stdin).Decode)
+}
+
+// describe prints the manifest for --describe
+func describe(manifest Manifest, stdout io.Writer) int {
+	if err := json.NewEncoder(stdout).Encode(manifest); err != nil {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// reply handles a single call and writes its result or error
+func reply(stdout io.Writer, manifest Manifest, fn HandlerFunc, validate bool, decode func(interface{}) error) int {
+	output, code, err := handle(manifest, fn, validate, decode)
+	if err != nil {
+		return writeError(stdout, code, err)
+	}
+
+	if err := json.NewEncoder(stdout).Encode(output); err != nil {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// handle decodes, validates and processes one input, returning the output
+// or the exit status and error to report
+func handle(manifest Manifest, fn HandlerFunc, validate bool, decode func(interface{}) error) (output map[string]interface{}, code int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			output, code, err = nil, ExitPanic, fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+
+	var input map[string]interface{}
+	if err := decode(&input); err != nil {
+		return nil, ExitBadInput, fmt.Errorf("failed to decode input: %w", err)
+	}
+
+	if validate && manifest.InputSchema != nil {
+		if err := schema.Validate(manifest.InputSchema, input); err != nil {
+			return nil, ExitBadInput, fmt.Errorf("input does not match schema: %w", err)
+		}
+	}
+
+	output, err = fn(input)
+	if err != nil {
+		if errors.Is(err, ErrBadInput) {
+			return nil, ExitBadInput, err
+		}
+		return nil, ExitHandlerError, err
+	}
+
+	if validate && manifest.OutputSchema != nil {
+		if err := validateOutput(manifest.OutputSchema, output); err != nil {
+			return nil, ExitHandlerError, fmt.Errorf("output does not match schema: %w", err)
+		}
+	}
+	return output, ExitOK, nil
+}
+
+// validateOutput round-trips the result through JSON so Go values such as
+// ints and structs are checked in the form the caller will receive
+func validateOutput(outputSchema json.RawMessage, output map[string]interface{}) error {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	return schema.Validate(outputSchema, decoded)
+}
+
+// writeError emits the standard error envelope and returns the exit status
+func writeError(stdout io.Writer, code int, err error) int {
+	json.NewEncoder(stdout).Encode(map[string]string{"error": err.Error()})
+	return code
+}
@@ -0,0 +1,148 @@
+package pforgehandler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"example/internal/schema"
+)
+
+// StreamErrorField is the key of the trailer record a ServeStreamResponse
+// handler writes when it fails. A record must not start with it as its
+// first key.
+//
+// Records are written to stdout as NDJSON, one JSON value per line, each
+// flushed as soon as it is written. If the handler fails, before or after
+// writing records, one more line follows:
+//
+//	{"_error":{"status":3,"error":"..."}}
+//
+// and the process exits with that status. Records already written stay
+// valid; a caller can tell a complete result from a truncated one only by
+// the absence of the trailer and a zero exit status.
+const StreamErrorField = "_error"
+
+// StreamError is the value of the StreamErrorField trailer
+type StreamError struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// streamErrorPrefix starts every trailer line and no record line
+var streamErrorPrefix = []byte(`{"` + StreamErrorField + `":`)
+
+// errStreamResponseServe fails every request to a ServeStreamResponse
+// handler started with --serve
+var errStreamResponseServe = errors.New("streamed responses are not supported with " + ServeFlag)
+
+// IsStreamError reports whether line, one line of a ServeStreamResponse
+// handler's output, is the error trailer, and decodes it if so
+func IsStreamError(line []byte) (StreamError, bool) {
+	if !bytes.HasPrefix(bytes.TrimSpace(line), streamErrorPrefix) {
+		return StreamError{}, false
+	}
+	var trailer map[string]StreamError
+	if err := json.Unmarshal(line, &trailer); err != nil {
+		return StreamError{Status: ExitFailure, Error: fmt.Sprintf("malformed error trailer: %v", err)}, true
+	}
+	return trailer[StreamErrorField], true
+}
+
+// RecordWriter writes the records of a ServeStreamResponse handler
+type RecordWriter struct {
+	w            *bufio.Writer
+	outputSchema json.RawMessage
+}
+
+// Write encodes record as one NDJSON line and flushes it, so the caller
+// receives it while the handler keeps working. When the manifest declares
+// an output schema and validation is on, each record is validated against
+// it. A record that is a JSON object with StreamErrorField as its first key
+// is rejected.
+func (r *RecordWriter) Write(record interface{}) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+	if bytes.HasPrefix(line, streamErrorPrefix) {
+		return fmt.Errorf("record sets the reserved %s field", StreamErrorField)
+	}
+
+	if r.outputSchema != nil {
+		var decoded interface{}
+		if err := json.Unmarshal(line, &decoded); err != nil {
+			return err
+		}
+		if err := schema.Validate(r.outputSchema, decoded); err != nil {
+			return fmt.Errorf("record does not match schema: %w", err)
+		}
+	}
+
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+// StreamResponseFunc processes one decoded input and writes its result as
+// a sequence of records. Returning an error after writing records ends the
+// stream with the StreamErrorField trailer.
+type StreamResponseFunc func(input map[string]interface{}, records *RecordWriter) error
+
+// ServeStreamResponse is Serve for handlers whose result is a sequence of
+// records, read with ExecHandler.ExecuteHandlerNDJSONFunc as they are
+// written instead of after the handler exits. See StreamErrorField for the
+// output format.
+//
+// Records cannot be streamed through --serve reply frames, so with
+// ServeFlag every request fails; these handlers cannot run in an ExecPool.
+func ServeStreamResponse(manifest Manifest, fn StreamResponseFunc) {
+	os.Exit(runStreamResponse(manifest, fn, validationEnabled(), os.Args[1:], os.Stdin, os.Stdout))
+}
+
+// runStreamResponse is run for stream response handlers
+func runStreamResponse(manifest Manifest, fn StreamResponseFunc, validate bool, args []string, stdin io.Reader, stdout io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case DescribeFlag:
+			return describe(manifest, stdout)
+		case ServeFlag:
+			unsupported := func(map[string]interface{}, *StreamReader) (map[string]interface{}, error) {
+				return nil, errStreamResponseServe
+			}
+			return serve(manifest, unsupported, false, validate, stdin, stdout)
+		}
+	}
+
+	w := bufio.NewWriter(stdout)
+	records := &RecordWriter{w: w}
+	if validate {
+		records.outputSchema = manifest.OutputSchema
+	}
+	call := func(input map[string]interface{}) (map[string]interface{}, error) {
+		return nil, fn(input, records)
+	}
+
+	// Records are validated as they are written, not as one output
+	manifest.OutputSchema = nil
+	_, code, err := handle(manifest, call, validate, json.NewDecoder(stdin).Decode)
+	if err != nil {
+		return writeStreamError(w, code, err)
+	}
+	if err := w.Flush(); err != nil {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// writeStreamError writes the error trailer and returns the exit status
+func writeStreamError(w *bufio.Writer, code int, err error) int {
+	json.NewEncoder(w).Encode(map[string]StreamError{StreamErrorField: {Status: code, Error: err.Error()}})
+	w.Flush()
+	return code
+}
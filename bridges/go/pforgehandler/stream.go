@@ -0,0 +1,221 @@
+package pforgehandler
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Stream frame types. A stream frame is a 1-byte type, a 4-byte big-endian
+// payload length and the payload. Data frames carry raw bytes with no
+// encoding; control frames carry one JSON value; FrameEnd (empty) ends the
+// stream normally and FrameAbort ends it with an error message payload.
+const (
+	FrameData    byte = 1
+	FrameControl byte = 2
+	FrameEnd     byte = 3
+	FrameAbort   byte = 4
+)
+
+// MaxControlFrame is the largest control or abort payload a StreamReader
+// accepts. Data frames are read incrementally and have no limit.
+const MaxControlFrame = 64 << 10
+
+// ErrStreamAborted is returned by StreamReader.Read when the sender ended
+// the stream with FrameAbort
+var ErrStreamAborted = errors.New("stream aborted by sender")
+
+// StreamHandlerFunc processes one decoded input together with a stream of
+// raw bytes, e.g. to hash data too large for a JSON input. It may stop
+// reading data early; the rest of the stream is discarded.
+type StreamHandlerFunc func(input map[string]interface{}, data *StreamReader) (map[string]interface{}, error)
+
+// ServeStream is Serve for handlers that consume a byte stream.
+//
+// In a single call the stream is the rest of stdin after the input JSON
+// object, starting right after its closing brace and ending at end of
+// file. With --serve each request frame is followed by stream frames up to
+// FrameEnd, and the reply frame is written once the handler returns and
+// the stream has been consumed.
+func ServeStream(manifest Manifest, fn StreamHandlerFunc) {
+	os.Exit(runStream(manifest, fn, validationEnabled(), os.Args[1:], os.Stdin, os.Stdout))
+}
+
+// runStream is run for stream handlers
+func runStream(manifest Manifest, fn StreamHandlerFunc, validate bool, args []string, stdin io.Reader, stdout io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case DescribeFlag:
+			return describe(manifest, stdout)
+		case ServeFlag:
+			return serve(manifest, fn, true, validate, stdin, stdout)
+		}
+	}
+
+	dec := json.NewDecoder(stdin)
+	call := func(input map[string]interface{}) (map[string]interface{}, error) {
+		// The decoder has buffered the start of the stream by now
+		return fn(input, &StreamReader{raw: io.MultiReader(dec.Buffered(), stdin)})
+	}
+	return reply(stdout, manifest, call, validate, dec.Decode)
+}
+
+// StreamReader reads the data frames of a stream as an io.Reader, so a
+// handler can io.Copy from it. Read returns io.EOF at FrameEnd.
+type StreamReader struct {
+	r   *bufio.Reader
+	raw io.Reader
+	// remaining is what is left of the current data frame
+	remaining uint32
+	err       error
+
+	// OnControl, if set, receives each control frame as it is reached.
+	// Set it before the first Read.
+	OnControl func(json.RawMessage)
+}
+
+// NewStreamReader reads stream frames from r
+func NewStreamReader(r io.Reader) *StreamReader {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &StreamReader{r: br}
+}
+
+// Read reads stream data, skipping over control frames
+func (s *StreamReader) Read(p []byte) (int, error) {
+	if s.raw != nil {
+		// Unframed stream: the rest of stdin in a single call
+		return s.raw.Read(p)
+	}
+
+	for s.remaining == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		s.err = s.nextFrame()
+	}
+
+	if uint32(len(p)) > s.remaining {
+		p = p[:s.remaining]
+	}
+	n, err := s.r.Read(p)
+	s.remaining -= uint32(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		s.err = fmt.Errorf("truncated data frame: %w", err)
+		return n, s.err
+	}
+	return n, nil
+}
+
+// nextFrame reads the next frame header, handling control frames in full.
+// It returns the error Read should report from then on, if the stream ended.
+func (s *StreamReader) nextFrame() error {
+	var header [5]byte
+	if _, err := io.ReadFull(s.r, header[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("truncated stream: %w", err)
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+
+	switch header[0] {
+	case FrameData:
+		s.remaining = size
+		return nil
+	case FrameControl, FrameAbort:
+		if size > MaxControlFrame {
+			return fmt.Errorf("%w: %d byte control frame", ErrFrameTooLarge, size)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(s.r, payload); err != nil {
+			return fmt.Errorf("truncated control frame: %w", err)
+		}
+		if header[0] == FrameAbort {
+			return fmt.Errorf("%w: %s", ErrStreamAborted, payload)
+		}
+		if s.OnControl != nil {
+			s.OnControl(json.RawMessage(payload))
+		}
+		return nil
+	case FrameEnd:
+		if size != 0 {
+			return fmt.Errorf("end frame with %d byte payload", size)
+		}
+		return io.EOF
+	default:
+		return fmt.Errorf("unknown stream frame type %d", header[0])
+	}
+}
+
+// drain discards the rest of the stream so the next request frame can be
+// read. An aborted stream drains cleanly; a broken one cannot be resynced.
+func (s *StreamReader) drain() error {
+	_, err := io.Copy(io.Discard, s)
+	if errors.Is(err, ErrStreamAborted) {
+		return nil
+	}
+	return err
+}
+
+// StreamWriter writes stream frames, for callers feeding a stream handler
+// running with --serve
+type StreamWriter struct {
+	w io.Writer
+}
+
+// NewStreamWriter writes stream frames to w
+func NewStreamWriter(w io.Writer) *StreamWriter {
+	return &StreamWriter{w: w}
+}
+
+// Write sends p as one data frame. An empty p sends nothing.
+func (s *StreamWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := writeStreamFrame(s.w, FrameData, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Control sends v encoded as JSON in a control frame
+func (s *StreamWriter) Control(v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeStreamFrame(s.w, FrameControl, payload)
+}
+
+// Close ends the stream normally
+func (s *StreamWriter) Close() error {
+	return writeStreamFrame(s.w, FrameEnd, nil)
+}
+
+// Abort ends the stream with an error; the handler's next Read returns
+// ErrStreamAborted
+func (s *StreamWriter) Abort(reason string) error {
+	return writeStreamFrame(s.w, FrameAbort, []byte(reason))
+}
+
+func writeStreamFrame(w io.Writer, frameType byte, payload []byte) error {
+	var header [5]byte
+	header[0] = frameType
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
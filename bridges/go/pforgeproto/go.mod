@@ -0,0 +1,10 @@
+module example/pforgeproto
+
+go 1.21
+
+require (
+	example v0.0.0
+	google.golang.org/protobuf v1.33.0
+)
+
+replace example => ../
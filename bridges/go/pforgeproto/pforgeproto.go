@@ -0,0 +1,88 @@
+// Package pforgeproto calls pforge handlers with protobuf messages, using
+// the canonical protobuf JSON mapping (protojson) for inputs and results.
+// It is a separate module so the bridge itself does not depend on protobuf.
+//
+// Handlers see the same JSON a gRPC-gateway would produce: lowerCamelCase
+// field names, enums as strings, int64 as strings, and well-known types in
+// their JSON forms (e.g. Timestamp as an RFC 3339 string).
+package pforgeproto
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pforge "example"
+)
+
+// Codec holds the protojson options used to encode inputs and decode
+// results. The zero value uses protojson's defaults, which reject result
+// fields the message does not define.
+type Codec struct {
+	Marshal   protojson.MarshalOptions
+	Unmarshal protojson.UnmarshalOptions
+}
+
+// DefaultCodec is used by Execute. It ignores unknown result fields, since
+// handler results may carry fields the message does not declare, such as
+// pforge.SchemaVersionField.
+var DefaultCodec = Codec{
+	Unmarshal: protojson.UnmarshalOptions{DiscardUnknown: true},
+}
+
+// Execute calls handlerName with in and decodes its result into out, using
+// DefaultCodec
+func Execute(ctx context.Context, exec pforge.Executor, handlerName string, in, out proto.Message) error {
+	return DefaultCodec.Execute(ctx, exec, handlerName, in, out)
+}
+
+// Execute calls handlerName with in encoded by protojson and decodes the
+// result into out. in must encode to a JSON object, so well-known types
+// with scalar JSON forms, such as a bare Timestamp, cannot be the input
+// message itself; wrap them in a message field.
+func (c Codec) Execute(ctx context.Context, exec pforge.Executor, handlerName string, in, out proto.Message) error {
+	input, err := c.Input(in)
+	if err != nil {
+		return err
+	}
+
+	output, err := exec.ExecuteHandlerContext(ctx, handlerName, input)
+	if err != nil {
+		return err
+	}
+
+	return c.Output(output, out)
+}
+
+// Input converts a message into handler input
+func (c Codec) Input(in proto.Message) (map[string]interface{}, error) {
+	data, err := c.Marshal.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", in.ProtoReflect().Descriptor().FullName(), err)
+	}
+
+	// Keep numbers as written; protojson's output is already exact
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var input map[string]interface{}
+	if err := dec.Decode(&input); err != nil {
+		return nil, fmt.Errorf("%s does not encode to a JSON object: %w", in.ProtoReflect().Descriptor().FullName(), err)
+	}
+	return input, nil
+}
+
+// Output decodes a handler result into out, replacing its contents
+func (c Codec) Output(output map[string]interface{}, out proto.Message) error {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Errorf("failed to re-encode result: %w", err)
+	}
+	if err := c.Unmarshal.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode result into %s: %w", out.ProtoReflect().Descriptor().FullName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Package pforgetest provides helpers for testing pforge handlers.
+//
+// AssertGolden compares a handler's output with a golden file. Run the
+// tests with -pforgetest.update to rewrite the golden files from the
+// current outputs:
+//
+//	go test ./... -pforgetest.update
+//
+// The flag is namespaced so it does not collide with an -update flag the
+// test package defines itself.
+package pforgetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pforge "example"
+)
+
+var update = flag.Bool("pforgetest.update", false, "rewrite pforgetest golden files with current handler outputs")
+
+// AssertGolden calls handlerName on exec (a Bridge or any other
+// pforge.Executor) and compares the result with the JSON in goldenPath.
+//
+// Both sides are canonicalized (object keys sorted, two-space indentation)
+// before comparing, so golden files can be hand-edited freely. Object keys
+// named in ignore are dropped at any depth on both sides, for volatile
+// fields such as timestamps. With -pforgetest.update the golden file is
+// written instead, without the ignored fields.
+func AssertGolden(t testing.TB, exec pforge.Executor, handlerName string, input map[string]interface{}, goldenPath string, ignore ...string) {
+	t.Helper()
+
+	output, err := exec.ExecuteHandler(handlerName, input)
+	if err != nil {
+		t.Fatalf("%s: %v", handlerName, err)
+	}
+	got, err := canonical(output, ignore)
+	if err != nil {
+		t.Fatalf("%s: canonicalizing output: %v", handlerName, err)
+	}
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			t.Fatalf("updating %s: %v", goldenPath, err)
+		}
+		if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+			t.Fatalf("updating %s: %v", goldenPath, err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("%s: reading golden file (run with -pforgetest.update to create it): %v", handlerName, err)
+	}
+	var golden interface{}
+	if err := json.Unmarshal(data, &golden); err != nil {
+		t.Fatalf("%s: golden file %s is not JSON: %v", handlerName, goldenPath, err)
+	}
+	want, err := canonical(golden, ignore)
+	if err != nil {
+		t.Fatalf("%s: canonicalizing %s: %v", handlerName, goldenPath, err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s: output does not match %s\n--- want\n%s--- got\n%s", handlerName, goldenPath, want, got)
+	}
+}
+
+// canonical encodes v with sorted keys and fixed indentation, after
+// dropping ignored keys. v is round-tripped through JSON first so Go
+// values compare in the form a golden file holds.
+func canonical(v interface{}, ignore []string) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	skip := make(map[string]bool, len(ignore))
+	for _, name := range ignore {
+		skip[name] = true
+	}
+	out, err := json.MarshalIndent(strip(decoded, skip), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// strip removes ignored keys from objects at any depth
+func strip(v interface{}, skip map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if skip[key] {
+				delete(v, key)
+				continue
+			}
+			v[key] = strip(value, skip)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = strip(item, skip)
+		}
+	}
+	return v
+}
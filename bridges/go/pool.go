@@ -0,0 +1,310 @@
+package pforge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"example/pforgehandler"
+)
+
+var errPoolClosed = errors.New("exec pool closed")
+
+// ExecPool runs subprocess handlers like ExecHandler, but keeps persistent
+// handler processes alive in pforgehandler's --serve mode and dispatches
+// each call to an idle one, saving process startup on every call.
+//
+// Processes are started on demand, up to Size per handler. A process that
+// dies while idle is replaced before a call is sent to it; if it dies
+// mid-request, that request fails with ErrHandlerFailed. Handler binaries
+// must be built with a pforgehandler version that supports ServeFlag.
+//
+// The configuration fields are read when a handler's pool is first used.
+type ExecPool struct {
+	// Binaries maps handler names to executable paths
+	Binaries map[string]string
+	// Size is the number of processes kept per handler.
+	// Zero means runtime.GOMAXPROCS(0).
+	Size int
+	// MaxRequests recycles a process after it has served that many calls.
+	// Zero means processes are never recycled.
+	MaxRequests int
+	// MaxOutputBytes caps a single result, as for ExecHandler.
+	// Zero means DefaultExecMaxOutput.
+	MaxOutputBytes int64
+	// Stderr receives the stderr of every pooled process, as for
+	// ExecHandler. A process serves many calls, so its output is not
+	// delimited per call.
+	Stderr io.Writer
+
+	mu        sync.Mutex
+	pools     map[string]*workerPool
+	closed    bool
+	recycling sync.WaitGroup
+}
+
+// NewExecPool creates a pooled exec adapter keeping size processes per handler
+func NewExecPool(binaries map[string]string, size int) *ExecPool {
+	return &ExecPool{Binaries: binaries, Size: size}
+}
+
+// workerPool holds the processes of one handler. Each slot is an idle
+// worker, or nil when the process has yet to be started.
+type workerPool struct {
+	path   string
+	stderr io.Writer
+	slots  chan *poolWorker
+	done   chan struct{}
+}
+
+// poolWorker is one persistent handler process
+type poolWorker struct {
+	cmd      *exec.Cmd
+	cancel   context.CancelFunc
+	stdin    io.WriteCloser
+	stdout   *bufio.Reader
+	stderr   *tailBuffer
+	requests int
+	// exited is closed once the process has exited and been waited for
+	exited chan struct{}
+}
+
+// ExecuteHandler runs a pooled subprocess handler with JSON input
+func (e *ExecPool) ExecuteHandler(handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	return e.ExecuteHandlerContext(context.Background(), handlerName, input)
+}
+
+// ExecuteHandlerContext runs a pooled subprocess handler. If ctx is done
+// while waiting for an idle process the call fails with the context error;
+// if it is done mid-request the process is killed and replaced.
+func (e *ExecPool) ExecuteHandlerContext(ctx context.Context, handlerName string, input map[string]interface{}) (map[string]interface{}, error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	pool, err := e.pool(handlerName)
+	if err != nil {
+		return nil, err
+	}
+
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	w, err := pool.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if w != nil && w.dead() {
+		w.stop(false)
+		w = nil
+	}
+	if w == nil {
+		if w, err = pool.spawn(); err != nil {
+			pool.slots <- nil
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("%w: %s: %v", ErrHandlerNotFound, handlerName, err)
+			}
+			return nil, fmt.Errorf("failed to start handler %s: %w", handlerName, err)
+		}
+	}
+
+	limit := e.MaxOutputBytes
+	if limit <= 0 {
+		limit = DefaultExecMaxOutput
+	}
+
+	resp, err := w.roundTrip(ctx, inputJSON, limit)
+	if err != nil {
+		w.stop(false)
+		pool.slots <- nil
+		if ctx.Err() != nil {
+			return nil, contextError(ctx)
+		}
+		if errors.Is(err, pforgehandler.ErrFrameTooLarge) {
+			return nil, fmt.Errorf("%w: %s wrote more than %d bytes", ErrResultTooLarge, handlerName, limit)
+		}
+		return nil, fmt.Errorf("%w: %s exited mid-request: %v%s", ErrHandlerFailed, handlerName, err, stderrSuffix(w.stderr.String()))
+	}
+
+	w.requests++
+	if e.MaxRequests > 0 && w.requests >= e.MaxRequests {
+		e.recycle(w)
+		w = nil
+	}
+	pool.slots <- w
+
+	if resp.Status != pforgehandler.ExitOK {
+		return nil, newHandlerError(resp.Status, resp.Error, exitErrorKind(resp.Status))
+	}
+	return decodeOutput(StdJSON, resp.Output)
+}
+
+// pool returns the worker pool for a handler, creating it on first use
+func (e *ExecPool) pool(handlerName string) (*workerPool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, errPoolClosed
+	}
+	if pool, ok := e.pools[handlerName]; ok {
+		return pool, nil
+	}
+
+	path, ok := e.Binaries[handlerName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrHandlerNotFound, handlerName)
+	}
+
+	size := e.Size
+	if size <= 0 {
+		size = runtime.GOMAXPROCS(0)
+	}
+	pool := &workerPool{
+		path:   path,
+		stderr: e.Stderr,
+		slots:  make(chan *poolWorker, size),
+		done:   make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		pool.slots <- nil
+	}
+
+	if e.pools == nil {
+		e.pools = make(map[string]*workerPool)
+	}
+	e.pools[handlerName] = pool
+	return pool, nil
+}
+
+// recycle stops a worker that reached MaxRequests without blocking the caller
+func (e *ExecPool) recycle(w *poolWorker) {
+	e.recycling.Add(1)
+	go func() {
+		defer e.recycling.Done()
+		w.stop(true)
+	}()
+}
+
+// Close stops every pooled process, waiting for in-flight calls to finish.
+// Calls made after Close fail.
+func (e *ExecPool) Close() error {
+	e.mu.Lock()
+	e.closed = true
+	pools := e.pools
+	e.pools = nil
+	e.mu.Unlock()
+
+	for _, pool := range pools {
+		close(pool.done)
+		for i := 0; i < cap(pool.slots); i++ {
+			if w := <-pool.slots; w != nil {
+				w.stop(true)
+			}
+		}
+	}
+	e.recycling.Wait()
+	return nil
+}
+
+// acquire waits for an idle slot
+func (p *workerPool) acquire(ctx context.Context) (*poolWorker, error) {
+	select {
+	case w := <-p.slots:
+		return w, nil
+	case <-p.done:
+		return nil, errPoolClosed
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	}
+}
+
+// spawn starts a handler process in --serve mode
+func (p *workerPool) spawn() (*poolWorker, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, p.path, pforgehandler.ServeFlag)
+	cmd.WaitDelay = execWaitDelay
+
+	w := &poolWorker{cmd: cmd, cancel: cancel, stderr: &tailBuffer{size: stderrTailSize}, exited: make(chan struct{})}
+	cmd.Stderr = stderrWriter(w.stderr, p.stderr)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	w.stdin = stdin
+	w.stdout = bufio.NewReader(stdout)
+	// Waiting as soon as it starts lets acquire spot a process that died
+	// while idle
+	go func() {
+		cmd.Wait()
+		close(w.exited)
+	}()
+	return w, nil
+}
+
+// dead reports whether the process has exited
+func (w *poolWorker) dead() bool {
+	select {
+	case <-w.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// roundTrip sends one request frame and reads the reply, killing the
+// process if ctx is done first
+func (w *poolWorker) roundTrip(ctx context.Context, payload []byte, limit int64) (pforgehandler.Response, error) {
+	stop := context.AfterFunc(ctx, w.cancel)
+	defer stop()
+
+	var resp pforgehandler.Response
+	if err := pforgehandler.WriteFrame(w.stdin, payload); err != nil {
+		return resp, err
+	}
+
+	frame, err := pforgehandler.ReadFrame(w.stdout, int(limit))
+	if err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(frame, &resp); err != nil {
+		return resp, fmt.Errorf("malformed reply: %w", err)
+	}
+	return resp, nil
+}
+
+// stop ends the process. A graceful stop closes stdin so the serve loop
+// exits on its own, killing the process if it has not within execWaitDelay.
+func (w *poolWorker) stop(graceful bool) {
+	if graceful {
+		w.stdin.Close()
+		timer := time.AfterFunc(execWaitDelay, w.cancel)
+		defer timer.Stop()
+	} else {
+		w.cancel()
+	}
+	<-w.exited
+	w.cancel()
+}
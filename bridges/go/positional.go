@@ -0,0 +1,60 @@
+package pforge
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// ExecuteHandlerPositional calls a handler that takes JSON-RPC style
+// positional params, passing them as a JSON array ([arg0, arg1, ...])
+// instead of a named object. With no params the input is [].
+//
+// The native side tells the two forms apart by the first byte of the
+// input: '[' for positional params, '{' for named ones. Only handlers that
+// declare positional params accept an array; others fail with ErrBadInput.
+// Envelope fields (ClientField, SchemaVersionField) and schema validation
+// need an object, so they do not apply to positional calls.
+func (b *Bridge) ExecuteHandlerPositional(handlerName string, params ...any) (map[string]interface{}, error) {
+	start := time.Now()
+	output, err := b.observe(b.executePositional(handlerName, params))
+	b.finishCall(context.Background(), "handler call", handlerName, start, err)
+	b.audit(context.Background(), "handler call", handlerName, start, params, output, err)
+	return output, err
+}
+
+func (b *Bridge) executePositional(handlerName string, params []any) (map[string]interface{}, error) {
+	release, err := b.acquireHandler(context.Background(), handlerName)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	payload, err := b.marshalPositional(params)
+	if err != nil {
+		return nil, err
+	}
+	return b.call(entryExecute, handlerName, payload)
+}
+
+// marshalPositional serializes params as a JSON array, reporting
+// unserializable values like marshalInput
+func (b *Bridge) marshalPositional(params []any) ([]byte, error) {
+	if params == nil {
+		params = []any{}
+	}
+	if b.strictUTF8 {
+		if err := checkValue(reflect.ValueOf(params), "$", true); err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := b.jsonCodec.Marshal(params)
+	if err != nil {
+		if inputErr := checkValue(reflect.ValueOf(params), "$", false); inputErr != nil {
+			return nil, inputErr
+		}
+		return nil, &InputError{Path: "$", Message: err.Error()}
+	}
+	return payload, nil
+}
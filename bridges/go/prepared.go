@@ -0,0 +1,121 @@
+package pforge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PreparedCall is a handler call whose input was serialized once, for
+// polling or health handlers called repeatedly with the same input. It is
+// safe for concurrent use.
+type PreparedCall struct {
+	bridge      *Bridge
+	handlerName string
+	// payload is never modified after PreparedCall returns; per-call
+	// fields are spliced into a copy
+	payload []byte
+	// inputHash is the audit hash of the caller's input, when auditing
+	inputHash auditDigest
+}
+
+// PreparedCall validates and serializes input for handlerName once,
+// including the Bridge's envelope fields. input may be a map or any value
+// that encodes to a JSON object.
+func (b *Bridge) PreparedCall(handlerName string, input any) (*PreparedCall, error) {
+	data, err := b.jsonCodec.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	// Numbers stay json.Number, as in ServeHTTP, so integers past 2^53
+	// reach the handler unchanged
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded map[string]interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("input must encode to a JSON object: %w", err)
+	}
+
+	// Execute may splice in these fields from its context
+	decoded, err = b.reserveFields(handlerName, decoded, DeadlineField, SeedField, ProjectionField, IdempotencyKeyField, LocaleField)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := b.marshalInputLocale(handlerName, decoded, "")
+	if err != nil {
+		return nil, err
+	}
+	call := &PreparedCall{bridge: b, handlerName: handlerName, payload: payload}
+	if b.auditSink != nil {
+		call.inputHash = auditDigest(auditHash(b.Redact(decoded)))
+	}
+	return call, nil
+}
+
+// Execute calls the handler with the prepared input. It behaves like
+// ExecuteHandlerContext, including the deadline, seed, projection,
+// idempotency key and locale fields and cancellation.
+func (p *PreparedCall) Execute(ctx context.Context) (map[string]interface{}, error) {
+	defer releaseCallHandle(ctx)
+
+	b := p.bridge
+	start := time.Now()
+	output, err := b.observe(b.callContext(ctx, p.handlerName, func(ctx context.Context) (map[string]interface{}, error) {
+		payload := p.payload
+		if deadline, ok := ctx.Deadline(); ok {
+			field, err := encodeField(DeadlineField, time.Until(deadline).Milliseconds())
+			if err != nil {
+				return nil, err
+			}
+			if payload, err = spliceFields(payload, field); err != nil {
+				return nil, err
+			}
+		}
+		if seed, ok := SeedFromContext(ctx); ok {
+			field, err := encodeField(SeedField, seed)
+			if err != nil {
+				return nil, err
+			}
+			if payload, err = spliceFields(payload, field); err != nil {
+				return nil, err
+			}
+		}
+		if fields, ok := ProjectionFromContext(ctx); ok {
+			field, err := encodeField(ProjectionField, fields)
+			if err != nil {
+				return nil, err
+			}
+			if payload, err = spliceFields(payload, field); err != nil {
+				return nil, err
+			}
+		}
+		if locale := b.callLocale(ctx); locale != "" {
+			if err := ValidateLocale(locale); err != nil {
+				return nil, err
+			}
+			field, err := encodeField(LocaleField, locale)
+			if err != nil {
+				return nil, err
+			}
+			if payload, err = spliceFields(payload, field); err != nil {
+				return nil, err
+			}
+		}
+		if key, ok := IdempotencyKeyFromContext(ctx); ok {
+			field, err := encodeField(IdempotencyKeyField, key)
+			if err != nil {
+				return nil, err
+			}
+			if payload, err = spliceFields(payload, field); err != nil {
+				return nil, err
+			}
+		}
+		return b.call(entryExecute, p.handlerName, payload)
+	}))
+	b.finishCall(ctx, "handler call", p.handlerName, start, err)
+	b.audit(ctx, "handler call", p.handlerName, start, p.inputHash, output, err)
+	return output, err
+}
@@ -0,0 +1,168 @@
+package pforge
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// Priority orders calls waiting for a WithHandlerConcurrency slot: when a
+// slot frees up it goes to the highest-priority waiter, and to the one that
+// has waited longest among equals. Any int is a valid priority; the
+// constants cover the usual split between interactive and background work.
+type Priority int
+
+// Common priorities; calls default to PriorityNormal
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// ContextWithPriority returns a context whose calls wait for handler slots
+// at priority p. Calls without one use PriorityNormal. Priority only
+// matters for handlers limited with WithHandlerConcurrency, since others
+// never wait, and it does not exempt a call from WithAdmissionControl.
+func ContextWithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// priorityKey is the context key for ContextWithPriority
+type priorityKey struct{}
+
+// PriorityFromContext returns the priority set with ContextWithPriority, or
+// PriorityNormal
+func PriorityFromContext(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// handlerSlots is a counting semaphore for one handler's concurrency limit
+// that hands free slots to waiters in priority order
+type handlerSlots struct {
+	mu      sync.Mutex
+	limit   int
+	held    int
+	waiters slotWaiters
+	// arrivals numbers waiters to keep each priority FIFO
+	arrivals uint64
+}
+
+func newHandlerSlots(limit int) *handlerSlots {
+	return &handlerSlots{limit: limit}
+}
+
+// acquire takes a slot, waiting at priority p until one is free or ctx is
+// done
+func (s *handlerSlots) acquire(ctx context.Context, p Priority) error {
+	s.mu.Lock()
+	if s.held < s.limit && len(s.waiters) == 0 {
+		s.held++
+		s.mu.Unlock()
+		return nil
+	}
+	s.arrivals++
+	w := &slotWaiter{priority: p, arrival: s.arrivals, ready: make(chan struct{})}
+	heap.Push(&s.waiters, w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	granted := w.index < 0
+	if !granted {
+		heap.Remove(&s.waiters, w.index)
+	}
+	s.mu.Unlock()
+	if granted {
+		// The slot was handed over as ctx ended; pass it on
+		s.release()
+	}
+	return contextError(ctx)
+}
+
+// release frees a slot, handing it straight to the first waiter if any
+func (s *handlerSlots) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held--
+	s.grant()
+}
+
+// setLimit changes the number of slots. Lowering it does not affect calls
+// already holding a slot; new calls wait until enough have finished.
+func (s *handlerSlots) setLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = limit
+	s.grant()
+}
+
+// grant hands free slots to waiters. s.mu must be held.
+func (s *handlerSlots) grant() {
+	for s.held < s.limit && len(s.waiters) > 0 {
+		w := heap.Pop(&s.waiters).(*slotWaiter)
+		s.held++
+		close(w.ready)
+	}
+}
+
+// snapshot returns the slots in use and the waiters per priority
+func (s *handlerSlots) snapshot() (limit, held int, waiting map[Priority]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.waiters) > 0 {
+		waiting = make(map[Priority]int)
+		for _, w := range s.waiters {
+			waiting[w.priority]++
+		}
+	}
+	return s.limit, s.held, waiting
+}
+
+// slotWaiter is a call waiting in handlerSlots
+type slotWaiter struct {
+	priority Priority
+	arrival  uint64
+	ready    chan struct{}
+	// index is the position in the heap, or -1 once granted
+	index int
+}
+
+// slotWaiters is a heap of waiters, highest priority and earliest arrival
+// first
+type slotWaiters []*slotWaiter
+
+func (h slotWaiters) Len() int { return len(h) }
+
+func (h slotWaiters) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].arrival < h[j].arrival
+}
+
+func (h slotWaiters) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *slotWaiters) Push(x any) {
+	w := x.(*slotWaiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *slotWaiters) Pop() any {
+	old := *h
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*h = old[:len(old)-1]
+	return w
+}
@@ -0,0 +1,130 @@
+package pforge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"time"
+)
+
+// ProgressField marks a progress record in the stream of a handler called
+// with ExecuteHandlerWithProgress
+const ProgressField = "_progress"
+
+// Progress is a progress event reported by a long-running handler
+type Progress struct {
+	// Fraction is the completed share of the work, from 0 to 1
+	Fraction float64 `json:"fraction"`
+	Message  string  `json:"message,omitempty"`
+}
+
+// ExecuteHandlerWithProgress calls a handler that reports progress while it
+// works, using the native streaming entry points. The handler streams
+// newline-delimited JSON records: {"_progress": {"fraction": 0.4,
+// "message": "..."}} for each progress event, and a final record holding
+// the result.
+//
+// onProgress runs on its own goroutine so a slow callback never delays the
+// stream or the result. Events arriving while it is busy are coalesced to
+// the most recent one, and events still pending when the result arrives
+// are dropped.
+func (b *Bridge) ExecuteHandlerWithProgress(ctx context.Context, handlerName string, input map[string]interface{}, onProgress func(Progress)) (map[string]interface{}, error) {
+	defer releaseCallHandle(ctx)
+
+	start := time.Now()
+	output, err := b.observe(b.executeWithProgress(ctx, handlerName, input, onProgress))
+	b.finishCall(ctx, "handler call", handlerName, start, err)
+	b.audit(ctx, "handler call", handlerName, start, input, output, err)
+	return output, err
+}
+
+func (b *Bridge) executeWithProgress(ctx context.Context, handlerName string, input map[string]interface{}, onProgress func(Progress)) (map[string]interface{}, error) {
+	if ctx.Err() != nil {
+		return nil, contextError(ctx)
+	}
+	ctx, cancelTimeout := b.handlerTimeout(ctx, handlerName)
+	defer cancelTimeout()
+
+	stream, err := b.openStream(handlerName, input)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	release := stream.watch(ctx)
+	defer release()
+
+	events := make(chan Progress, 1)
+	done := make(chan struct{})
+	defer close(done)
+	if onProgress != nil {
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case p := <-events:
+					onProgress(p)
+				}
+			}
+		}()
+	}
+
+	var result []byte
+	reader := bufio.NewReader(&streamReader{stream: stream})
+	for {
+		line, err := reader.ReadBytes('\n')
+		if record := bytes.TrimSpace(line); len(record) > 0 {
+			if p, ok := progressRecord(b.jsonCodec, record); ok {
+				if onProgress != nil {
+					publishProgress(events, p)
+				}
+			} else {
+				result = record
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, contextError(ctx)
+			}
+			return nil, err
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, contextError(ctx)
+	}
+
+	return decodeOutput(b.jsonCodec, result)
+}
+
+// progressRecord reports whether a stream record is a progress event
+func progressRecord(codec JSONCodec, record []byte) (Progress, bool) {
+	var envelope struct {
+		Progress *Progress `json:"_progress"`
+	}
+	if err := codec.Unmarshal(record, &envelope); err != nil || envelope.Progress == nil {
+		return Progress{}, false
+	}
+	return *envelope.Progress, true
+}
+
+// publishProgress hands an event to the callback goroutine without
+// blocking, replacing any event it has not picked up yet. There is a
+// single publisher, so the retry always succeeds.
+func publishProgress(events chan Progress, p Progress) {
+	select {
+	case events <- p:
+		return
+	default:
+	}
+	select {
+	case <-events:
+	default:
+	}
+	events <- p
+}
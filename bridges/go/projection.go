@@ -0,0 +1,49 @@
+package pforge
+
+import (
+	"context"
+
+	"example/pforgectx"
+)
+
+// ContextWithProjection returns a copy of ctx that makes
+// ExecuteHandlerContext and PreparedCall.Execute add fields to the input as
+// ProjectionField, asking the handler to return only those top-level result
+// fields. For wide results this cuts both the bytes crossing the FFI
+// boundary and the decoding work.
+//
+// The projection is advisory and handler-dependent: only handlers that read
+// ProjectionField use it, and the rest return their full result. A handler
+// that honored it echoes the fields it kept in its result; see
+// ResultProjection. An empty fields list leaves ctx unchanged.
+func ContextWithProjection(ctx context.Context, fields ...string) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	return pforgectx.WithProjection(ctx, fields)
+}
+
+// ProjectionFromContext returns the fields stored by ContextWithProjection
+func ProjectionFromContext(ctx context.Context) (fields []string, ok bool) {
+	return pforgectx.Projection(ctx)
+}
+
+// ResultProjection returns the ProjectionField a handler reported in its
+// result, which by convention means it honored the projection and returned
+// only those fields. ok is false if the handler did not report one, in
+// which case the result should be assumed to be complete.
+func ResultProjection(output map[string]interface{}) (fields []string, ok bool) {
+	list, ok := output[ProjectionField].([]interface{})
+	if !ok {
+		return nil, false
+	}
+	fields = make([]string, 0, len(list))
+	for _, v := range list {
+		field, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		fields = append(fields, field)
+	}
+	return fields, true
+}
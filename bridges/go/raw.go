@@ -0,0 +1,115 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"context"
+	"time"
+)
+
+// ExecuteHandlerRawAndMap calls a handler once and returns both the exact
+// bytes the native side produced, for forwarding or hashing verbatim, and
+// the map decoded from them. raw is nil when the handler returned an empty
+// result, in which case the map is empty. On a decode error raw is still
+// returned.
+func (b *Bridge) ExecuteHandlerRawAndMap(handlerName string, input map[string]interface{}) ([]byte, map[string]interface{}, error) {
+	start := time.Now()
+	raw, output, err := b.executeRawAndMap(handlerName, input)
+	b.record(err)
+	b.finishCall(context.Background(), "handler call", handlerName, start, err)
+	b.audit(context.Background(), "handler call", handlerName, start, input, output, err)
+	return raw, output, err
+}
+
+func (b *Bridge) executeRawAndMap(handlerName string, input map[string]interface{}) ([]byte, map[string]interface{}, error) {
+	release, err := b.acquireHandler(context.Background(), handlerName)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	inputJSON, err := b.marshalInput(handlerName, input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, err := b.callRaw(entryExecute, handlerName, inputJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	output, err := decodeOutput(b.wireCodec(handlerName), raw)
+	return raw, output, err
+}
+
+// ExecuteHandlerSized calls a handler and also returns the size in bytes of
+// the encoded result as the native side produced it, for tracking payload
+// sizes without re-encoding the map. size is 0 for an empty result and when
+// the handler fails.
+func (b *Bridge) ExecuteHandlerSized(handlerName string, input map[string]interface{}) (output map[string]interface{}, size int, err error) {
+	start := time.Now()
+	output, size, err = b.executeSized(handlerName, input)
+	b.record(err)
+	b.finishCall(context.Background(), "handler call", handlerName, start, err)
+	b.audit(context.Background(), "handler call", handlerName, start, input, output, err)
+	return output, size, err
+}
+
+func (b *Bridge) executeSized(handlerName string, input map[string]interface{}) (map[string]interface{}, int, error) {
+	release, err := b.acquireHandler(context.Background(), handlerName)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer release()
+
+	inputJSON, err := b.marshalInput(handlerName, input)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var output map[string]interface{}
+	var size int
+	err = b.invoke(entryExecute, handlerName, inputJSON, func(result C.FfiResult) error {
+		var err error
+		if output, err = b.decodeResult(handlerName, result); err != nil {
+			return err
+		}
+		size = int(result.data_len)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return output, size, nil
+}
+
+// ExecuteHandlerAsync calls a side-effect-only handler, such as one that
+// emits an event or enqueues a job, checking only the result code: the
+// result body is never copied or decoded. Despite the name it returns when
+// the native call completes, as the FFI has no asynchronous entry point;
+// run it on a goroutine to avoid waiting. Audit events for these calls have
+// no ResultHash.
+func (b *Bridge) ExecuteHandlerAsync(handlerName string, input map[string]interface{}) error {
+	start := time.Now()
+	err := b.record(b.executeDiscard(handlerName, input))
+	b.finishCall(context.Background(), "handler call", handlerName, start, err)
+	b.audit(context.Background(), "handler call", handlerName, start, input, nil, err)
+	return err
+}
+
+func (b *Bridge) executeDiscard(handlerName string, input map[string]interface{}) error {
+	release, err := b.acquireHandler(context.Background(), handlerName)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	inputJSON, err := b.marshalInput(handlerName, input)
+	if err != nil {
+		return err
+	}
+
+	return b.invoke(entryExecute, handlerName, inputJSON, resultError)
+}
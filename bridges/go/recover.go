@@ -0,0 +1,25 @@
+package pforge
+
+import "fmt"
+
+// RecoverFunc turns a panic recovered around a native call into the error
+// the caller receives. recovered is the value passed to panic. It may
+// re-panic to crash the program instead.
+type RecoverFunc func(handlerName string, recovered any) error
+
+// recoverPanic is deferred around native calls so a panic in the Go side
+// of the call (input encoding, result decoding, callbacks) fails the call
+// rather than the program, or a goroutine the caller cannot reach. It sets
+// *err from the WithRecoverHandler function, or to an error wrapping
+// ErrHandlerPanic by default.
+func (b *Bridge) recoverPanic(handlerName string, err *error) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	if b.recoverHandler != nil {
+		*err = b.recoverHandler(handlerName, recovered)
+		return
+	}
+	*err = fmt.Errorf("%w: %s: %v", ErrHandlerPanic, handlerName, recovered)
+}
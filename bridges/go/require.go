@@ -0,0 +1,28 @@
+package pforge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExecuteHandlerRequire calls a handler like ExecuteHandler, then checks
+// that each of requiredKeys is present and non-null in the result. If any
+// is missing the error wraps ErrMissingResultField and names them all; the
+// result is still returned for inspection.
+func (b *Bridge) ExecuteHandlerRequire(handlerName string, input map[string]interface{}, requiredKeys ...string) (map[string]interface{}, error) {
+	output, err := b.ExecuteHandler(handlerName, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, key := range requiredKeys {
+		if output[key] == nil {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return output, fmt.Errorf("%w: %s returned no %s", ErrMissingResultField, handlerName, strings.Join(missing, ", "))
+	}
+	return output, nil
+}
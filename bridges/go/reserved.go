@@ -0,0 +1,44 @@
+package pforge
+
+import (
+	"context"
+	"fmt"
+)
+
+// reserveFields checks a caller's input for the fields the Bridge will
+// inject into this call: the envelope fields for handlerName plus any named
+// in injected. With WithStrictReservedFields a collision fails with
+// ErrReservedField. Otherwise the caller's value is dropped, with a warning
+// on the Bridge logger, so the input never carries a field twice and the
+// Bridge's value wins. The caller's map is never modified.
+func (b *Bridge) reserveFields(handlerName string, input map[string]interface{}, injected ...string) (map[string]interface{}, error) {
+	var colliding []string
+	check := func(keys []string) {
+		for _, key := range keys {
+			if _, ok := input[key]; ok {
+				colliding = append(colliding, key)
+			}
+		}
+	}
+	check(b.envelopeKeys)
+	check(b.handlerEnvelopeKeys[handlerName])
+	check(injected)
+	if len(colliding) == 0 {
+		return input, nil
+	}
+
+	if b.strictReserved {
+		return nil, fmt.Errorf("%w: input to %s sets %q", ErrReservedField, handlerName, colliding)
+	}
+
+	b.logger(context.Background()).Warn("overwriting reserved input fields",
+		"handler", handlerName, "fields", colliding)
+	stripped := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		stripped[k] = v
+	}
+	for _, key := range colliding {
+		delete(stripped, key)
+	}
+	return stripped, nil
+}
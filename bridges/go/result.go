@@ -0,0 +1,157 @@
+package pforge
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+)
+
+// Bytes is binary data carried in JSON as a base64 string, the encoding
+// json.Marshal already uses for []byte. Use it in structs decoded from
+// handler results so binary fields decode back to bytes instead of strings.
+type Bytes []byte
+
+// MarshalJSON encodes b as a base64 string
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]byte(b))
+}
+
+// UnmarshalJSON decodes a base64 string; null leaves b nil
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var raw []byte
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*b = raw
+	return nil
+}
+
+// Bytes returns the base64 string field key of the output decoded to
+// bytes. ok is false if the field is missing, not a string, or not valid
+// base64. Wrap an ExecuteHandler result as Result{Output: output} to use
+// the accessors on it.
+func (r Result) Bytes(key string) (value []byte, ok bool) {
+	s, ok := r.Output[key].(string)
+	if !ok {
+		return nil, false
+	}
+	value, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Float64 returns field key of the output as a float64. It accepts the
+// float64 that decoded results hold, json.Number, Go integer types, and
+// strings holding a decimal number, as some handlers send large or exact
+// values. ok is false for a missing field, any other type, a string that
+// is not a number, and NaN or infinity.
+func (r Result) Float64(key string) (value float64, ok bool) {
+	switch v := r.Output[key].(type) {
+	case float64:
+		value = v
+	case float32:
+		value = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, false
+		}
+		value = f
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		value = f
+	case uint64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	default:
+		i, ok := intValue(v)
+		if !ok {
+			return 0, false
+		}
+		return float64(i), true
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	return value, true
+}
+
+// Int64 returns field key of the output as an int64, accepting the same
+// representations as Float64. ok is false if the value has a fractional
+// part or does not fit in an int64. Integers in json.Number and string
+// form are parsed exactly; a float64 is only exact up to ±2^53.
+func (r Result) Int64(key string) (value int64, ok bool) {
+	switch v := r.Output[key].(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, true
+		}
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, true
+		}
+	default:
+		if i, ok := intValue(v); ok {
+			return i, true
+		}
+	}
+
+	// Forms like 1.0 or 1e3 that hold an integer
+	f, ok := r.Float64(key)
+	if !ok || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// Int returns field key of the output as an int, like Int64 but also
+// failing if the value does not fit in an int
+func (r Result) Int(key string) (value int, ok bool) {
+	i, ok := r.Int64(key)
+	if !ok || i < math.MinInt || i > math.MaxInt {
+		return 0, false
+	}
+	return int(i), true
+}
+
+// intValue converts Go integer types, as found in outputs built in Go
+// rather than decoded from JSON
+func intValue(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,115 @@
+package pforge
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestBytesRoundTrip sends arbitrary bytes through the echo handler as
+// JSON and reads them back with Result.Bytes and the Bytes type
+func TestBytesRoundTrip(t *testing.T) {
+	b := newStubBridge(t, nil)
+
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	for name, data := range map[string][]byte{
+		"empty":         {},
+		"every byte":    all,
+		"invalid utf-8": {0xff, 0xfe, 0xc3, 0x28},
+		"random":        random,
+	} {
+		t.Run(name, func(t *testing.T) {
+			output, err := b.ExecuteHandler("echo", map[string]interface{}{"blob": Bytes(data), "raw": data})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := Result{Output: output}
+			for _, key := range []string{"blob", "raw"} {
+				got, ok := result.Bytes(key)
+				if !ok {
+					t.Fatalf("Bytes(%q) failed on %#v", key, output[key])
+				}
+				if !bytes.Equal(got, data) {
+					t.Errorf("Bytes(%q) = %x, want %x", key, got, data)
+				}
+			}
+
+			encoded, err := json.Marshal(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded struct {
+				Blob Bytes `json:"blob"`
+			}
+			if err := json.Unmarshal(encoded, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded.Blob, data) {
+				t.Errorf("decoded Bytes = %x, want %x", decoded.Blob, data)
+			}
+		})
+	}
+}
+
+// TestResultNumbers covers each representation a number can arrive in
+func TestResultNumbers(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		// intOK reports whether Int64 and Int succeed, with wantInt
+		wantInt   int64
+		intOK     bool
+		wantFloat float64
+		floatOK   bool
+	}{
+		{name: "float64", value: float64(42), wantInt: 42, intOK: true, wantFloat: 42, floatOK: true},
+		{name: "fractional float64", value: 2.5, wantFloat: 2.5, floatOK: true},
+		{name: "float64 exponent", value: 1e3, wantInt: 1000, intOK: true, wantFloat: 1000, floatOK: true},
+		{name: "float32", value: float32(0.5), wantFloat: 0.5, floatOK: true},
+		{name: "json.Number integer", value: json.Number("9007199254740993"), wantInt: 9007199254740993, intOK: true, wantFloat: 9007199254740992, floatOK: true},
+		{name: "json.Number fraction", value: json.Number("-1.25"), wantFloat: -1.25, floatOK: true},
+		{name: "json.Number integral fraction", value: json.Number("7.0"), wantInt: 7, intOK: true, wantFloat: 7, floatOK: true},
+		{name: "numeric string", value: "-17", wantInt: -17, intOK: true, wantFloat: -17, floatOK: true},
+		{name: "decimal string", value: "3.75", wantFloat: 3.75, floatOK: true},
+		{name: "int", value: 5, wantInt: 5, intOK: true, wantFloat: 5, floatOK: true},
+		{name: "int8", value: int8(-8), wantInt: -8, intOK: true, wantFloat: -8, floatOK: true},
+		{name: "uint32", value: uint32(32), wantInt: 32, intOK: true, wantFloat: 32, floatOK: true},
+		{name: "uint64 over int64", value: uint64(math.MaxUint64), wantFloat: math.MaxUint64, floatOK: true},
+		{name: "float64 over int64", value: 1e19, wantFloat: 1e19, floatOK: true},
+		{name: "NaN string", value: "NaN"},
+		{name: "infinity", value: math.Inf(1)},
+		{name: "non-numeric string", value: "forty-two"},
+		{name: "bool", value: true},
+		{name: "nil", value: nil},
+		{name: "object", value: map[string]interface{}{"n": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Result{Output: map[string]interface{}{"n": tt.value}}
+
+			if got, ok := r.Float64("n"); ok != tt.floatOK || got != tt.wantFloat {
+				t.Errorf("Float64 = %v, %v; want %v, %v", got, ok, tt.wantFloat, tt.floatOK)
+			}
+			if got, ok := r.Int64("n"); ok != tt.intOK || got != tt.wantInt {
+				t.Errorf("Int64 = %v, %v; want %v, %v", got, ok, tt.wantInt, tt.intOK)
+			}
+			if got, ok := r.Int("n"); ok != tt.intOK || int64(got) != tt.wantInt {
+				t.Errorf("Int = %v, %v; want %v, %v", got, ok, tt.wantInt, tt.intOK)
+			}
+		})
+	}
+
+	var missing Result
+	if _, ok := missing.Int("n"); ok {
+		t.Error("Int succeeded on a missing field")
+	}
+}
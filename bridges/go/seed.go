@@ -0,0 +1,46 @@
+package pforge
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+
+	"example/pforgectx"
+)
+
+// ContextWithSeed returns a copy of ctx that makes ExecuteHandlerContext
+// and PreparedCall.Execute add seed to the input as SeedField, so handlers
+// that use randomness can seed their RNG and produce reproducible results,
+// e.g. in tests.
+//
+// The seed is advisory: only handlers that read SeedField use it, and
+// nothing stops a handler from drawing on other sources of randomness. A
+// handler that honors it should echo it in its result; see ResultSeed.
+func ContextWithSeed(ctx context.Context, seed int64) context.Context {
+	return pforgectx.WithSeed(ctx, seed)
+}
+
+// SeedFromContext returns the seed stored by ContextWithSeed
+func SeedFromContext(ctx context.Context) (seed int64, ok bool) {
+	return pforgectx.Seed(ctx)
+}
+
+// ResultSeed returns the SeedField a handler reported in its result,
+// which by convention means it honored that seed. ok is false if the
+// handler did not report one. Results decode numbers as float64, so seeds
+// beyond ±2^53 are only reported exactly by handlers called through a
+// path that keeps json.Number values.
+func ResultSeed(output map[string]interface{}) (seed int64, ok bool) {
+	switch v := output[SeedField].(type) {
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case json.Number:
+		seed, err := v.Int64()
+		return seed, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,94 @@
+package pforge
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"example/internal/schema"
+)
+
+// RedactedValue replaces the values of sensitive fields; see
+// WithSensitiveFields
+const RedactedValue = "***"
+
+// Redact returns a copy of v, an input or output map, with the fields
+// named by WithSensitiveFields replaced by RedactedValue, for logging or
+// tracing it without leaking secrets. Only the objects along sensitive
+// paths are copied; v itself is never modified. Without sensitive fields v
+// is returned as is.
+func (b *Bridge) Redact(v map[string]interface{}) map[string]interface{} {
+	if len(b.sensitive) == 0 || v == nil {
+		return v
+	}
+	for _, path := range b.sensitive {
+		v, _ = redactPath(v, path).(map[string]interface{})
+	}
+	return v
+}
+
+// redactPath replaces the value at path in v, copying what it changes. An
+// array along the path has the rest of the path applied to each element.
+func redactPath(v interface{}, path []string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		value, ok := v[path[0]]
+		if !ok {
+			return v
+		}
+		redacted := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			redacted[k] = val
+		}
+		if len(path) == 1 {
+			redacted[path[0]] = RedactedValue
+		} else {
+			redacted[path[0]] = redactPath(value, path[1:])
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactPath(item, path)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
+
+// arrayIndex matches the array indices in a validation error path
+var arrayIndex = regexp.MustCompile(`\[\d+\]`)
+
+// sensitivePath reports whether a validation error path such as
+// "$.user.tokens[2]" lies at or under a sensitive field
+func (b *Bridge) sensitivePath(path string) bool {
+	fields := strings.Split(strings.TrimPrefix(arrayIndex.ReplaceAllString(path, ""), "$."), ".")
+	for _, sensitive := range b.sensitive {
+		if len(fields) < len(sensitive) {
+			continue
+		}
+		match := true
+		for i, field := range sensitive {
+			if fields[i] != field {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// badInput wraps a schema validation error in ErrBadInput, dropping the
+// offending value from errors about a sensitive field
+func (b *Bridge) badInput(err error) error {
+	var validationErr *schema.ValidationError
+	if len(b.sensitive) == 0 || !errors.As(err, &validationErr) || !b.sensitivePath(validationErr.Path) {
+		return fmt.Errorf("%w: %v", ErrBadInput, err)
+	}
+	return fmt.Errorf("%w: %s: invalid value (redacted)", ErrBadInput, validationErr.Path)
+}
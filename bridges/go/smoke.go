@@ -0,0 +1,31 @@
+package pforge
+
+// SmokeResult is the outcome of calling one handler during SmokeTest
+type SmokeResult struct {
+	Handler string
+	Passed  bool
+	Err     error
+}
+
+// SmokeTest calls every registered handler once with the input produced by
+// inputFor, returning pass/fail per handler. Failures do not stop the sweep.
+// If the handler list itself cannot be fetched, a single failed result with
+// an empty Handler is returned.
+func (b *Bridge) SmokeTest(inputFor func(HandlerInfo) map[string]interface{}) []SmokeResult {
+	handlers, err := b.ListHandlers()
+	if err != nil {
+		return []SmokeResult{{Err: err}}
+	}
+
+	results := make([]SmokeResult, 0, len(handlers))
+	for _, info := range handlers {
+		_, err := b.ExecuteHandler(info.Name, inputFor(info))
+		results = append(results, SmokeResult{
+			Handler: info.Name,
+			Passed:  err == nil,
+			Err:     err,
+		})
+	}
+
+	return results
+}
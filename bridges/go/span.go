@@ -0,0 +1,107 @@
+package pforge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"example/pforgectx"
+)
+
+// SpanContext identifies a span for nesting call spans under it
+type SpanContext = pforgectx.SpanContext
+
+// ContextWithSpan returns a copy of ctx carrying the caller's span. Spans
+// written for calls made with that context join its trace and name it as
+// their parent.
+func ContextWithSpan(ctx context.Context, span SpanContext) context.Context {
+	return pforgectx.WithSpan(ctx, span)
+}
+
+// SpanFromContext returns the span stored by ContextWithSpan
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	return pforgectx.Span(ctx)
+}
+
+// SpanRecord is the JSON line written by WithSpanWriter for each call
+type SpanRecord struct {
+	TraceID      string    `json:"trace_id"`
+	SpanID       string    `json:"span_id"`
+	ParentSpanID string    `json:"parent_span_id,omitempty"`
+	Name         string    `json:"name"`
+	Handler      string    `json:"handler"`
+	Start        time.Time `json:"start"`
+	DurationUS   int64     `json:"duration_us"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// spanWriter serializes span records onto a shared writer
+type spanWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// write emits one record per line. Write errors are dropped: tracing must
+// not fail calls.
+func (s *spanWriter) write(record SpanRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Write(line)
+}
+
+// finishCall logs a completed call and writes its span
+func (b *Bridge) finishCall(ctx context.Context, msg, handlerName string, start time.Time, err error) {
+	b.finish(ctx, msg, handlerName, start, false, err)
+}
+
+// finishStream is finishCall for streams, which bypass circuit breaker
+// admission
+func (b *Bridge) finishStream(ctx context.Context, msg, handlerName string, start time.Time, err error) {
+	b.finish(ctx, msg, handlerName, start, true, err)
+}
+
+func (b *Bridge) finish(ctx context.Context, msg, handlerName string, start time.Time, stream bool, err error) {
+	b.logCall(ctx, msg, handlerName, start, err)
+	if b.breakers != nil {
+		b.breakers.record(handlerName, stream, err)
+	}
+	if b.spans == nil {
+		return
+	}
+
+	record := SpanRecord{
+		SpanID:     randomID(8),
+		Name:       msg,
+		Handler:    handlerName,
+		Start:      start,
+		DurationUS: time.Since(start).Microseconds(),
+	}
+	if parent, ok := SpanFromContext(ctx); ok {
+		record.TraceID = parent.TraceID
+		record.ParentSpanID = parent.SpanID
+	}
+	if record.TraceID == "" {
+		record.TraceID = randomID(16)
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	b.spans.write(record)
+}
+
+// randomID returns n random bytes in hex, the format of trace and span IDs
+func randomID(n int) string {
+	id := make([]byte, n)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
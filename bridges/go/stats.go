@@ -0,0 +1,98 @@
+package pforge
+
+import "sync/atomic"
+
+// Stats is a point-in-time snapshot of Bridge counters
+type Stats struct {
+	// Calls is the number of ExecuteHandler invocations
+	Calls uint64
+	// Errors is the number of calls that returned an error
+	Errors uint64
+	// BytesIn is the total size of serialized inputs passed to handlers
+	BytesIn uint64
+	// BytesOut is the total size of results returned by handlers
+	BytesOut uint64
+	// SchemaCacheHits is the number of schema lookups served from cache
+	SchemaCacheHits uint64
+	// SchemaCacheMisses is the number of schema lookups that crossed the FFI
+	SchemaCacheMisses uint64
+	// InFlight is the number of native calls and open streams right now
+	InFlight int64
+	// Queued is the number of handler calls admitted and not yet finished,
+	// running or waiting for a WithHandlerConcurrency slot; this is what
+	// WithAdmissionControl limits
+	Queued int64
+	// Shed is the number of calls rejected with ErrOverloaded
+	Shed uint64
+	// HandlerInFlight is the number of running calls per handler, for
+	// handlers limited with WithHandlerConcurrency
+	HandlerInFlight map[string]int
+	// Waiting is the number of calls waiting for a WithHandlerConcurrency
+	// slot, across handlers, per priority (see ContextWithPriority).
+	// Priorities with no waiting calls are left out.
+	Waiting map[Priority]int
+	// BatchSize is the size ExecuteBatch will use for its next batch,
+	// which changes over time with WithAdaptiveBatching
+	BatchSize int
+	// ResultCodes is the distribution of native result codes per handler
+	// for handler calls, batches and duplex streams
+	ResultCodes map[string]CodeCounts
+}
+
+// counters holds the live atomic counters behind Stats
+type counters struct {
+	calls    atomic.Uint64
+	errors   atomic.Uint64
+	bytesIn  atomic.Uint64
+	bytesOut atomic.Uint64
+
+	schemaHits   atomic.Uint64
+	schemaMisses atomic.Uint64
+
+	inFlight atomic.Int64
+
+	codes codeHistogram
+}
+
+// Stats returns a snapshot of the Bridge's internal counters.
+// Counters are updated atomically, so the snapshot is cheap and safe
+// to call from a debug endpoint while handlers are running.
+func (b *Bridge) Stats() Stats {
+	var (
+		handlerInFlight map[string]int
+		waiting         map[Priority]int
+	)
+	b.settings.RLock()
+	if len(b.handlerLimits) > 0 {
+		handlerInFlight = make(map[string]int, len(b.handlerLimits))
+		for name, slots := range b.handlerLimits {
+			_, held, handlerWaiting := slots.snapshot()
+			handlerInFlight[name] = held
+			for p, n := range handlerWaiting {
+				if waiting == nil {
+					waiting = make(map[Priority]int)
+				}
+				waiting[p] += n
+			}
+		}
+	}
+	b.settings.RUnlock()
+
+	return Stats{
+		Calls:    b.stats.calls.Load(),
+		Errors:   b.stats.errors.Load(),
+		BytesIn:  b.stats.bytesIn.Load(),
+		BytesOut: b.stats.bytesOut.Load(),
+
+		SchemaCacheHits:   b.stats.schemaHits.Load(),
+		SchemaCacheMisses: b.stats.schemaMisses.Load(),
+
+		InFlight:        b.stats.inFlight.Load(),
+		Queued:          b.admission.depth.Load(),
+		Shed:            b.admission.shed.Load(),
+		HandlerInFlight: handlerInFlight,
+		Waiting:         waiting,
+		BatchSize:       b.currentBatchSize(),
+		ResultCodes:     b.stats.codes.snapshot(),
+	}
+}
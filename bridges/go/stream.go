@@ -0,0 +1,281 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unsafe"
+)
+
+// nativeStream is an open native result stream
+type nativeStream struct {
+	bridge *Bridge
+	syms   *C.PforgeSymbols
+	handle unsafe.Pointer
+	// pace is set by watch when the stream is rate limited, and idle
+	// when it has an idle timeout
+	pace *throttle
+	idle *idleTimer
+}
+
+// openStream starts a streaming handler call. The stream counts as an
+// in-flight call until released by watch.
+func (b *Bridge) openStream(handlerName string, input map[string]interface{}) (stream *nativeStream, err error) {
+	if err := b.enter(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			b.leave()
+		}
+	}()
+
+	if !b.supports(FeatureStream) {
+		return nil, fmt.Errorf("%w: streaming results", ErrNotSupported)
+	}
+
+	inputJSON, err := b.marshalInput(handlerName, input)
+	if err != nil {
+		return nil, err
+	}
+	if inputJSON, err = b.wireInput(handlerName, inputJSON); err != nil {
+		return nil, err
+	}
+
+	cHandlerName := C.CString(handlerName)
+	defer C.free(unsafe.Pointer(cHandlerName))
+
+	syms := b.symbols()
+	var handle unsafe.Pointer
+	b.native(func() {
+		handle = C.pforge_call_stream_open(
+			syms,
+			cHandlerName,
+			(*C.uchar)(unsafe.Pointer(&inputJSON[0])),
+			C.size_t(len(inputJSON)),
+		)
+	})
+	if handle == nil {
+		return nil, fmt.Errorf("failed to open stream for handler %s", handlerName)
+	}
+	b.stats.bytesIn.Add(uint64(len(inputJSON)))
+
+	return &nativeStream{bridge: b, syms: syms, handle: handle}, nil
+}
+
+// next blocks for the next chunk, returning io.EOF at the end of the stream.
+// Keepalives are consumed here, restarting the idle timeout without
+// returning. The idle timeout only runs while waiting on the handler. On a
+// rate-limited stream it returns no sooner than the limit allows.
+func (s *nativeStream) next() (data []byte, err error) {
+	for {
+		if s.idle != nil {
+			s.idle.arm()
+		}
+		s.bridge.native(func() {
+			data, err = s.nextNative()
+		})
+		if s.idle != nil {
+			s.idle.disarm()
+			if idleErr := s.idle.err(); idleErr != nil {
+				return nil, idleErr
+			}
+		}
+		if err != errKeepalive {
+			break
+		}
+	}
+	if s.pace != nil && len(data) > 0 {
+		s.pace.wait(len(data))
+	}
+	return data, err
+}
+
+// errKeepalive is returned by nextNative for a keepalive result
+var errKeepalive = errors.New("keepalive")
+
+func (s *nativeStream) nextNative() ([]byte, error) {
+	result := C.pforge_call_stream_next(s.syms, s.handle)
+	defer C.pforge_call_free_result(s.syms, result)
+
+	if result.code == 0 && result.data == nil {
+		return nil, io.EOF
+	}
+	if result.code == CodeKeepalive {
+		return nil, errKeepalive
+	}
+
+	data, err := resultData(result)
+	if err != nil {
+		return nil, err
+	}
+	s.bridge.stats.bytesOut.Add(uint64(len(data)))
+	return data, nil
+}
+
+// watch cancels the native stream if ctx is done or Close stops waiting
+// for it. The returned function stops watching, waits for the watcher to
+// exit, and frees the stream, so it must be called once the caller has
+// stopped reading. It also applies the stream rate limit for ctx and
+// sets up the idle timeout.
+func (s *nativeStream) watch(ctx context.Context) (release func()) {
+	rate := s.bridge.streamRate(ctx)
+	ctx, cancel := s.bridge.scope(ctx)
+	s.pace = newThrottle(ctx, rate)
+	s.bridge.settings.RLock()
+	s.idle = newIdleTimer(s, s.bridge.streamIdleTimeout)
+	s.bridge.settings.RUnlock()
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			// Called directly, even with WithLockedThread, since it must
+			// interrupt a next call blocked on the locked thread
+			C.pforge_call_stream_cancel(s.syms, s.handle)
+		case <-done:
+			// A caller that cancelled ctx just before releasing still
+			// expects the native side to stop
+			if ctx.Err() != nil {
+				C.pforge_call_stream_cancel(s.syms, s.handle)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-exited
+		cancel()
+		if s.idle != nil {
+			s.idle.stop()
+		}
+		s.bridge.native(func() {
+			C.pforge_call_stream_free(s.syms, s.handle)
+		})
+		s.bridge.leave()
+	}
+}
+
+// streamReader exposes a native stream as an io.Reader
+type streamReader struct {
+	stream *nativeStream
+	buf    []byte
+	err    error
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.buf, r.err = r.stream.next()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// StreamChunk is one piece of a streaming handler result
+type StreamChunk struct {
+	// Data is the raw chunk. Each chunk is a separate copy, so it stays
+	// valid after later chunks arrive and after the stream fails.
+	Data []byte
+	// Err is set only on the last chunk of a stream that failed partway
+	// through, and Data is then nil
+	Err error
+}
+
+// ExecuteHandlerStream calls a streaming handler and delivers its result
+// chunks on the returned channel as the native side produces them.
+//
+// The channel is closed when the stream ends. A stream that completed
+// successfully just closes the channel; one that failed, including after
+// some chunks were delivered, first sends a final chunk with Err set. If
+// ctx is done the native stream is cancelled and the final error, if still
+// deliverable, wraps ErrCallerCancelled or ErrCallerDeadlineExceeded.
+//
+// Callers must read until the channel is closed or cancel ctx; the native
+// stream counts as an in-flight call until then.
+func (b *Bridge) ExecuteHandlerStream(ctx context.Context, handlerName string, input map[string]interface{}) (<-chan StreamChunk, error) {
+	start := time.Now()
+	ctx, cancelTimeout := b.handlerTimeout(ctx, handlerName)
+	stream, err := b.openStream(handlerName, input)
+	if err != nil {
+		cancelTimeout()
+		err = b.record(err)
+		b.finishStream(ctx, "handler stream", handlerName, start, err)
+		b.audit(ctx, "handler stream", handlerName, start, input, nil, err)
+		releaseCallHandle(ctx)
+		return nil, err
+	}
+
+	chunks := make(chan StreamChunk)
+	go func() {
+		defer close(chunks)
+		defer releaseCallHandle(ctx)
+		defer cancelTimeout()
+
+		release := stream.watch(ctx)
+		err := b.record(pumpStream(ctx, stream, chunks))
+		release()
+		b.finishStream(ctx, "handler stream", handlerName, start, err)
+		b.audit(ctx, "handler stream", handlerName, start, input, nil, err)
+		if err != nil {
+			select {
+			case chunks <- StreamChunk{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return chunks, nil
+}
+
+// pumpStream forwards chunks until the stream ends, returning nil at a
+// clean end of stream
+func pumpStream(ctx context.Context, stream *nativeStream, chunks chan<- StreamChunk) error {
+	return readStream(ctx, stream, func(data []byte) bool {
+		select {
+		case chunks <- StreamChunk{Data: data}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// readStream passes chunks to emit until the stream ends or emit returns
+// false, returning nil at a clean end of stream or when emit stopped it
+// with ctx still live
+func readStream(ctx context.Context, stream *nativeStream, emit func(data []byte) bool) error {
+	for {
+		data, err := stream.next()
+		if err == io.EOF {
+			if ctx.Err() != nil {
+				// Cancellation ends a native stream early without an error
+				return contextError(ctx)
+			}
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return contextError(ctx)
+			}
+			return err
+		}
+
+		if !emit(data) {
+			if ctx.Err() != nil {
+				return contextError(ctx)
+			}
+			return nil
+		}
+	}
+}
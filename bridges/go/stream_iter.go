@@ -0,0 +1,69 @@
+//go:build go1.23
+
+package pforge
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// Stream calls a streaming handler and returns its result chunks as an
+// iterator, the range-over-func form of ExecuteHandlerStream:
+//
+//	for chunk, err := range bridge.Stream(ctx, "export", input) {
+//		if err != nil {
+//			return err
+//		}
+//		w.Write(chunk.Data)
+//	}
+//
+// Each chunk is yielded with a nil error. A stream that fails, including
+// partway through, yields one final StreamChunk with Err set, together
+// with the same error. Breaking out of the loop cancels the native stream
+// and frees it before the loop statement finishes.
+//
+// The handler is called each time the iterator is ranged over.
+func (b *Bridge) Stream(ctx context.Context, handlerName string, input map[string]interface{}) iter.Seq2[StreamChunk, error] {
+	return func(yield func(StreamChunk, error) bool) {
+		if err := b.stream(ctx, handlerName, input, yield); err != nil {
+			yield(StreamChunk{Err: err}, err)
+		}
+	}
+}
+
+// stream runs one Stream iteration, returning the error to yield last
+func (b *Bridge) stream(ctx context.Context, handlerName string, input map[string]interface{}, yield func(StreamChunk, error) bool) error {
+	defer releaseCallHandle(ctx)
+
+	start := time.Now()
+	ctx, cancelTimeout := b.handlerTimeout(ctx, handlerName)
+	defer cancelTimeout()
+
+	stream, err := b.openStream(handlerName, input)
+	if err != nil {
+		err = b.record(err)
+		b.finishStream(ctx, "handler stream", handlerName, start, err)
+		b.audit(ctx, "handler stream", handlerName, start, input, nil, err)
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	release := stream.watch(ctx)
+	stopped := false
+	err = readStream(ctx, stream, func(data []byte) bool {
+		stopped = !yield(StreamChunk{Data: data}, nil)
+		return !stopped
+	})
+	// Cancel first so a stream left early is stopped on the native side
+	cancel()
+	release()
+
+	err = b.record(err)
+	b.finishStream(ctx, "handler stream", handlerName, start, err)
+	b.audit(ctx, "handler stream", handlerName, start, input, nil, err)
+	if stopped {
+		return nil
+	}
+	return err
+}
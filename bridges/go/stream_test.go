@@ -0,0 +1,70 @@
+package pforge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestStreamMidStreamError checks a stream that fails after three chunks
+// delivers them, then a final chunk carrying the error, and that a stream
+// that succeeds just closes the channel
+func TestStreamMidStreamError(t *testing.T) {
+	b := newStubBridge(t, nil)
+
+	for _, tt := range []struct {
+		handler string
+		fails   bool
+	}{
+		{handler: "echo"},
+		{handler: "fail", fails: true},
+	} {
+		t.Run(tt.handler, func(t *testing.T) {
+			chunks, err := b.ExecuteHandlerStream(context.Background(), tt.handler, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var data [][]byte
+			var streamErr error
+			for chunk := range chunks {
+				if streamErr != nil {
+					t.Fatalf("chunk after the error chunk: %+v", chunk)
+				}
+				if chunk.Err != nil {
+					if chunk.Data != nil {
+						t.Errorf("error chunk has data %q", chunk.Data)
+					}
+					streamErr = chunk.Err
+					continue
+				}
+				data = append(data, chunk.Data)
+			}
+
+			// Earlier chunks must still hold their own data
+			if len(data) != 3 {
+				t.Fatalf("got %d data chunks, want 3", len(data))
+			}
+			for i, chunk := range data {
+				if want := fmt.Sprintf(`{"chunk":%d}`, i+1); string(chunk) != want {
+					t.Errorf("chunk %d = %q, want %q", i, chunk, want)
+				}
+			}
+
+			if !tt.fails {
+				if streamErr != nil {
+					t.Fatalf("successful stream ended with %v", streamErr)
+				}
+				return
+			}
+			var handlerErr *HandlerError
+			if !errors.As(streamErr, &handlerErr) || !errors.Is(streamErr, ErrHandlerFailed) {
+				t.Fatalf("stream error = %v, want a *HandlerError wrapping ErrHandlerFailed", streamErr)
+			}
+			if handlerErr.Message != "stream broke after three chunks" {
+				t.Errorf("error message = %q", handlerErr.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// stub is a canned handler result set with WithStub
+type stub struct {
+	response map[string]interface{}
+	err      error
+}
+
+// invokeStub answers a call to a stubbed handler in place of the native
+// library. A stubbed error is returned as is; a stubbed response is encoded
+// and handed to consume as a successful native result would be.
+func (b *Bridge) invokeStub(s stub, handlerName string, payload []byte, consume func(C.FfiResult) error) error {
+	b.stats.bytesIn.Add(uint64(len(payload)))
+	if s.err != nil {
+		return s.err
+	}
+
+	var result C.FfiResult
+	if s.response != nil {
+		data, err := b.wireCodec(handlerName).Marshal(s.response)
+		if err != nil {
+			return fmt.Errorf("failed to marshal stub response: %w", err)
+		}
+		result.data = (*C.uchar)(C.CBytes(data))
+		defer C.free(unsafe.Pointer(result.data))
+		result.data_len = C.size_t(len(data))
+	}
+	b.stats.codes.observe(handlerName, CodeOK)
+	return consume(result)
+}
@@ -0,0 +1,75 @@
+package pforge
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubs caches the libraries built by stubLibrary, by compiler flags, in
+// a directory removed by TestMain
+var stubs struct {
+	mu   sync.Mutex
+	dir  string
+	libs map[string]string
+}
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "pforge-stub")
+	if err != nil {
+		panic(err)
+	}
+	stubs.dir = dir
+	stubs.libs = make(map[string]string)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// stubLibrary builds testdata/stub.c into a shared library and returns its
+// path, for tests that need native behaviour they control. defines are
+// passed to the C compiler as -D flags to select stub variants.
+func stubLibrary(tb testing.TB, defines ...string) string {
+	tb.Helper()
+
+	key := strings.Join(defines, " ")
+	stubs.mu.Lock()
+	defer stubs.mu.Unlock()
+	if lib, ok := stubs.libs[key]; ok {
+		return lib
+	}
+
+	cc := os.Getenv("CC")
+	if cc == "" {
+		cc = "cc"
+	}
+	lib := filepath.Join(stubs.dir, fmt.Sprintf("libstub%d.so", len(stubs.libs)))
+	args := []string{"-shared", "-fPIC", "-o", lib}
+	for _, define := range defines {
+		args = append(args, "-D"+define)
+	}
+	args = append(args, filepath.Join("testdata", "stub.c"), "-lpthread")
+	if out, err := exec.Command(cc, args...).CombinedOutput(); err != nil {
+		tb.Fatalf("building stub library: %v\n%s", err, out)
+	}
+	stubs.libs[key] = lib
+	return lib
+}
+
+// newStubBridge returns a Bridge over the stub library, closed when the
+// test ends
+func newStubBridge(tb testing.TB, defines []string, opts ...Option) *Bridge {
+	tb.Helper()
+
+	b, err := NewBridgeWithLibrary(stubLibrary(tb, defines...), opts...)
+	if err != nil {
+		tb.Fatalf("loading stub library: %v", err)
+	}
+	tb.Cleanup(func() { b.Close() })
+	return b
+}
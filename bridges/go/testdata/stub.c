@@ -0,0 +1,252 @@
+// Stub native library for the bridge tests, built by stubLibrary and loaded
+// with NewBridgeWithLibrary. Each entry point implements just enough of the
+// C ABI for the tests, with behaviour chosen by handler name.
+
+#include <pthread.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <unistd.h>
+
+typedef struct {
+    int code;
+    unsigned char* data;
+    size_t data_len;
+    const char* error;
+} FfiResult;
+
+static FfiResult ok(const void* data, size_t len) {
+    FfiResult r = {0};
+    if (len > 0) {
+        r.data = malloc(len);
+        memcpy(r.data, data, len);
+        r.data_len = len;
+    }
+    return r;
+}
+
+static FfiResult fail(int code, const char* message) {
+    FfiResult r = {0};
+    r.code = code;
+    r.error = strdup(message);
+    return r;
+}
+
+const char* pforge_version(void) { return "0.0.0-stub"; }
+
+uint32_t pforge_abi_version(void) { return 1; }
+
+// pforge_execute_handler echoes its input. The "fail" handler fails and
+// the "slow" handler takes 20ms.
+FfiResult pforge_execute_handler(const char* name, const unsigned char* input, size_t len) {
+    if (strcmp(name, "fail") == 0) {
+        return fail(-3, "stub failure");
+    }
+    if (strcmp(name, "slow") == 0) {
+        usleep(20000);
+    }
+    return ok(input, len);
+}
+
+void pforge_free_result(FfiResult r) {
+    free(r.data);
+    free((void*)r.error);
+}
+
+#ifdef CODECS
+// With CODECS the library and every handler support JSON and MessagePack.
+// Echoed inputs come back in the handler's codec without the stub decoding
+// them.
+
+static const char codecs[] = "[\"json\",\"msgpack\"]";
+
+static int known_codec(const char* codec) {
+    return strcmp(codec, "json") == 0 || strcmp(codec, "msgpack") == 0 ? 0 : -4;
+}
+
+FfiResult pforge_codecs(void) { return ok(codecs, strlen(codecs)); }
+
+int pforge_select_codec(const char* codec) { return known_codec(codec); }
+
+FfiResult pforge_handler_codecs(const char* name) { return ok(codecs, strlen(codecs)); }
+
+int pforge_select_handler_codec(const char* name, const char* codec) { return known_codec(codec); }
+#endif
+
+// Duplex streams echo each input with "_queued" set to the number of inputs
+// waiting in the stream when it was received, so tests can check the window
+// is never exceeded. The "slow" handler takes 2ms to produce each result.
+
+typedef struct message {
+    unsigned char* data;
+    size_t len;
+    struct message* next;
+} message;
+
+typedef struct {
+    pthread_mutex_t mu;
+    pthread_cond_t cv;
+    message *head, *tail;
+    int queued, closed, cancelled, slow;
+} duplex;
+
+void* pforge_duplex_open(const char* name) {
+    duplex* d = calloc(1, sizeof(duplex));
+    pthread_mutex_init(&d->mu, NULL);
+    pthread_cond_init(&d->cv, NULL);
+    d->slow = strcmp(name, "slow") == 0;
+    return d;
+}
+
+size_t pforge_duplex_window(void* stream) { return 4; }
+
+int pforge_duplex_send(void* stream, const unsigned char* input, size_t len) {
+    duplex* d = stream;
+    message* m = calloc(1, sizeof(message));
+    m->data = malloc(len);
+    memcpy(m->data, input, len);
+    m->len = len;
+
+    pthread_mutex_lock(&d->mu);
+    if (d->tail) {
+        d->tail->next = m;
+    } else {
+        d->head = m;
+    }
+    d->tail = m;
+    d->queued++;
+    pthread_cond_broadcast(&d->cv);
+    pthread_mutex_unlock(&d->mu);
+    return 0;
+}
+
+FfiResult pforge_duplex_recv(void* stream) {
+    duplex* d = stream;
+    FfiResult r = {0};
+    if (d->slow) {
+        usleep(2000);
+    }
+
+    pthread_mutex_lock(&d->mu);
+    while (!d->head && !d->closed && !d->cancelled) {
+        pthread_cond_wait(&d->cv, &d->mu);
+    }
+    if (d->cancelled || !d->head) {
+        pthread_mutex_unlock(&d->mu);
+        return r;
+    }
+    message* m = d->head;
+    d->head = m->next;
+    if (!d->head) {
+        d->tail = NULL;
+    }
+    int queued = d->queued--;
+    pthread_mutex_unlock(&d->mu);
+
+    // Splice "_queued" in after the opening brace of the echoed input
+    size_t cap = m->len + 32;
+    r.data = malloc(cap);
+    r.data_len = snprintf((char*)r.data, cap, "{\"_queued\":%d,%.*s", queued, (int)m->len - 1, m->data + 1);
+    free(m->data);
+    free(m);
+    return r;
+}
+
+static void duplex_wake(void* stream, int cancel) {
+    duplex* d = stream;
+    pthread_mutex_lock(&d->mu);
+    if (cancel) {
+        d->cancelled = 1;
+    } else {
+        d->closed = 1;
+    }
+    pthread_cond_broadcast(&d->cv);
+    pthread_mutex_unlock(&d->mu);
+}
+
+void pforge_duplex_close(void* stream) { duplex_wake(stream, 0); }
+
+void pforge_duplex_cancel(void* stream) { duplex_wake(stream, 1); }
+
+void pforge_duplex_free(void* stream) {
+    duplex* d = stream;
+    while (d->head) {
+        message* m = d->head;
+        d->head = m->next;
+        free(m->data);
+        free(m);
+    }
+    free(d);
+}
+
+#ifndef NO_BULK_FREE
+void pforge_free_results(FfiResult* results, size_t count) {
+    for (size_t i = 0; i < count; i++) {
+        pforge_free_result(results[i]);
+    }
+}
+#endif
+
+// Streams emit three chunks {"chunk":1} to {"chunk":3} and end, except
+// that the "fail" handler then fails instead of ending, and the "bulk"
+// handler emits ten 1000-byte chunks instead. The "pause" handler first
+// sends ten keepalives 10ms apart, and the "stall" handler first goes
+// silent for 200ms or until cancelled.
+
+typedef struct {
+    char name[32];
+    int step;
+    int keepalives;
+    int cancelled;
+} stream;
+
+void* pforge_stream_open(const char* name, const unsigned char* input, size_t len) {
+    stream* s = calloc(1, sizeof(stream));
+    strncpy(s->name, name, sizeof(s->name) - 1);
+    return s;
+}
+
+FfiResult pforge_stream_next(void* handle) {
+    stream* s = handle;
+    FfiResult r = {0};
+    if (__atomic_load_n(&s->cancelled, __ATOMIC_SEQ_CST)) {
+        return r;
+    }
+
+    if (strcmp(s->name, "pause") == 0 && s->keepalives < 10) {
+        s->keepalives++;
+        usleep(10000);
+        r.code = 1;  // CodeKeepalive
+        return r;
+    }
+    s->step++;
+    if (strcmp(s->name, "stall") == 0 && s->step == 1) {
+        for (int i = 0; i < 200 && !__atomic_load_n(&s->cancelled, __ATOMIC_SEQ_CST); i++) {
+            usleep(1000);
+        }
+    }
+    if (strcmp(s->name, "bulk") == 0) {
+        if (s->step > 10) {
+            return r;
+        }
+        char chunk[1000];
+        memset(chunk, 'x', sizeof(chunk));
+        return ok(chunk, sizeof(chunk));
+    }
+    if (s->step <= 3) {
+        char chunk[32];
+        return ok(chunk, snprintf(chunk, sizeof(chunk), "{\"chunk\":%d}", s->step));
+    }
+    if (strcmp(s->name, "fail") == 0) {
+        return fail(-3, "stream broke after three chunks");
+    }
+    return r;
+}
+
+void pforge_stream_cancel(void* handle) {
+    stream* s = handle;
+    __atomic_store_n(&s->cancelled, 1, __ATOMIC_SEQ_CST);
+}
+
+void pforge_stream_free(void* handle) { free(handle); }
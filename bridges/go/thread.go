@@ -0,0 +1,47 @@
+package pforge
+
+import "runtime"
+
+// lockedThread runs functions one at a time on a goroutine locked to a
+// single OS thread
+type lockedThread struct {
+	calls chan func()
+}
+
+func newLockedThread() *lockedThread {
+	t := &lockedThread{calls: make(chan func())}
+	go t.loop()
+	return t
+}
+
+func (t *lockedThread) loop() {
+	// Never unlocked: the thread exits with the goroutine after stop
+	runtime.LockOSThread()
+	for fn := range t.calls {
+		fn()
+	}
+}
+
+// do runs fn on the locked thread and waits for it to return
+func (t *lockedThread) do(fn func()) {
+	done := make(chan struct{})
+	t.calls <- func() {
+		defer close(done)
+		fn()
+	}
+	<-done
+}
+
+// stop ends the thread once queued calls have run
+func (t *lockedThread) stop() {
+	close(t.calls)
+}
+
+// native runs a native call, on the locked thread if WithLockedThread is set
+func (b *Bridge) native(fn func()) {
+	if b.thread == nil {
+		fn()
+		return
+	}
+	b.thread.do(fn)
+}
@@ -0,0 +1,62 @@
+package pforge
+
+import (
+	"context"
+	"time"
+)
+
+// throttle paces stream delivery to a byte rate. It tracks the total sent
+// since the stream started rather than a token bucket, so the average rate
+// holds exactly and a burst is at most one chunk.
+type throttle struct {
+	ctx         context.Context
+	bytesPerSec int
+	start       time.Time
+	sent        int64
+}
+
+// newThrottle returns nil, meaning unthrottled, for a rate of zero or less
+func newThrottle(ctx context.Context, bytesPerSec int) *throttle {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &throttle{ctx: ctx, bytesPerSec: bytesPerSec, start: time.Now()}
+}
+
+// wait accounts for n delivered bytes and sleeps until delivering them is
+// within the rate, returning early if ctx is done
+func (t *throttle) wait(n int) {
+	t.sent += int64(n)
+	due := t.start.Add(time.Duration(t.sent * int64(time.Second) / int64(t.bytesPerSec)))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-t.ctx.Done():
+	}
+}
+
+// ContextWithStreamRateLimit returns a copy of ctx that caps streams read
+// with it at bytesPerSec, overriding WithStreamRateLimit for those calls.
+// Zero or less lifts the Bridge-wide limit.
+func ContextWithStreamRateLimit(ctx context.Context, bytesPerSec int) context.Context {
+	return context.WithValue(ctx, streamRateKey{}, bytesPerSec)
+}
+
+// streamRateKey is the context key for ContextWithStreamRateLimit
+type streamRateKey struct{}
+
+// streamRate returns the rate limit for a stream read with ctx
+func (b *Bridge) streamRate(ctx context.Context) int {
+	if rate, ok := ctx.Value(streamRateKey{}).(int); ok {
+		return rate
+	}
+	b.settings.RLock()
+	defer b.settings.RUnlock()
+	return b.streamRateLimit
+}
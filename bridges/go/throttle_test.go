@@ -0,0 +1,85 @@
+package pforge
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestStreamRateLimit streams 10000 bytes under a rate limit and checks
+// the measured throughput stays at or just under it
+func TestStreamRateLimit(t *testing.T) {
+	const total = 10000
+
+	tests := []struct {
+		name string
+		opts []Option
+		ctx  func() context.Context
+		rate int
+	}{
+		{
+			name: "bridge limit",
+			opts: []Option{WithStreamRateLimit(40000)},
+			ctx:  context.Background,
+			rate: 40000,
+		},
+		{
+			name: "per-stream limit",
+			opts: []Option{WithStreamRateLimit(1000)},
+			ctx: func() context.Context {
+				return ContextWithStreamRateLimit(context.Background(), 100000)
+			},
+			rate: 100000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newStubBridge(t, nil, tt.opts...)
+
+			start := time.Now()
+			chunks, err := b.ExecuteHandlerStream(tt.ctx(), "bulk", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := 0
+			for chunk := range chunks {
+				if chunk.Err != nil {
+					t.Fatal(chunk.Err)
+				}
+				n += len(chunk.Data)
+			}
+			elapsed := time.Since(start)
+
+			if n != total {
+				t.Fatalf("streamed %d bytes, want %d", n, total)
+			}
+			throughput := float64(n) / elapsed.Seconds()
+			t.Logf("%d bytes in %v: %.0f bytes/s, limit %d", n, elapsed, throughput, tt.rate)
+			if throughput > float64(tt.rate)*1.05 {
+				t.Errorf("throughput %.0f bytes/s exceeds the limit of %d", throughput, tt.rate)
+			}
+			if throughput < float64(tt.rate)/2 {
+				t.Errorf("throughput %.0f bytes/s is far below the limit of %d", throughput, tt.rate)
+			}
+		})
+	}
+}
+
+// TestStreamRateLimitCancel checks a throttled stream stops waiting when
+// its context is cancelled
+func TestStreamRateLimitCancel(t *testing.T) {
+	b := newStubBridge(t, nil, WithStreamRateLimit(100))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	chunks, err := b.ExecuteHandlerStream(ctx, "bulk", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range chunks {
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("cancelled stream took %v to end", elapsed)
+	}
+}
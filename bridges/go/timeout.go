@@ -0,0 +1,31 @@
+package pforge
+
+import (
+	"context"
+	"time"
+)
+
+// handlerTimeout applies the handler's default timeout (WithHandlerTimeout,
+// then WithDefaultTimeout) to ctx if the caller set no deadline. The
+// returned cancel must always be called.
+func (b *Bridge) handlerTimeout(ctx context.Context, handlerName string) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+
+	timeout := b.timeoutFor(handlerName)
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timeoutFor returns the handler's default timeout, zero or less for none
+func (b *Bridge) timeoutFor(handlerName string) time.Duration {
+	b.settings.RLock()
+	defer b.settings.RUnlock()
+	if timeout, ok := b.handlerTimeouts[handlerName]; ok {
+		return timeout
+	}
+	return b.defaultTimeout
+}
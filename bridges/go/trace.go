@@ -0,0 +1,133 @@
+package pforge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// TraceRecord is one line of a JSON Lines call trace, as written by
+// WithTraceRecorder: a handler call and the result it produced when
+// recorded. Error holds the error message of a failed call, in which case
+// Output is empty.
+type TraceRecord struct {
+	Handler string                 `json:"handler"`
+	Input   map[string]interface{} `json:"input"`
+	Output  map[string]interface{} `json:"output,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// traceWriter serializes trace records onto a shared writer
+type traceWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// write emits one record per line. Write errors are dropped: recording
+// must not fail calls.
+func (t *traceWriter) write(record TraceRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.w.Write(line)
+}
+
+// recordTrace writes a finished call to the trace recorder, if one is set.
+// Only calls with a map input and, unless they failed, a map result can be
+// replayed, so others are skipped.
+func (b *Bridge) recordTrace(handlerName string, input, output any, err error) {
+	if b.traces == nil {
+		return
+	}
+	in, ok := input.(map[string]interface{})
+	if !ok && input != nil {
+		return
+	}
+	record := TraceRecord{Handler: handlerName, Input: b.Redact(in)}
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		out, ok := output.(map[string]interface{})
+		if !ok {
+			return
+		}
+		record.Output = b.Redact(out)
+	}
+	b.traces.write(record)
+}
+
+// TraceReport summarizes a VerifyTrace run
+type TraceReport struct {
+	// Calls is the number of records replayed; Matched and Diverged split it
+	Calls    int
+	Matched  int
+	Diverged int
+	// Diffs holds the differences of the diverged calls, with Index the
+	// record's position in the trace, Old the recorded value and New the
+	// replayed one
+	Diffs []Diff
+}
+
+// OK reports whether every replayed call matched its recording
+func (r TraceReport) OK() bool {
+	return r.Diverged == 0
+}
+
+func (r TraceReport) String() string {
+	return fmt.Sprintf("replayed %d calls: %d matched, %d diverged", r.Calls, r.Matched, r.Diverged)
+}
+
+// VerifyTrace replays each TraceRecord read from trace, such as one written
+// by WithTraceRecorder, against exec and checks that the result matches the
+// recorded one, turning recorded traffic into a regression corpus. Outputs
+// are compared as CompareOutputs does, so IgnoreFields skips volatile
+// fields; a recorded failure matches a replayed one with the same error
+// message. Calls run sequentially in trace order.
+//
+// The error is non-nil only if the trace cannot be read or parsed, or ctx
+// is done, in which case the report covers the calls replayed so far.
+// Divergences are reported in the TraceReport; gate CI on its OK method.
+func VerifyTrace(ctx context.Context, exec Executor, trace io.Reader, opts ...CompareOption) (TraceReport, error) {
+	cfg := compareConfig{ignore: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var report TraceReport
+	dec := json.NewDecoder(trace)
+	for {
+		var record TraceRecord
+		if err := dec.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return report, fmt.Errorf("trace record %d: %w", report.Calls, err)
+		}
+		if ctx.Err() != nil {
+			return report, contextError(ctx)
+		}
+
+		var recordedErr error
+		if record.Error != "" {
+			recordedErr = errors.New(record.Error)
+		}
+		output, err := exec.ExecuteHandlerContext(ctx, record.Handler, record.Input)
+		diffs := cfg.diff(report.Calls, record.Handler, record.Output, recordedErr, output, err)
+
+		report.Calls++
+		if len(diffs) == 0 {
+			report.Matched++
+			continue
+		}
+		report.Diverged++
+		report.Diffs = append(report.Diffs, diffs...)
+	}
+	return report, nil
+}
@@ -0,0 +1,42 @@
+package pforge
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestTraceRecordReplay checks a trace written by WithTraceRecorder, with
+// sensitive fields redacted, replays cleanly through VerifyTrace
+func TestTraceRecordReplay(t *testing.T) {
+	var trace bytes.Buffer
+	b := newStubBridge(t, nil, WithTraceRecorder(&trace), WithSensitiveFields([]string{"token"}))
+
+	if _, err := b.ExecuteHandler("echo", map[string]interface{}{"n": 1, "token": "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.ExecuteHandlerContext(context.Background(), "fail", map[string]interface{}{"n": 2}); err == nil {
+		t.Fatal("fail handler succeeded")
+	}
+	if err := b.ExecuteHandlerWith("echo", nil, func([]byte) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Count(trace.String(), "\n"); lines != 2 {
+		t.Fatalf("trace has %d records, want 2 (the borrowed result is not replayable):\n%s", lines, trace.String())
+	}
+	if strings.Contains(trace.String(), "s3cret") {
+		t.Fatalf("trace holds a sensitive value:\n%s", trace.String())
+	}
+
+	// Replaying through b records again, so read from a copy
+	recorded := bytes.NewReader(trace.Bytes())
+	report, err := VerifyTrace(context.Background(), b, recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Calls != 2 || !report.OK() {
+		t.Errorf("%v, diffs %v; want both calls to match", report, report.Diffs)
+	}
+}
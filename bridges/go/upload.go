@@ -0,0 +1,143 @@
+package pforge
+
+/*
+#include "pforge_bridge.h"
+*/
+import "C"
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+	"unsafe"
+)
+
+// UploadChunkSize is the largest chunk ExecuteHandlerUpload passes to the
+// native side in one crossing
+const UploadChunkSize = 1 << 20
+
+// ExecuteHandlerUpload calls a handler with input streamed from r, for
+// inputs too large to hold in memory. r is read in chunks of up to
+// UploadChunkSize bytes, each handed to the native side before the next is
+// read; the handler runs once r reaches io.EOF and its single result is
+// returned.
+//
+// The bytes are passed through as they are: they are not validated, and
+// envelope fields such as WithClientIdentity are not added. What they must
+// contain (JSON, or raw data the handler parses itself) is up to the
+// handler.
+//
+// If reading r fails, or the native side rejects a chunk, the upload is
+// aborted and the handler does not run. A rejected chunk fails with a
+// HandlerError carrying the native code.
+//
+// With both WithAuditSink and WithSensitiveFields, the input is also kept
+// in memory until the call ends, so that it can be redacted before its
+// audit hash is computed.
+func (b *Bridge) ExecuteHandlerUpload(handlerName string, r io.Reader) (map[string]interface{}, error) {
+	start := time.Now()
+	var inputHash hash.Hash
+	var input bytes.Buffer
+	switch {
+	case b.auditSink == nil:
+	case len(b.sensitive) > 0:
+		r = io.TeeReader(r, &input)
+	default:
+		inputHash = sha256.New()
+		r = io.TeeReader(r, inputHash)
+	}
+	output, err := b.observe(b.executeUpload(handlerName, r))
+	b.finishCall(context.Background(), "upload call", handlerName, start, err)
+	if b.auditSink != nil {
+		var digest auditDigest
+		if inputHash != nil {
+			digest = auditDigest(hex.EncodeToString(inputHash.Sum(nil)))
+		} else {
+			digest = b.auditRawHash(input.Bytes())
+		}
+		b.audit(context.Background(), "upload call", handlerName, start, digest, output, err)
+	}
+	return output, err
+}
+
+func (b *Bridge) executeUpload(handlerName string, r io.Reader) (output map[string]interface{}, err error) {
+	if err := b.enter(); err != nil {
+		return nil, err
+	}
+	defer b.leave()
+
+	if !b.supports(FeatureUpload) {
+		return nil, fmt.Errorf("%w: chunked upload", ErrNotSupported)
+	}
+
+	release, err := b.acquireHandler(context.Background(), handlerName)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer b.recoverPanic(handlerName, &err)
+
+	cHandlerName := C.CString(handlerName)
+	defer C.free(unsafe.Pointer(cHandlerName))
+
+	syms := b.symbols()
+	var upload unsafe.Pointer
+	b.native(func() {
+		upload = C.pforge_call_upload_open(syms, cHandlerName)
+	})
+	if upload == nil {
+		return nil, fmt.Errorf("failed to open upload for handler %s", handlerName)
+	}
+	finished := false
+	defer func() {
+		if !finished {
+			b.native(func() {
+				C.pforge_call_upload_abort(syms, upload)
+			})
+		}
+	}()
+
+	buf := make([]byte, UploadChunkSize)
+	var sent uint64
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			var code C.int
+			b.native(func() {
+				code = C.pforge_call_upload_write(
+					syms,
+					upload,
+					(*C.uchar)(unsafe.Pointer(&buf[0])),
+					C.size_t(n),
+				)
+			})
+			if code != 0 {
+				msg := fmt.Sprintf("upload rejected after %d bytes", sent)
+				return nil, newHandlerError(int(code), msg, nativeErrorKind(int(code)))
+			}
+			sent += uint64(n)
+			b.stats.bytesIn.Add(uint64(n))
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("reading upload for handler %s after %d bytes: %w", handlerName, sent, readErr)
+		}
+	}
+
+	// finish consumes the upload whatever the outcome
+	finished = true
+	b.native(func() {
+		result := C.pforge_call_upload_finish(syms, upload)
+		defer C.pforge_call_free_result(syms, result)
+		b.stats.codes.observe(handlerName, int(result.code))
+		output, err = b.decodeResult(handlerName, result)
+	})
+	return output, err
+}
@@ -0,0 +1,53 @@
+package pforge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SlowWarmupThreshold is the warmup time above which Warmup logs a warning
+const SlowWarmupThreshold = 500 * time.Millisecond
+
+// Warmup calls every registered handler once with the input produced by
+// inputFor, so their initialization cost is paid before real traffic
+// arrives, and returns how long each call took. On a freshly loaded library
+// that is the handler's cold-start cost, for budgeting startup time; calls
+// taking longer than SlowWarmupThreshold are logged as warnings.
+//
+// Handlers are called one at a time in ListHandlers order, so timings do
+// not include contention between them. A failed call is still timed, and
+// failures are returned joined, each naming its handler. If ctx is done the
+// sweep stops with the context error, returning the timings so far.
+func (b *Bridge) Warmup(ctx context.Context, inputFor func(HandlerInfo) map[string]interface{}) (map[string]time.Duration, error) {
+	handlers, err := b.ListHandlers()
+	if err != nil {
+		return nil, err
+	}
+
+	timings := make(map[string]time.Duration, len(handlers))
+	var errs []error
+	for _, info := range handlers {
+		if ctx.Err() != nil {
+			return timings, contextError(ctx)
+		}
+
+		start := time.Now()
+		_, err := b.ExecuteHandlerContext(ctx, info.Name, inputFor(info))
+		elapsed := time.Since(start)
+		timings[info.Name] = elapsed
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("handler %s: %w", info.Name, err))
+		}
+		if elapsed > SlowWarmupThreshold {
+			b.logger(ctx).LogAttrs(ctx, slog.LevelWarn, "slow handler warmup",
+				slog.String("handler", info.Name),
+				slog.Duration("duration", elapsed),
+			)
+		}
+	}
+	return timings, errors.Join(errs...)
+}
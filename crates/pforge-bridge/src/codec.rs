@@ -0,0 +1,149 @@
+//! Wire codec handshake
+//!
+//! Bridges ask which codecs the library supports and select one before
+//! their first call, and may override it for single handlers. Handlers
+//! currently exchange JSON only.
+
+use std::ffi::CStr;
+use std::os::raw::{c_char, c_int};
+
+use crate::{
+    catch_panic, handler_name_str, pforge_free_result, FfiResult, PFORGE_ERR_INVALID_INPUT,
+    PFORGE_ERR_NULL_POINTER, PFORGE_OK,
+};
+
+/// Codecs the library can decode inputs from and encode results to, in
+/// order of preference
+pub const CODECS: &[&str] = &["json"];
+
+/// Checks a codec name argument, returning the code for
+/// `pforge_select_codec`
+///
+/// # Safety
+/// - `codec` must be null or a valid null-terminated string
+pub(crate) unsafe fn check_codec(codec: *const c_char) -> c_int {
+    if codec.is_null() {
+        return PFORGE_ERR_NULL_POINTER;
+    }
+    match CStr::from_ptr(codec).to_str() {
+        Ok(codec) if CODECS.contains(&codec) => PFORGE_OK,
+        _ => PFORGE_ERR_INVALID_INPUT,
+    }
+}
+
+/// List the supported codecs as a JSON array of names
+///
+/// # Safety
+/// - Caller must free the result with `pforge_free_result`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_codecs() -> FfiResult {
+    catch_panic(|| FfiResult::json(CODECS))
+}
+
+/// Select the codec for all later calls
+///
+/// Returns 0 if the codec is supported, and a non-zero code otherwise.
+///
+/// # Safety
+/// - `codec` must be a valid null-terminated string
+#[no_mangle]
+pub unsafe extern "C" fn pforge_select_codec(codec: *const c_char) -> c_int {
+    check_codec(codec)
+}
+
+/// List the codecs one handler supports as a JSON array of names
+///
+/// # Safety
+/// - `handler_name` must be a valid null-terminated string
+/// - Caller must free the result with `pforge_free_result`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_handler_codecs(handler_name: *const c_char) -> FfiResult {
+    catch_panic(|| match handler_name_str(handler_name) {
+        // Every handler supports every codec the library does
+        Ok(_) => FfiResult::json(CODECS),
+        Err(result) => result,
+    })
+}
+
+/// Select the codec for one handler's later calls, overriding
+/// `pforge_select_codec`
+///
+/// Returns 0 if the handler supports the codec, and a non-zero code
+/// otherwise.
+///
+/// # Safety
+/// - `handler_name` and `codec` must be valid null-terminated strings
+#[no_mangle]
+pub unsafe extern "C" fn pforge_select_handler_codec(
+    handler_name: *const c_char,
+    codec: *const c_char,
+) -> c_int {
+    match handler_name_str(handler_name) {
+        Ok(_) => check_codec(codec),
+        Err(result) => {
+            let code = result.code;
+            pforge_free_result(result);
+            code
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::pforge_free_result;
+    use std::ffi::CString;
+    use std::slice;
+
+    #[test]
+    fn test_codecs() {
+        unsafe {
+            let result = pforge_codecs();
+            assert_eq!(result.code, 0);
+            let data = slice::from_raw_parts(result.data, result.data_len);
+            let codecs: Vec<String> = serde_json::from_slice(data).unwrap();
+            assert_eq!(codecs, vec!["json"]);
+            pforge_free_result(result);
+        }
+    }
+
+    #[test]
+    fn test_select_codec() {
+        unsafe {
+            let json = CString::new("json").unwrap();
+            assert_eq!(pforge_select_codec(json.as_ptr()), PFORGE_OK);
+            let msgpack = CString::new("msgpack").unwrap();
+            assert_eq!(
+                pforge_select_codec(msgpack.as_ptr()),
+                PFORGE_ERR_INVALID_INPUT
+            );
+            assert_eq!(
+                pforge_select_codec(std::ptr::null()),
+                PFORGE_ERR_NULL_POINTER
+            );
+        }
+    }
+
+    #[test]
+    fn test_handler_codecs() {
+        unsafe {
+            let name = CString::new("encode").unwrap();
+            let result = pforge_handler_codecs(name.as_ptr());
+            assert_eq!(result.code, 0);
+            let data = slice::from_raw_parts(result.data, result.data_len);
+            let codecs: Vec<String> = serde_json::from_slice(data).unwrap();
+            assert_eq!(codecs, CODECS);
+            pforge_free_result(result);
+
+            let json = CString::new("json").unwrap();
+            assert_eq!(
+                pforge_select_handler_codec(name.as_ptr(), json.as_ptr()),
+                PFORGE_OK
+            );
+            assert_eq!(
+                pforge_select_handler_codec(std::ptr::null(), json.as_ptr()),
+                PFORGE_ERR_NULL_POINTER
+            );
+        }
+    }
+}
@@ -0,0 +1,311 @@
+//! Duplex streams: many inputs in, many results out
+//!
+//! Each input runs the handler as it is sent, and its result is queued for
+//! `pforge_duplex_recv`. Results echo the input's `_correlation_id` so the
+//! caller can pair them up. At most `DUPLEX_WINDOW` results wait to be
+//! received; sending more blocks until the caller catches up.
+
+use std::collections::VecDeque;
+use std::ffi::c_void;
+use std::os::raw::{c_char, c_int};
+use std::slice;
+use std::sync::{Condvar, Mutex, MutexGuard, PoisonError};
+
+use crate::{
+    catch_panic, dispatch, handler_name_str, pforge_free_result, FfiResult,
+    PFORGE_ERR_INVALID_INPUT, PFORGE_ERR_NULL_POINTER, PFORGE_OK,
+};
+
+/// Input field the caller pairs results with
+const CORRELATION_FIELD: &str = "_correlation_id";
+
+/// How many results a duplex stream buffers, advertised by
+/// `pforge_duplex_window`
+pub const DUPLEX_WINDOW: usize = 64;
+
+/// An open duplex stream, handed to the caller as an opaque pointer
+struct DuplexStream {
+    handler_name: String,
+    state: Mutex<DuplexState>,
+    /// Signalled when a result is queued or received, and when the stream
+    /// is closed or cancelled
+    changed: Condvar,
+}
+
+struct DuplexState {
+    results: VecDeque<FfiResult>,
+    closed: bool,
+    cancelled: bool,
+}
+
+impl DuplexStream {
+    fn lock(&self) -> MutexGuard<'_, DuplexState> {
+        self.state.lock().unwrap_or_else(PoisonError::into_inner)
+    }
+}
+
+/// Runs the handler on one duplex input, echoing its correlation ID
+fn respond(handler_name: &str, input: &[u8]) -> FfiResult {
+    let value: serde_json::Value = match serde_json::from_slice(input) {
+        Ok(value) => value,
+        Err(e) => {
+            return FfiResult::error(PFORGE_ERR_INVALID_INPUT, &format!("Invalid input: {}", e))
+        }
+    };
+
+    let mut response = dispatch(handler_name, input);
+    if let Some(id) = value.get(CORRELATION_FIELD) {
+        response[CORRELATION_FIELD] = id.clone();
+    }
+    FfiResult::json(&response)
+}
+
+/// Open a duplex stream to a handler
+///
+/// Returns null if `handler_name` is null or not valid UTF-8.
+///
+/// # Safety
+/// - `handler_name` must be a valid null-terminated string
+/// - The stream must be freed with `pforge_duplex_free`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_duplex_open(handler_name: *const c_char) -> *mut c_void {
+    let handler_name = match handler_name_str(handler_name) {
+        Ok(name) => name.to_string(),
+        Err(result) => {
+            pforge_free_result(result);
+            return std::ptr::null_mut();
+        }
+    };
+
+    let stream = Box::new(DuplexStream {
+        handler_name,
+        state: Mutex::new(DuplexState {
+            results: VecDeque::new(),
+            closed: false,
+            cancelled: false,
+        }),
+        changed: Condvar::new(),
+    });
+    Box::into_raw(stream) as *mut c_void
+}
+
+/// Send one JSON input on a duplex stream
+///
+/// Returns 0 once the input is accepted, or a non-zero code if a pointer
+/// is null or the stream was already closed or cancelled. Blocks while
+/// `DUPLEX_WINDOW` results are waiting to be received.
+///
+/// # Safety
+/// - `stream` must have been returned by `pforge_duplex_open` and not freed
+/// - `input_json` must be a valid pointer to `input_len` bytes
+#[no_mangle]
+pub unsafe extern "C" fn pforge_duplex_send(
+    stream: *mut c_void,
+    input_json: *const u8,
+    input_len: usize,
+) -> c_int {
+    if stream.is_null() || input_json.is_null() {
+        return PFORGE_ERR_NULL_POINTER;
+    }
+    let stream = &*(stream as *const DuplexStream);
+    {
+        let state = stream.lock();
+        if state.closed || state.cancelled {
+            return PFORGE_ERR_INVALID_INPUT;
+        }
+    }
+
+    let input = slice::from_raw_parts(input_json, input_len);
+    let result = catch_panic(|| respond(&stream.handler_name, input));
+
+    let mut state = stream.lock();
+    while state.results.len() >= DUPLEX_WINDOW && !state.cancelled {
+        state = stream
+            .changed
+            .wait(state)
+            .unwrap_or_else(PoisonError::into_inner);
+    }
+    if state.cancelled {
+        pforge_free_result(result);
+        return PFORGE_OK;
+    }
+    state.results.push_back(result);
+    stream.changed.notify_all();
+    PFORGE_OK
+}
+
+/// Receive the next result from a duplex stream, blocking until one is
+/// ready
+///
+/// Returns code 0 with null data at the end of the stream: once it is
+/// closed and every result has been received, or once it is cancelled.
+///
+/// # Safety
+/// - `stream` must have been returned by `pforge_duplex_open` and not freed
+/// - Caller must free the result with `pforge_free_result`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_duplex_recv(stream: *mut c_void) -> FfiResult {
+    if stream.is_null() {
+        return FfiResult::error(PFORGE_ERR_NULL_POINTER, "Null pointer provided");
+    }
+    let stream = &*(stream as *const DuplexStream);
+
+    let mut state = stream.lock();
+    loop {
+        if state.cancelled {
+            return FfiResult::empty(PFORGE_OK);
+        }
+        if let Some(result) = state.results.pop_front() {
+            stream.changed.notify_all();
+            return result;
+        }
+        if state.closed {
+            return FfiResult::empty(PFORGE_OK);
+        }
+        state = stream
+            .changed
+            .wait(state)
+            .unwrap_or_else(PoisonError::into_inner);
+    }
+}
+
+/// Close a duplex stream for input: no more inputs will be sent
+///
+/// # Safety
+/// - `stream` must have been returned by `pforge_duplex_open` and not freed
+#[no_mangle]
+pub unsafe extern "C" fn pforge_duplex_close(stream: *mut c_void) {
+    if stream.is_null() {
+        return;
+    }
+    let stream = &*(stream as *const DuplexStream);
+    stream.lock().closed = true;
+    stream.changed.notify_all();
+}
+
+/// Abort a duplex stream, discarding queued results and unblocking
+/// `pforge_duplex_recv`
+///
+/// # Safety
+/// - `stream` must have been returned by `pforge_duplex_open` and not freed
+#[no_mangle]
+pub unsafe extern "C" fn pforge_duplex_cancel(stream: *mut c_void) {
+    if stream.is_null() {
+        return;
+    }
+    let stream = &*(stream as *const DuplexStream);
+    stream.lock().cancelled = true;
+    stream.changed.notify_all();
+}
+
+/// Get the number of results a duplex stream buffers before
+/// `pforge_duplex_send` blocks
+///
+/// # Safety
+/// - `stream` must have been returned by `pforge_duplex_open` and not freed
+#[no_mangle]
+pub unsafe extern "C" fn pforge_duplex_window(stream: *mut c_void) -> usize {
+    if stream.is_null() {
+        return 0;
+    }
+    DUPLEX_WINDOW
+}
+
+/// Free a duplex stream and any results not received
+///
+/// # Safety
+/// - `stream` must have been returned by `pforge_duplex_open`
+/// - No other duplex call on `stream` may be running or follow
+#[no_mangle]
+pub unsafe extern "C" fn pforge_duplex_free(stream: *mut c_void) {
+    if stream.is_null() {
+        return;
+    }
+    let stream = Box::from_raw(stream as *mut DuplexStream);
+    let mut state = stream.lock();
+    while let Some(result) = state.results.pop_front() {
+        pforge_free_result(result);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::ffi::CString;
+    use std::thread;
+
+    unsafe fn recv_json(stream: *mut c_void) -> Option<serde_json::Value> {
+        let result = pforge_duplex_recv(stream);
+        assert_eq!(result.code, 0);
+        if result.data.is_null() {
+            return None;
+        }
+        let value = serde_json::from_slice(slice::from_raw_parts(result.data, result.data_len));
+        pforge_free_result(result);
+        Some(value.unwrap())
+    }
+
+    #[test]
+    fn test_duplex_round_trip() {
+        unsafe {
+            let name = CString::new("echo").unwrap();
+            let stream = pforge_duplex_open(name.as_ptr());
+            assert!(!stream.is_null());
+
+            for id in 1..=3 {
+                let input = format!(r#"{{"_correlation_id":{}}}"#, id);
+                assert_eq!(pforge_duplex_send(stream, input.as_ptr(), input.len()), 0);
+            }
+            pforge_duplex_close(stream);
+            assert_ne!(pforge_duplex_send(stream, b"{}".as_ptr(), 2), 0);
+
+            for id in 1..=3 {
+                let response = recv_json(stream).unwrap();
+                assert_eq!(response["handler"], "echo");
+                assert_eq!(response[CORRELATION_FIELD], id);
+            }
+            assert!(recv_json(stream).is_none());
+            pforge_duplex_free(stream);
+        }
+    }
+
+    #[test]
+    fn test_duplex_window() {
+        unsafe {
+            let name = CString::new("echo").unwrap();
+            let stream = pforge_duplex_open(name.as_ptr());
+            assert_eq!(pforge_duplex_window(stream), DUPLEX_WINDOW);
+
+            for _ in 0..DUPLEX_WINDOW {
+                assert_eq!(pforge_duplex_send(stream, b"{}".as_ptr(), 2), 0);
+            }
+            // One more send waits for a result to be received
+            let addr = stream as usize;
+            let sender =
+                thread::spawn(move || pforge_duplex_send(addr as *mut c_void, b"{}".as_ptr(), 2));
+            while (*(stream as *const DuplexStream)).lock().results.len() < DUPLEX_WINDOW {
+                thread::yield_now();
+            }
+            assert!(!sender.is_finished());
+            assert!(recv_json(stream).is_some());
+            assert_eq!(sender.join().unwrap(), 0);
+
+            pforge_duplex_cancel(stream);
+            pforge_duplex_free(stream);
+        }
+    }
+
+    #[test]
+    fn test_duplex_cancel_unblocks_recv() {
+        unsafe {
+            let name = CString::new("echo").unwrap();
+            let stream = pforge_duplex_open(name.as_ptr());
+            let addr = stream as usize;
+
+            let receiver = thread::spawn(move || recv_json(addr as *mut c_void));
+            pforge_duplex_cancel(stream);
+            assert!(receiver.join().unwrap().is_none());
+            pforge_duplex_free(stream);
+        }
+    }
+}
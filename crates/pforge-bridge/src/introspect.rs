@@ -0,0 +1,200 @@
+//! Handler introspection: which handlers the library serves
+
+use std::os::raw::c_char;
+
+use serde::Serialize;
+
+use crate::{catch_panic, handler_name_str, FfiResult, PFORGE_ERR_SERIALIZATION, PFORGE_OK};
+
+/// A handler served by the library
+#[derive(Serialize)]
+pub(crate) struct HandlerEntry {
+    pub name: &'static str,
+    pub description: &'static str,
+    /// JSON Schemas of the handler's input and output, if it declares them
+    #[serde(skip)]
+    pub input_schema: Option<&'static str>,
+    #[serde(skip)]
+    pub output_schema: Option<&'static str>,
+}
+
+/// A handler's schemas as `{"input": ..., "output": ...}`
+#[derive(Serialize)]
+pub(crate) struct SchemaPair {
+    pub input: Option<serde_json::Value>,
+    pub output: Option<serde_json::Value>,
+}
+
+impl HandlerEntry {
+    /// Parses the declared schemas; a handler declaring neither has none
+    pub(crate) fn schemas(&self) -> Result<Option<SchemaPair>, serde_json::Error> {
+        if self.input_schema.is_none() && self.output_schema.is_none() {
+            return Ok(None);
+        }
+        let parse = |schema: Option<&str>| schema.map(serde_json::from_str).transpose();
+        Ok(Some(SchemaPair {
+            input: parse(self.input_schema)?,
+            output: parse(self.output_schema)?,
+        }))
+    }
+}
+
+/// The handlers served by the library
+///
+/// TODO: List the handler registry once `dispatch` goes through it. Until
+/// then no handler is registered.
+pub(crate) static HANDLERS: &[HandlerEntry] = &[];
+
+/// List registered handlers as a JSON array of `{"name", "description"}`
+///
+/// # Safety
+/// - Caller must free the result with `pforge_free_result`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_list_handlers() -> FfiResult {
+    catch_panic(|| FfiResult::json(HANDLERS))
+}
+
+/// Get a handler's schemas as `{"input": <JSON Schema>, "output": <JSON
+/// Schema>}`
+///
+/// Returns code 0 with null data for a handler that declares no schemas,
+/// including one that is not registered.
+///
+/// # Safety
+/// - `handler_name` must be a valid null-terminated string
+/// - Caller must free the result with `pforge_free_result`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_handler_schema(handler_name: *const c_char) -> FfiResult {
+    catch_panic(|| {
+        let name = match handler_name_str(handler_name) {
+            Ok(name) => name,
+            Err(result) => return result,
+        };
+        let handler = match HANDLERS.iter().find(|handler| handler.name == name) {
+            Some(handler) => handler,
+            None => return FfiResult::empty(PFORGE_OK),
+        };
+        match handler.schemas() {
+            Ok(Some(schemas)) => FfiResult::json(&schemas),
+            Ok(None) => FfiResult::empty(PFORGE_OK),
+            Err(e) => FfiResult::error(
+                PFORGE_ERR_SERIALIZATION,
+                &format!("Invalid schema for handler {}: {}", name, e),
+            ),
+        }
+    })
+}
+
+/// One handler with its schemas, as listed by `pforge_catalog`
+#[derive(Serialize)]
+struct CatalogEntry<'a> {
+    #[serde(flatten)]
+    handler: &'a HandlerEntry,
+    #[serde(flatten)]
+    schemas: Option<SchemaPair>,
+}
+
+/// List every handler with its schemas, as a JSON array of `{"name",
+/// "description", "input", "output"}`
+///
+/// # Safety
+/// - Caller must free the result with `pforge_free_result`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_catalog() -> FfiResult {
+    catch_panic(|| {
+        let mut catalog = Vec::with_capacity(HANDLERS.len());
+        for handler in HANDLERS {
+            match handler.schemas() {
+                Ok(schemas) => catalog.push(CatalogEntry { handler, schemas }),
+                Err(e) => {
+                    return FfiResult::error(
+                        PFORGE_ERR_SERIALIZATION,
+                        &format!("Invalid schema for handler {}: {}", handler.name, e),
+                    )
+                }
+            }
+        }
+        FfiResult::json(&catalog)
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::pforge_free_result;
+    use std::slice;
+
+    #[test]
+    fn test_list_handlers() {
+        unsafe {
+            let result = pforge_list_handlers();
+            assert_eq!(result.code, 0);
+            let data = slice::from_raw_parts(result.data, result.data_len);
+            let handlers: Vec<serde_json::Value> = serde_json::from_slice(data).unwrap();
+            assert_eq!(handlers.len(), HANDLERS.len());
+            pforge_free_result(result);
+        }
+    }
+
+    #[test]
+    fn test_handler_schema() {
+        unsafe {
+            let name = std::ffi::CString::new("unregistered").unwrap();
+            let result = pforge_handler_schema(name.as_ptr());
+            assert_eq!(result.code, 0);
+            assert!(result.data.is_null());
+            pforge_free_result(result);
+
+            let result = pforge_handler_schema(std::ptr::null());
+            assert_eq!(result.code, crate::PFORGE_ERR_NULL_POINTER);
+            pforge_free_result(result);
+        }
+    }
+
+    #[test]
+    fn test_schemas() {
+        let handler = HandlerEntry {
+            name: "add",
+            description: "Add two numbers",
+            input_schema: Some(r#"{"type":"object"}"#),
+            output_schema: None,
+        };
+        let schemas = handler.schemas().unwrap().unwrap();
+        assert_eq!(
+            serde_json::to_value(schemas).unwrap(),
+            serde_json::json!({"input": {"type": "object"}, "output": null})
+        );
+
+        let entry = CatalogEntry {
+            handler: &handler,
+            schemas: handler.schemas().unwrap(),
+        };
+        assert_eq!(
+            serde_json::to_value(entry).unwrap(),
+            serde_json::json!({
+                "name": "add",
+                "description": "Add two numbers",
+                "input": {"type": "object"},
+                "output": null
+            })
+        );
+
+        let handler = HandlerEntry {
+            input_schema: None,
+            ..handler
+        };
+        assert!(handler.schemas().unwrap().is_none());
+    }
+
+    #[test]
+    fn test_catalog() {
+        unsafe {
+            let result = pforge_catalog();
+            assert_eq!(result.code, 0);
+            let data = slice::from_raw_parts(result.data, result.data_len);
+            let catalog: Vec<serde_json::Value> = serde_json::from_slice(data).unwrap();
+            assert_eq!(catalog.len(), HANDLERS.len());
+            pforge_free_result(result);
+        }
+    }
+}
@@ -5,8 +5,49 @@
 
 use std::ffi::{CStr, CString};
 use std::os::raw::{c_char, c_int};
+use std::panic::{self, AssertUnwindSafe};
 use std::slice;
 
+mod codec;
+mod duplex;
+mod introspect;
+mod multipart;
+mod stream;
+mod upload;
+
+pub use codec::{
+    pforge_codecs, pforge_handler_codecs, pforge_select_codec, pforge_select_handler_codec, CODECS,
+};
+pub use duplex::{
+    pforge_duplex_cancel, pforge_duplex_close, pforge_duplex_free, pforge_duplex_open,
+    pforge_duplex_recv, pforge_duplex_send, pforge_duplex_window, DUPLEX_WINDOW,
+};
+pub use introspect::{pforge_catalog, pforge_handler_schema, pforge_list_handlers};
+pub use multipart::pforge_execute_handler_multipart;
+pub use stream::{
+    pforge_stream_cancel, pforge_stream_free, pforge_stream_next, pforge_stream_open,
+    KEEPALIVE_INTERVAL,
+};
+pub use upload::{
+    pforge_upload_abort, pforge_upload_finish, pforge_upload_open, pforge_upload_write,
+};
+
+/// Success
+pub const PFORGE_OK: c_int = 0;
+/// Returned with no data by `pforge_stream_next` while a handler is still
+/// working but has nothing to send yet
+pub const PFORGE_KEEPALIVE: c_int = 1;
+/// A required pointer argument was null
+pub const PFORGE_ERR_NULL_POINTER: c_int = -1;
+/// The handler name was not valid UTF-8
+pub const PFORGE_ERR_INVALID_NAME: c_int = -2;
+/// The result could not be serialized
+pub const PFORGE_ERR_SERIALIZATION: c_int = -3;
+/// The input was malformed, such as a truncated multipart frame
+pub const PFORGE_ERR_INVALID_INPUT: c_int = -4;
+/// The handler panicked; the panic was caught at the FFI boundary
+pub const PFORGE_ERR_PANIC: c_int = -5;
+
 /// Opaque handle to a handler context
 #[repr(C)]
 pub struct HandlerContext {
@@ -38,65 +79,38 @@ pub unsafe extern "C" fn pforge_execute_handler(
     handler_name: *const c_char,
     input_json: *const u8,
     input_len: usize,
+) -> FfiResult {
+    catch_panic(|| execute_handler(handler_name, input_json, input_len))
+}
+
+unsafe fn execute_handler(
+    handler_name: *const c_char,
+    input_json: *const u8,
+    input_len: usize,
 ) -> FfiResult {
     // Validate inputs
-    if handler_name.is_null() || input_json.is_null() {
-        return FfiResult {
-            code: -1,
-            data: std::ptr::null_mut(),
-            data_len: 0,
-            error: create_error_string("Null pointer provided"),
-        };
-    }
-
-    // Convert handler name
-    let name = match CStr::from_ptr(handler_name).to_str() {
-        Ok(s) => s,
-        Err(_) => {
-            return FfiResult {
-                code: -2,
-                data: std::ptr::null_mut(),
-                data_len: 0,
-                error: create_error_string("Invalid UTF-8 in handler name"),
-            }
-        }
+    if input_json.is_null() {
+        return FfiResult::error(PFORGE_ERR_NULL_POINTER, "Null pointer provided");
+    }
+    let name = match handler_name_str(handler_name) {
+        Ok(name) => name,
+        Err(result) => return result,
     };
 
-    // Get input bytes
-    let _input = slice::from_raw_parts(input_json, input_len);
+    let input = slice::from_raw_parts(input_json, input_len);
+    FfiResult::json(&dispatch(name, input))
+}
 
-    // TODO: Actually dispatch to handler registry
-    // For now, return a simple echo response
-    let response = serde_json::json!({
+/// Run a handler on its input
+///
+/// TODO: Actually dispatch to handler registry. For now every entry point
+/// returns a simple echo response built here.
+pub(crate) fn dispatch(name: &str, input: &[u8]) -> serde_json::Value {
+    serde_json::json!({
         "handler": name,
-        "input_size": input_len,
+        "input_size": input.len(),
         "status": "ok"
-    });
-
-    match serde_json::to_vec(&response) {
-        Ok(data) => {
-            let mut boxed = data.into_boxed_slice();
-            let data_ptr = boxed.as_mut_ptr();
-            let data_len = boxed.len();
-            // SAFETY: Transfer ownership to C caller. Memory will be freed via pforge_free_result.
-            // This is the correct pattern for FFI memory management.
-            #[allow(clippy::mem_forget)]
-            std::mem::forget(boxed);
-
-            FfiResult {
-                code: 0,
-                data: data_ptr,
-                data_len,
-                error: std::ptr::null(),
-            }
-        }
-        Err(e) => FfiResult {
-            code: -3,
-            data: std::ptr::null_mut(),
-            data_len: 0,
-            error: create_error_string(&format!("Serialization error: {}", e)),
-        },
-    }
+    })
 }
 
 /// Free result data allocated by pforge
@@ -114,6 +128,23 @@ pub unsafe extern "C" fn pforge_free_result(result: FfiResult) {
     }
 }
 
+/// Free several results in one call, such as those of a batch
+///
+/// # Safety
+/// - `results` must point to `count` results, each returned by pforge and
+///   not yet freed
+/// - The array itself is not freed; it stays owned by the caller
+#[no_mangle]
+pub unsafe extern "C" fn pforge_free_results(results: *mut FfiResult, count: usize) {
+    if results.is_null() {
+        return;
+    }
+    for result in slice::from_raw_parts_mut(results, count) {
+        let result = std::mem::replace(result, FfiResult::empty(PFORGE_OK));
+        pforge_free_result(result);
+    }
+}
+
 /// Get the pforge version
 ///
 /// # Safety
@@ -124,8 +155,130 @@ pub unsafe extern "C" fn pforge_version() -> *const c_char {
     VERSION.as_ptr() as *const c_char
 }
 
+/// FFI ABI version, bumped on any change that breaks binaries built against
+/// the previous version, independently of the crate version
+pub const PFORGE_ABI_VERSION: u32 = 1;
+
+/// Get the FFI ABI version, checked by bridges at load time
+#[no_mangle]
+pub extern "C" fn pforge_abi_version() -> u32 {
+    PFORGE_ABI_VERSION
+}
+
+/// Limits the library enforces on calls; 0 means no limit
+#[derive(serde::Serialize)]
+pub struct Limits {
+    pub max_input_bytes: u64,
+    pub max_result_bytes: u64,
+    pub max_batch_size: u64,
+}
+
+/// The limits of this library, which currently enforces none
+pub const LIMITS: Limits = Limits {
+    max_input_bytes: 0,
+    max_result_bytes: 0,
+    max_batch_size: 0,
+};
+
+/// Get the limits the library enforces, as `{"max_input_bytes": N,
+/// "max_result_bytes": N, "max_batch_size": N}` with 0 for no limit
+///
+/// # Safety
+/// - Caller must free the result with `pforge_free_result`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_limits() -> FfiResult {
+    catch_panic(|| FfiResult::json(&LIMITS))
+}
+
 // Helper functions
 
+impl FfiResult {
+    /// A successful result owning `data`, or an empty one for no data
+    pub(crate) fn ok(data: Vec<u8>) -> Self {
+        if data.is_empty() {
+            return Self::empty(PFORGE_OK);
+        }
+        let mut boxed = data.into_boxed_slice();
+        let data_ptr = boxed.as_mut_ptr();
+        let data_len = boxed.len();
+        // SAFETY: Transfer ownership to C caller. Memory will be freed via pforge_free_result.
+        // This is the correct pattern for FFI memory management.
+        #[allow(clippy::mem_forget)]
+        std::mem::forget(boxed);
+
+        FfiResult {
+            code: PFORGE_OK,
+            data: data_ptr,
+            data_len,
+            error: std::ptr::null(),
+        }
+    }
+
+    /// A successful result holding `value` serialized as JSON
+    pub(crate) fn json<T: serde::Serialize + ?Sized>(value: &T) -> Self {
+        match serde_json::to_vec(value) {
+            Ok(data) => Self::ok(data),
+            Err(e) => Self::error(
+                PFORGE_ERR_SERIALIZATION,
+                &format!("Serialization error: {}", e),
+            ),
+        }
+    }
+
+    /// A failed result carrying `msg`
+    pub(crate) fn error(code: c_int, msg: &str) -> Self {
+        FfiResult {
+            code,
+            data: std::ptr::null_mut(),
+            data_len: 0,
+            error: create_error_string(msg),
+        }
+    }
+
+    /// A result with no data and no message, such as an end of stream
+    pub(crate) fn empty(code: c_int) -> Self {
+        FfiResult {
+            code,
+            data: std::ptr::null_mut(),
+            data_len: 0,
+            error: std::ptr::null(),
+        }
+    }
+}
+
+/// Converts a handler name argument, failing with the result to return
+/// when it is null or not UTF-8
+///
+/// # Safety
+/// - `handler_name` must be null or a valid null-terminated string
+pub(crate) unsafe fn handler_name_str<'a>(
+    handler_name: *const c_char,
+) -> Result<&'a str, FfiResult> {
+    if handler_name.is_null() {
+        return Err(FfiResult::error(
+            PFORGE_ERR_NULL_POINTER,
+            "Null pointer provided",
+        ));
+    }
+    CStr::from_ptr(handler_name)
+        .to_str()
+        .map_err(|_| FfiResult::error(PFORGE_ERR_INVALID_NAME, "Invalid UTF-8 in handler name"))
+}
+
+/// Runs an entry point's body, turning a panic into a `PFORGE_ERR_PANIC`
+/// result instead of unwinding across the FFI boundary, which is undefined
+/// behavior
+fn catch_panic(f: impl FnOnce() -> FfiResult) -> FfiResult {
+    panic::catch_unwind(AssertUnwindSafe(f)).unwrap_or_else(|payload| {
+        let message = payload
+            .downcast_ref::<&str>()
+            .map(|s| s.to_string())
+            .or_else(|| payload.downcast_ref::<String>().cloned())
+            .unwrap_or_else(|| "unknown panic".to_string());
+        FfiResult::error(PFORGE_ERR_PANIC, &format!("Handler panicked: {}", message))
+    })
+}
+
 fn create_error_string(msg: &str) -> *const c_char {
     match CString::new(msg) {
         Ok(s) => s.into_raw() as *const c_char,
@@ -148,6 +301,11 @@ mod tests {
         }
     }
 
+    #[test]
+    fn test_abi_version() {
+        assert_eq!(pforge_abi_version(), PFORGE_ABI_VERSION);
+    }
+
     #[test]
     fn test_execute_handler_null_safety() {
         unsafe {
@@ -179,4 +337,51 @@ mod tests {
             pforge_free_result(result);
         }
     }
+
+    #[test]
+    fn test_free_results() {
+        unsafe {
+            let handler_name = CString::new("test_handler").unwrap();
+            let mut results: Vec<FfiResult> = (0..3)
+                .map(|_| pforge_execute_handler(handler_name.as_ptr(), b"{}".as_ptr(), 2))
+                .collect();
+            results.push(pforge_execute_handler(
+                std::ptr::null(),
+                std::ptr::null(),
+                0,
+            ));
+
+            pforge_free_results(results.as_mut_ptr(), results.len());
+            assert!(results
+                .iter()
+                .all(|r| r.data.is_null() && r.error.is_null()));
+            pforge_free_results(std::ptr::null_mut(), 0);
+        }
+    }
+
+    #[test]
+    fn test_limits() {
+        unsafe {
+            let result = pforge_limits();
+            assert_eq!(result.code, 0);
+            let data = slice::from_raw_parts(result.data, result.data_len);
+            let limits: serde_json::Value = serde_json::from_slice(data).unwrap();
+            assert_eq!(
+                limits,
+                serde_json::json!({"max_input_bytes": 0, "max_result_bytes": 0, "max_batch_size": 0})
+            );
+            pforge_free_result(result);
+        }
+    }
+
+    #[test]
+    fn test_catch_panic() {
+        unsafe {
+            let result = catch_panic(|| panic!("boom"));
+            assert_eq!(result.code, PFORGE_ERR_PANIC);
+            let message = CStr::from_ptr(result.error).to_str().unwrap();
+            assert_eq!(message, "Handler panicked: boom");
+            pforge_free_result(result);
+        }
+    }
 }
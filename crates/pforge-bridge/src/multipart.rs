@@ -0,0 +1,192 @@
+//! Multipart calls: JSON metadata plus raw binary attachments, without
+//! base64 overhead
+
+use std::os::raw::c_char;
+use std::slice;
+
+use serde::{Deserialize, Serialize};
+
+use crate::{
+    catch_panic, dispatch, handler_name_str, FfiResult, PFORGE_ERR_INVALID_INPUT,
+    PFORGE_ERR_NULL_POINTER,
+};
+
+/// JSON header at the start of a multipart frame
+#[derive(Deserialize)]
+struct Header {
+    #[serde(default)]
+    meta: serde_json::Value,
+    #[serde(default)]
+    attachments: Vec<AttachmentRef>,
+}
+
+/// One attachment listed in the header
+#[derive(Deserialize, Serialize)]
+struct AttachmentRef {
+    key: String,
+    size: usize,
+}
+
+/// A decoded multipart frame, borrowing the attachment bytes from it
+pub(crate) struct Multipart<'a> {
+    pub meta: serde_json::Value,
+    pub attachments: Vec<(String, &'a [u8])>,
+}
+
+/// Splits a multipart frame: a 4-byte big-endian header length, the JSON
+/// header `{"meta": ..., "attachments": [{"key", "size"}]}`, then the
+/// attachment bytes back to back in header order
+pub(crate) fn parse_frame(frame: &[u8]) -> Result<Multipart<'_>, String> {
+    if frame.len() < 4 {
+        return Err("Multipart frame shorter than its header length".to_string());
+    }
+    let header_len = u32::from_be_bytes([frame[0], frame[1], frame[2], frame[3]]) as usize;
+    let rest = &frame[4..];
+    if header_len > rest.len() {
+        return Err(format!(
+            "Multipart header length {} exceeds frame of {} bytes",
+            header_len,
+            frame.len()
+        ));
+    }
+
+    let header: Header = serde_json::from_slice(&rest[..header_len])
+        .map_err(|e| format!("Invalid multipart header: {}", e))?;
+
+    let mut data = &rest[header_len..];
+    let mut attachments = Vec::with_capacity(header.attachments.len());
+    for attachment in header.attachments {
+        if attachment.size > data.len() {
+            return Err(format!(
+                "Attachment {:?} of {} bytes overruns the frame",
+                attachment.key, attachment.size
+            ));
+        }
+        let (bytes, tail) = data.split_at(attachment.size);
+        attachments.push((attachment.key, bytes));
+        data = tail;
+    }
+    if !data.is_empty() {
+        return Err(format!("{} bytes after the last attachment", data.len()));
+    }
+
+    Ok(Multipart {
+        meta: header.meta,
+        attachments,
+    })
+}
+
+/// Execute a handler with JSON metadata plus raw binary attachments
+///
+/// # Safety
+/// - `handler_name` must be a valid null-terminated string
+/// - `frame` must be a valid pointer to `frame_len` bytes
+/// - Caller must free the result with `pforge_free_result`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_execute_handler_multipart(
+    handler_name: *const c_char,
+    frame: *const u8,
+    frame_len: usize,
+) -> FfiResult {
+    catch_panic(|| {
+        if frame.is_null() {
+            return FfiResult::error(PFORGE_ERR_NULL_POINTER, "Null pointer provided");
+        }
+        let name = match handler_name_str(handler_name) {
+            Ok(name) => name,
+            Err(result) => return result,
+        };
+
+        let frame = slice::from_raw_parts(frame, frame_len);
+        let multipart = match parse_frame(frame) {
+            Ok(multipart) => multipart,
+            Err(msg) => return FfiResult::error(PFORGE_ERR_INVALID_INPUT, &msg),
+        };
+
+        // The metadata is the handler's input
+        let meta = serde_json::to_vec(&multipart.meta).unwrap_or_default();
+        let mut response = dispatch(name, &meta);
+        response["attachments"] = multipart
+            .attachments
+            .iter()
+            .map(|(key, bytes)| {
+                serde_json::json!(AttachmentRef {
+                    key: key.clone(),
+                    size: bytes.len(),
+                })
+            })
+            .collect();
+        FfiResult::json(&response)
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::pforge_free_result;
+    use std::ffi::{CStr, CString};
+
+    fn frame(header: &str, attachments: &[&[u8]]) -> Vec<u8> {
+        let mut frame = (header.len() as u32).to_be_bytes().to_vec();
+        frame.extend_from_slice(header.as_bytes());
+        for attachment in attachments {
+            frame.extend_from_slice(attachment);
+        }
+        frame
+    }
+
+    #[test]
+    fn test_parse_frame() {
+        let frame = frame(
+            r#"{"meta":{"n":1},"attachments":[{"key":"a","size":2},{"key":"b","size":3}]}"#,
+            &[b"xy", b"abc"],
+        );
+        let multipart = parse_frame(&frame).unwrap();
+        assert_eq!(multipart.meta["n"], 1);
+        assert_eq!(
+            multipart.attachments,
+            vec![
+                ("a".to_string(), &b"xy"[..]),
+                ("b".to_string(), &b"abc"[..])
+            ]
+        );
+    }
+
+    #[test]
+    fn test_parse_frame_rejects_malformed() {
+        let header = r#"{"meta":{},"attachments":[{"key":"a","size":4}]}"#;
+        for bad in [
+            vec![0, 0],
+            vec![0, 0, 0, 9, b'{'],
+            frame("not json", &[]),
+            frame(header, &[b"abc"]),
+            frame(header, &[b"abcde"]),
+        ] {
+            assert!(parse_frame(&bad).is_err(), "accepted {:?}", bad);
+        }
+    }
+
+    #[test]
+    fn test_execute_handler_multipart() {
+        unsafe {
+            let name = CString::new("upload").unwrap();
+            let frame = frame(
+                r#"{"meta":{},"attachments":[{"key":"blob","size":3}]}"#,
+                &[b"\x00\x01\x02"],
+            );
+            let result =
+                pforge_execute_handler_multipart(name.as_ptr(), frame.as_ptr(), frame.len());
+            assert_eq!(result.code, 0);
+            let data = slice::from_raw_parts(result.data, result.data_len);
+            let response: serde_json::Value = serde_json::from_slice(data).unwrap();
+            assert_eq!(response["attachments"][0]["key"], "blob");
+            assert_eq!(response["attachments"][0]["size"], 3);
+            pforge_free_result(result);
+
+            let result = pforge_execute_handler_multipart(name.as_ptr(), frame.as_ptr(), 2);
+            assert_eq!(result.code, PFORGE_ERR_INVALID_INPUT);
+            assert!(!CStr::from_ptr(result.error).to_bytes().is_empty());
+            pforge_free_result(result);
+        }
+    }
+}
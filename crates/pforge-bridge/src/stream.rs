@@ -0,0 +1,305 @@
+//! Streaming results: one input in, a sequence of chunks out
+//!
+//! The handler runs on its own thread, queueing chunks for
+//! `pforge_stream_next`. The queue is bounded, so a handler producing
+//! faster than the caller reads waits for it. While a handler is quiet,
+//! `pforge_stream_next` returns a keepalive every `KEEPALIVE_INTERVAL`, so
+//! the caller can tell a slow handler from a stuck one.
+
+use std::collections::VecDeque;
+use std::ffi::c_void;
+use std::os::raw::{c_char, c_int};
+use std::panic::{self, AssertUnwindSafe};
+use std::slice;
+use std::sync::{Arc, Condvar, Mutex, MutexGuard, PoisonError};
+use std::thread;
+use std::time::Duration;
+
+use crate::{
+    dispatch, handler_name_str, pforge_free_result, FfiResult, PFORGE_ERR_NULL_POINTER,
+    PFORGE_ERR_PANIC, PFORGE_ERR_SERIALIZATION, PFORGE_KEEPALIVE, PFORGE_OK,
+};
+
+/// How many chunks a stream buffers before its handler waits for the
+/// caller
+const STREAM_BUFFER: usize = 16;
+
+/// How long `pforge_stream_next` waits for a chunk before returning a
+/// keepalive instead
+pub const KEEPALIVE_INTERVAL: Duration = Duration::from_secs(5);
+
+/// A chunk of output, or the error ending the stream
+type Chunk = Result<Vec<u8>, (c_int, String)>;
+
+/// An open result stream, handed to the caller as an opaque pointer
+struct ResultStream {
+    shared: Arc<Shared>,
+}
+
+/// State shared between a stream's handler thread and its caller
+struct Shared {
+    state: Mutex<StreamState>,
+    /// Signalled when a chunk is queued or taken, and when the stream ends
+    /// or is cancelled
+    changed: Condvar,
+}
+
+struct StreamState {
+    chunks: VecDeque<Chunk>,
+    /// The handler has returned; no more chunks will be queued
+    done: bool,
+    cancelled: bool,
+}
+
+impl Shared {
+    fn lock(&self) -> MutexGuard<'_, StreamState> {
+        self.state.lock().unwrap_or_else(PoisonError::into_inner)
+    }
+
+    /// Queues a chunk, waiting while the buffer is full. Returns false if
+    /// the stream was cancelled, in which case the handler should stop.
+    fn emit(&self, chunk: Chunk) -> bool {
+        let mut state = self.lock();
+        while state.chunks.len() >= STREAM_BUFFER && !state.cancelled {
+            state = self
+                .changed
+                .wait(state)
+                .unwrap_or_else(PoisonError::into_inner);
+        }
+        if state.cancelled {
+            return false;
+        }
+        state.chunks.push_back(chunk);
+        self.changed.notify_all();
+        true
+    }
+
+    fn cancel(&self) {
+        self.lock().cancelled = true;
+        self.changed.notify_all();
+    }
+}
+
+/// Runs a streaming handler, queueing its output through `emit`
+///
+/// TODO: Dispatch to streaming handlers in the handler registry. For now
+/// the stream holds the `dispatch` response as a single NDJSON record.
+fn run_stream(handler_name: &str, input: &[u8], shared: &Shared) {
+    let chunk = match serde_json::to_vec(&dispatch(handler_name, input)) {
+        Ok(mut record) => {
+            record.push(b'\n');
+            Ok(record)
+        }
+        Err(e) => Err((
+            PFORGE_ERR_SERIALIZATION,
+            format!("Serialization error: {}", e),
+        )),
+    };
+    shared.emit(chunk);
+}
+
+/// Open a result stream, starting the handler
+///
+/// Returns null if a pointer is null or `handler_name` is not valid UTF-8.
+///
+/// # Safety
+/// - `handler_name` must be a valid null-terminated string
+/// - `input_json` must be a valid pointer to `input_len` bytes
+/// - The stream must be freed with `pforge_stream_free`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_stream_open(
+    handler_name: *const c_char,
+    input_json: *const u8,
+    input_len: usize,
+) -> *mut c_void {
+    if input_json.is_null() {
+        return std::ptr::null_mut();
+    }
+    let handler_name = match handler_name_str(handler_name) {
+        Ok(name) => name.to_string(),
+        Err(result) => {
+            pforge_free_result(result);
+            return std::ptr::null_mut();
+        }
+    };
+    let input = slice::from_raw_parts(input_json, input_len).to_vec();
+
+    let shared = Arc::new(Shared {
+        state: Mutex::new(StreamState {
+            chunks: VecDeque::new(),
+            done: false,
+            cancelled: false,
+        }),
+        changed: Condvar::new(),
+    });
+    let producer = Arc::clone(&shared);
+    thread::spawn(move || {
+        let outcome = panic::catch_unwind(AssertUnwindSafe(|| {
+            run_stream(&handler_name, &input, &producer)
+        }));
+        if outcome.is_err() {
+            producer.emit(Err((PFORGE_ERR_PANIC, "Handler panicked".to_string())));
+        }
+        producer.lock().done = true;
+        producer.changed.notify_all();
+    });
+
+    Box::into_raw(Box::new(ResultStream { shared })) as *mut c_void
+}
+
+/// Get the next chunk of a result stream, blocking until one is ready
+///
+/// Returns code 0 with null data at the end of the stream, and once it is
+/// cancelled, and `PFORGE_KEEPALIVE` with null data when the handler has
+/// sent nothing for `KEEPALIVE_INTERVAL`. A failed chunk ends the stream.
+///
+/// # Safety
+/// - `stream` must have been returned by `pforge_stream_open` and not freed
+/// - Caller must free the result with `pforge_free_result`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_stream_next(stream: *mut c_void) -> FfiResult {
+    if stream.is_null() {
+        return FfiResult::error(PFORGE_ERR_NULL_POINTER, "Null pointer provided");
+    }
+    next_chunk(
+        &(*(stream as *const ResultStream)).shared,
+        KEEPALIVE_INTERVAL,
+    )
+}
+
+/// Waits for the next chunk of a stream, or a keepalive after `keepalive`
+fn next_chunk(shared: &Shared, keepalive: Duration) -> FfiResult {
+    let mut state = shared.lock();
+    loop {
+        if state.cancelled {
+            return FfiResult::empty(PFORGE_OK);
+        }
+        if let Some(chunk) = state.chunks.pop_front() {
+            shared.changed.notify_all();
+            return match chunk {
+                Ok(data) => FfiResult::ok(data),
+                Err((code, msg)) => {
+                    // Nothing follows an error
+                    state.cancelled = true;
+                    FfiResult::error(code, &msg)
+                }
+            };
+        }
+        if state.done {
+            return FfiResult::empty(PFORGE_OK);
+        }
+        let (next, timeout) = shared
+            .changed
+            .wait_timeout(state, keepalive)
+            .unwrap_or_else(PoisonError::into_inner);
+        state = next;
+        if timeout.timed_out() && state.chunks.is_empty() && !state.done && !state.cancelled {
+            return FfiResult::empty(PFORGE_KEEPALIVE);
+        }
+    }
+}
+
+/// Abort a result stream, stopping its handler and unblocking
+/// `pforge_stream_next`
+///
+/// # Safety
+/// - `stream` must have been returned by `pforge_stream_open` and not freed
+#[no_mangle]
+pub unsafe extern "C" fn pforge_stream_cancel(stream: *mut c_void) {
+    if stream.is_null() {
+        return;
+    }
+    (*(stream as *const ResultStream)).shared.cancel();
+}
+
+/// Free a result stream. A handler still running is cancelled and exits on
+/// its own.
+///
+/// # Safety
+/// - `stream` must have been returned by `pforge_stream_open`
+/// - No other stream call on `stream` may be running or follow
+#[no_mangle]
+pub unsafe extern "C" fn pforge_stream_free(stream: *mut c_void) {
+    if stream.is_null() {
+        return;
+    }
+    let stream = Box::from_raw(stream as *mut ResultStream);
+    stream.shared.cancel();
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::ffi::CString;
+
+    #[test]
+    fn test_stream_chunks() {
+        unsafe {
+            let name = CString::new("report").unwrap();
+            let stream = pforge_stream_open(name.as_ptr(), b"{}".as_ptr(), 2);
+            assert!(!stream.is_null());
+
+            let result = pforge_stream_next(stream);
+            assert_eq!(result.code, 0);
+            let data = slice::from_raw_parts(result.data, result.data_len);
+            assert_eq!(data.last(), Some(&b'\n'));
+            let record: serde_json::Value = serde_json::from_slice(data).unwrap();
+            assert_eq!(record["handler"], "report");
+            pforge_free_result(result);
+
+            let end = pforge_stream_next(stream);
+            assert_eq!(end.code, 0);
+            assert!(end.data.is_null());
+            pforge_stream_free(stream);
+        }
+    }
+
+    fn new_shared() -> Arc<Shared> {
+        Arc::new(Shared {
+            state: Mutex::new(StreamState {
+                chunks: VecDeque::new(),
+                done: false,
+                cancelled: false,
+            }),
+            changed: Condvar::new(),
+        })
+    }
+
+    #[test]
+    fn test_keepalive_while_quiet() {
+        let shared = new_shared();
+        let keepalive = Duration::from_millis(10);
+
+        let result = next_chunk(&shared, keepalive);
+        assert_eq!(result.code, PFORGE_KEEPALIVE);
+        assert!(result.data.is_null());
+
+        shared.emit(Ok(b"late".to_vec()));
+        let result = next_chunk(&shared, keepalive);
+        assert_eq!(result.code, PFORGE_OK);
+        unsafe {
+            assert_eq!(slice::from_raw_parts(result.data, result.data_len), b"late");
+            pforge_free_result(result);
+        }
+    }
+
+    #[test]
+    fn test_emit_waits_for_reader_and_stops_on_cancel() {
+        let shared = new_shared();
+        let producer = Arc::clone(&shared);
+        let handle = thread::spawn(move || {
+            let mut sent = 0;
+            while producer.emit(Ok(vec![b'x'])) {
+                sent += 1;
+            }
+            sent
+        });
+
+        // The producer fills the buffer, then blocks until cancelled
+        while shared.lock().chunks.len() < STREAM_BUFFER {
+            thread::yield_now();
+        }
+        shared.cancel();
+        assert_eq!(handle.join().unwrap(), STREAM_BUFFER);
+    }
+}
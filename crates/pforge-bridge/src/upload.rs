@@ -0,0 +1,128 @@
+//! Chunked uploads: many chunks of input in, one result out
+
+use std::ffi::c_void;
+use std::os::raw::{c_char, c_int};
+use std::slice;
+
+use crate::{
+    catch_panic, dispatch, handler_name_str, pforge_free_result, FfiResult,
+    PFORGE_ERR_NULL_POINTER, PFORGE_OK,
+};
+
+/// An upload in progress, handed to the caller as an opaque pointer
+struct Upload {
+    handler_name: String,
+    input: Vec<u8>,
+}
+
+/// Start an upload to a handler
+///
+/// Returns null if `handler_name` is null or not valid UTF-8.
+///
+/// # Safety
+/// - `handler_name` must be a valid null-terminated string
+/// - The upload must be ended with `pforge_upload_finish` or
+///   `pforge_upload_abort`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_upload_open(handler_name: *const c_char) -> *mut c_void {
+    match handler_name_str(handler_name) {
+        Ok(name) => Box::into_raw(Box::new(Upload {
+            handler_name: name.to_string(),
+            input: Vec::new(),
+        })) as *mut c_void,
+        Err(result) => {
+            pforge_free_result(result);
+            std::ptr::null_mut()
+        }
+    }
+}
+
+/// Append a chunk to an upload's input
+///
+/// Returns 0 once the chunk is accepted, or a non-zero code if a pointer is
+/// null.
+///
+/// # Safety
+/// - `upload` must have been returned by `pforge_upload_open` and not ended
+/// - `chunk` must be a valid pointer to `chunk_len` bytes
+#[no_mangle]
+pub unsafe extern "C" fn pforge_upload_write(
+    upload: *mut c_void,
+    chunk: *const u8,
+    chunk_len: usize,
+) -> c_int {
+    if upload.is_null() || chunk.is_null() {
+        return PFORGE_ERR_NULL_POINTER;
+    }
+    let upload = &mut *(upload as *mut Upload);
+    upload
+        .input
+        .extend_from_slice(slice::from_raw_parts(chunk, chunk_len));
+    PFORGE_OK
+}
+
+/// End an upload's input, run the handler on it and free the upload
+///
+/// # Safety
+/// - `upload` must have been returned by `pforge_upload_open` and not ended
+/// - Caller must free the result with `pforge_free_result`
+#[no_mangle]
+pub unsafe extern "C" fn pforge_upload_finish(upload: *mut c_void) -> FfiResult {
+    if upload.is_null() {
+        return FfiResult::error(PFORGE_ERR_NULL_POINTER, "Null pointer provided");
+    }
+    let upload = Box::from_raw(upload as *mut Upload);
+    catch_panic(|| FfiResult::json(&dispatch(&upload.handler_name, &upload.input)))
+}
+
+/// Discard an upload's input without running the handler, and free the
+/// upload
+///
+/// # Safety
+/// - `upload` must have been returned by `pforge_upload_open` and not ended
+#[no_mangle]
+pub unsafe extern "C" fn pforge_upload_abort(upload: *mut c_void) {
+    if !upload.is_null() {
+        drop(Box::from_raw(upload as *mut Upload));
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::ffi::CString;
+
+    #[test]
+    fn test_upload() {
+        unsafe {
+            let name = CString::new("ingest").unwrap();
+            let upload = pforge_upload_open(name.as_ptr());
+            assert!(!upload.is_null());
+            for chunk in [&b"{\"data\":"[..], b"\"abc\"", b"}"] {
+                assert_eq!(pforge_upload_write(upload, chunk.as_ptr(), chunk.len()), 0);
+            }
+
+            let result = pforge_upload_finish(upload);
+            assert_eq!(result.code, 0);
+            let data = slice::from_raw_parts(result.data, result.data_len);
+            let response: serde_json::Value = serde_json::from_slice(data).unwrap();
+            assert_eq!(response["handler"], "ingest");
+            assert_eq!(response["input_size"], 14);
+            pforge_free_result(result);
+        }
+    }
+
+    #[test]
+    fn test_upload_abort() {
+        unsafe {
+            let name = CString::new("ingest").unwrap();
+            let upload = pforge_upload_open(name.as_ptr());
+            assert_eq!(
+                pforge_upload_write(upload, std::ptr::null(), 0),
+                PFORGE_ERR_NULL_POINTER
+            );
+            pforge_upload_abort(upload);
+            pforge_upload_abort(std::ptr::null_mut());
+        }
+    }
+}
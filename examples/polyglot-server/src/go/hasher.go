@@ -1,65 +1,824 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hash"
+	"io"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 )
 
-type HashResult struct {
-	Hash      string `json:"hash"`
-	Algorithm string `json:"algorithm"`
-	Data      string `json:"data"`
+// chunkSize is how much of a file is read between cancellation checks
+const chunkSize = 64 * 1024
+
+// Exit codes follow the pforgehandler SDK convention
+const (
+	exitBadInput     = 2
+	exitHandlerError = 3
+)
+
+var (
+	errUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	errVerifyAlgorithms     = errors.New("--verify takes a single algorithm")
+	errDirAlgorithms        = errors.New("--dir takes a single algorithm")
+	errUnknownSession       = errors.New("unknown or expired session")
+	errUnknownOp            = errors.New("unknown op")
+	errTooManySessions      = errors.New("too many open sessions")
+)
+
+// fixedTime pins the hasher's clock for test builds, so golden-file tests
+// of timestamped results stay stable. Set it at link time:
+//
+//	go build -ldflags "-X main.fixedTime=2024-01-01T00:00:00Z" hasher.go
+//
+// It is empty in normal builds, which use the real clock.
+var fixedTime string
+
+// now is the hasher's clock. Any timestamp the hasher reports must come from
+// now rather than time.Now, so that fixedTime applies to it.
+var now = time.Now
+
+// setClock installs fixedTime as the clock if the build set one
+func setClock() error {
+	if fixedTime == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, fixedTime)
+	if err != nil {
+		return fmt.Errorf("invalid fixed time %q: %w", fixedTime, err)
+	}
+	now = func() time.Time { return t }
+	return nil
 }
 
-func calculateHash(algorithm, data string) (string, error) {
-	var h hash.Hash
+type HashResult struct {
+	Hash      string            `json:"hash"`
+	Hashes    map[string]string `json:"hashes,omitempty"`
+	Algorithm string            `json:"algorithm"`
+	Data      string            `json:"data"`
+	File      string            `json:"file,omitempty"`
+	Dir       string            `json:"dir,omitempty"`
+	Files     map[string]string `json:"files,omitempty"`
+	Skipped   map[string]string `json:"skipped,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	BytesRead int64             `json:"bytes_read,omitempty"`
+	Match     *bool             `json:"match,omitempty"`
+	Expected  string            `json:"expected,omitempty"`
+	Actual    string            `json:"actual,omitempty"`
+}
 
+func newHash(algorithm string) (hash.Hash, error) {
 	switch algorithm {
 	case "md5":
-		h = md5.New()
+		return md5.New(), nil
 	case "sha1":
-		h = sha1.New()
+		return sha1.New(), nil
 	case "sha256":
-		h = sha256.New()
+		return sha256.New(), nil
 	case "sha512":
-		h = sha512.New()
+		return sha512.New(), nil
 	default:
-		return "", fmt.Errorf("unsupported algorithm: %s", algorithm)
+		return nil, fmt.Errorf("%w: %s", errUnsupportedAlgorithm, algorithm)
 	}
+}
 
-	h.Write([]byte(data))
-	return hex.EncodeToString(h.Sum(nil)), nil
+// multiHash computes several digests over a single pass of the data
+type multiHash struct {
+	algorithms []string
+	hashes     []hash.Hash
+	io.Writer
 }
 
-func main() {
-	if len(os.Args) < 3 {
-		result := map[string]string{"error": "algorithm and data arguments required"}
-		json.NewEncoder(os.Stdout).Encode(result)
-		os.Exit(1)
+// newMultiHash builds a multiHash from a comma-separated algorithm list
+func newMultiHash(spec string) (*multiHash, error) {
+	m := &multiHash{}
+	writers := []io.Writer{}
+	for _, algorithm := range strings.Split(spec, ",") {
+		algorithm = strings.TrimSpace(algorithm)
+		h, err := newHash(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		m.algorithms = append(m.algorithms, algorithm)
+		m.hashes = append(m.hashes, h)
+		writers = append(writers, h)
+	}
+	m.Writer = io.MultiWriter(writers...)
+	return m, nil
+}
+
+// fill sets the digest fields of a result. A single algorithm keeps the
+// original "hash" field; several algorithms are reported in "hashes".
+func (m *multiHash) fill(result *HashResult) {
+	if len(m.hashes) == 1 {
+		result.Hash = hex.EncodeToString(m.hashes[0].Sum(nil))
+		return
+	}
+
+	result.Hashes = make(map[string]string, len(m.hashes))
+	for i, h := range m.hashes {
+		result.Hashes[m.algorithms[i]] = hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+func calculateHash(algorithm, data string) (HashResult, error) {
+	m, err := newMultiHash(algorithm)
+	if err != nil {
+		return HashResult{}, err
+	}
+
+	m.Write([]byte(data))
+	result := HashResult{Algorithm: algorithm, Data: data}
+	m.fill(&result)
+	return result, nil
+}
+
+// hashReader streams r into w chunk by chunk, stopping early if ctx is
+// cancelled. It returns the number of bytes hashed.
+func hashReader(ctx context.Context, w io.Writer, r io.Reader) (int64, error) {
+	buf := make([]byte, chunkSize)
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		n, err := r.Read(buf)
+		if n > 0 {
+			w.Write(buf[:n])
+			total += int64(n)
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// hashFile streams a file through the hash. If the runtime cancels the call
+// (SIGINT/SIGTERM) the read stops at the next chunk and a cancelled result
+// reporting the bytes read so far is returned.
+func hashFile(ctx context.Context, algorithm, path string) (HashResult, error) {
+	m, err := newMultiHash(algorithm)
+	if err != nil {
+		return HashResult{}, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return HashResult{}, err
+	}
+	defer f.Close()
+
+	n, err := hashReader(ctx, m, f)
+	if ctx.Err() != nil {
+		return HashResult{Algorithm: algorithm, File: path, Status: "cancelled", BytesRead: n}, nil
+	}
+	if err != nil {
+		return HashResult{}, err
+	}
+
+	result := HashResult{Algorithm: algorithm, File: path, BytesRead: n}
+	m.fill(&result)
+	return result, nil
+}
+
+// dirWalker computes a Merkle digest over a directory tree.
+//
+// Each directory's digest is the hash of one line per entry, in byte order
+// of the entry names (the order os.ReadDir returns):
+//
+//	file <hex digest of contents> <name>
+//	dir <hex digest of subdirectory> <name>
+//
+// with the name quoted as a Go string literal (strconv.Quote) so names
+// containing spaces or newlines cannot collide. The root digest is the
+// digest of the top directory, so two trees have the same root exactly
+// when they hold the same names and contents, and equal subtrees have
+// equal digests wherever they appear. Empty directories are included.
+// Symlinks and other special files are not followed or hashed; they are
+// listed in "skipped".
+type dirWalker struct {
+	ctx            context.Context
+	algorithm      string
+	root           string
+	skipUnreadable bool
+	bytesRead      int64
+	files          map[string]string
+	skipped        map[string]string
+}
+
+// hashDir returns the digest of the directory at rel, a slash-separated
+// path relative to the root ("." for the root itself). With
+// skipUnreadable, an unreadable file or subdirectory is left out of its
+// parent's digest and recorded in skipped; it returns nil for such a
+// subdirectory.
+func (w *dirWalker) hashDir(rel string) ([]byte, error) {
+	entries, err := os.ReadDir(filepath.Join(w.root, filepath.FromSlash(rel)))
+	if err != nil {
+		if rel == "." || !w.skipUnreadable {
+			return nil, err
+		}
+		w.skipped[rel] = err.Error()
+		return nil, nil
+	}
+
+	h, err := newHash(w.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		entryPath := path.Join(rel, name)
+
+		var kind string
+		var digest []byte
+		switch {
+		case entry.IsDir():
+			kind = "dir"
+			digest, err = w.hashDir(entryPath)
+		case entry.Type().IsRegular():
+			kind = "file"
+			digest, err = w.hashFile(entryPath)
+		default:
+			w.skipped[entryPath] = "not a regular file or directory"
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if digest == nil {
+			continue
+		}
+		fmt.Fprintf(h, "%s %x %q\n", kind, digest, name)
+	}
+	return h.Sum(nil), nil
+}
+
+// hashFile returns the digest of the file at rel and records it in files,
+// or records it in skipped and returns nil if it cannot be read and
+// skipUnreadable is set
+func (w *dirWalker) hashFile(rel string) ([]byte, error) {
+	h, err := newHash(w.algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(w.root, filepath.FromSlash(rel)))
+	if err == nil {
+		var n int64
+		n, err = hashReader(w.ctx, h, f)
+		f.Close()
+		w.bytesRead += n
+	}
+	if w.ctx.Err() != nil {
+		return nil, w.ctx.Err()
+	}
+	if err != nil {
+		if !w.skipUnreadable {
+			return nil, err
+		}
+		w.skipped[rel] = err.Error()
+		return nil, nil
 	}
 
-	algorithm := os.Args[1]
-	data := os.Args[2]
+	digest := h.Sum(nil)
+	w.files[rel] = hex.EncodeToString(digest)
+	return digest, nil
+}
+
+// hashDirectory computes the Merkle digest of the tree at root (see
+// dirWalker), reporting it in "hash" with each file's digest in "files".
+// Like hashFile, a cancelled walk returns a cancelled result.
+func hashDirectory(ctx context.Context, algorithm, root string, skipUnreadable bool) (HashResult, error) {
+	if strings.Contains(algorithm, ",") {
+		return HashResult{}, errDirAlgorithms
+	}
+	if _, err := newHash(algorithm); err != nil {
+		return HashResult{}, err
+	}
 
-	hashValue, err := calculateHash(algorithm, data)
+	w := &dirWalker{
+		ctx:            ctx,
+		algorithm:      algorithm,
+		root:           root,
+		skipUnreadable: skipUnreadable,
+		files:          make(map[string]string),
+		skipped:        make(map[string]string),
+	}
+	digest, err := w.hashDir(".")
+	if ctx.Err() != nil {
+		return HashResult{Algorithm: algorithm, Dir: root, Status: "cancelled", BytesRead: w.bytesRead}, nil
+	}
 	if err != nil {
-		result := map[string]string{"error": err.Error()}
-		json.NewEncoder(os.Stdout).Encode(result)
-		os.Exit(1)
+		return HashResult{}, err
 	}
 
 	result := HashResult{
-		Hash:      hashValue,
+		Hash:      hex.EncodeToString(digest),
 		Algorithm: algorithm,
-		Data:      data,
+		Dir:       root,
+		Files:     w.files,
+		BytesRead: w.bytesRead,
+	}
+	if len(w.skipped) > 0 {
+		result.Skipped = w.skipped
+	}
+	return result, nil
+}
+
+// Session limits for --serve mode. A session unused for sessionTTL is
+// dropped, so a caller that abandons one does not leak its hash state.
+const (
+	defaultSessionTTL = 5 * time.Minute
+	maxSessions       = 1024
+)
+
+// maxFrameSize caps a --serve request frame, so a corrupt length prefix
+// fails the loop instead of allocating up to 4 GiB
+const maxFrameSize = 16 << 20
+
+// sessionRequest is one request in --serve mode. Op is "start" (with
+// Algorithm), "update" (with Session and Data), "finish" (with Session) or
+// "abort" (with Session).
+type sessionRequest struct {
+	Op        string `json:"op"`
+	Session   string `json:"session,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
+// sessionResult reports a session's state after each request. The digest
+// fields are only set by "finish".
+type sessionResult struct {
+	Session   string            `json:"session"`
+	Algorithm string            `json:"algorithm"`
+	BytesRead int64             `json:"bytes_read"`
+	ExpiresAt string            `json:"expires_at,omitempty"`
+	Hash      string            `json:"hash"`
+	Hashes    map[string]string `json:"hashes,omitempty"`
+}
+
+// sessionState is a session as saved between requests. States holds the
+// intermediate state of each digest, as the crypto hashes marshal it with
+// encoding.BinaryMarshaler.
+type sessionState struct {
+	Algorithm string   `json:"algorithm"`
+	States    [][]byte `json:"states"`
+	BytesRead int64    `json:"bytes_read"`
+}
+
+// sessionStore keeps each session in a file named by its ID, so that every
+// --serve process sharing the directory sees every session: an ExecPool
+// sends each request to whichever of its processes is idle and recycles
+// them, so the process that handled "start" is rarely the one that handles
+// the next "update". A file's modification time is when the session was
+// last used. Each request replaces the file whole, so requests for one
+// session must not overlap, which callers feeding it in order never do.
+type sessionStore struct {
+	dir string
+	ttl time.Duration
+}
+
+func newSessionStore(dir string, ttl time.Duration) (*sessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &sessionStore{dir: dir, ttl: ttl}, nil
+}
+
+// path returns the file of session id, rejecting IDs this store could not
+// have issued so that they cannot name other files
+func (s *sessionStore) path(id string) (string, error) {
+	if _, err := hex.DecodeString(id); err != nil || len(id) != 32 {
+		return "", fmt.Errorf("%w: %q", errUnknownSession, id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// expire deletes sessions, and files left by interrupted saves, unused
+// for longer than the TTL. It runs before every request, since the serve
+// loop has no other chance to run. It returns the number of sessions left.
+func (s *sessionStore) expire() (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read session directory: %w", err)
+	}
+
+	cutoff := now().Add(-s.ttl)
+	live := 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// Finished or expired by another process meanwhile
+			continue
+		}
+		if info.ModTime().Before(cutoff) {
+			os.Remove(filepath.Join(s.dir, entry.Name()))
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), ".json") {
+			live++
+		}
 	}
+	return live, nil
+}
 
+// load reads session id and restores its digests
+func (s *sessionStore) load(id string) (*multiHash, sessionState, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, sessionState{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, sessionState{}, fmt.Errorf("%w: %q", errUnknownSession, id)
+	}
+	if err != nil {
+		return nil, sessionState{}, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, sessionState{}, fmt.Errorf("corrupt session %s: %w", id, err)
+	}
+	m, err := newMultiHash(state.Algorithm)
+	if err != nil {
+		return nil, sessionState{}, err
+	}
+	if len(state.States) != len(m.hashes) {
+		return nil, sessionState{}, fmt.Errorf("corrupt session %s: %d digest states for %q", id, len(state.States), state.Algorithm)
+	}
+	for i, h := range m.hashes {
+		if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state.States[i]); err != nil {
+			return nil, sessionState{}, fmt.Errorf("corrupt session %s: %w", id, err)
+		}
+	}
+	return m, state, nil
+}
+
+// save writes session id, replacing the file atomically so that a
+// concurrent reader never sees it half written
+func (s *sessionStore) save(id string, m *multiHash, state sessionState) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	state.States = state.States[:0]
+	for _, h := range m.hashes {
+		b, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+		state.States = append(state.States, b)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	f, err := os.CreateTemp(s.dir, ".save-*")
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chtimes(f.Name(), now(), now())
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// handle applies one request to the store
+func (s *sessionStore) handle(req sessionRequest) (sessionResult, error) {
+	live, err := s.expire()
+	if err != nil {
+		return sessionResult{}, err
+	}
+
+	switch req.Op {
+	case "start":
+		if live >= maxSessions {
+			return sessionResult{}, fmt.Errorf("%w: limit is %d", errTooManySessions, maxSessions)
+		}
+		return s.start(req.Algorithm)
+	case "update":
+		m, state, err := s.load(req.Session)
+		if err != nil {
+			return sessionResult{}, err
+		}
+		m.Write([]byte(req.Data))
+		state.BytesRead += int64(len(req.Data))
+		if err := s.save(req.Session, m, state); err != nil {
+			return sessionResult{}, err
+		}
+		return s.result(req.Session, state), nil
+	case "finish", "abort":
+		m, state, err := s.load(req.Session)
+		if err != nil {
+			return sessionResult{}, err
+		}
+		path, _ := s.path(req.Session)
+		if err := os.Remove(path); err != nil {
+			// Another request finished it first
+			return sessionResult{}, fmt.Errorf("%w: %q", errUnknownSession, req.Session)
+		}
+		result := s.result(req.Session, state)
+		result.ExpiresAt = ""
+		if req.Op == "finish" {
+			var digest HashResult
+			m.fill(&digest)
+			result.Hash, result.Hashes = digest.Hash, digest.Hashes
+		}
+		return result, nil
+	default:
+		return sessionResult{}, fmt.Errorf("%w: %q", errUnknownOp, req.Op)
+	}
+}
+
+func (s *sessionStore) start(algorithm string) (sessionResult, error) {
+	m, err := newMultiHash(algorithm)
+	if err != nil {
+		return sessionResult{}, err
+	}
+
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return sessionResult{}, fmt.Errorf("failed to generate session id: %w", err)
+	}
+	state := sessionState{Algorithm: algorithm}
+	if err := s.save(hex.EncodeToString(id[:]), m, state); err != nil {
+		return sessionResult{}, err
+	}
+	return s.result(hex.EncodeToString(id[:]), state), nil
+}
+
+func (s *sessionStore) result(id string, state sessionState) sessionResult {
+	return sessionResult{
+		Session:   id,
+		Algorithm: state.Algorithm,
+		BytesRead: state.BytesRead,
+		ExpiresAt: now().Add(s.ttl).UTC().Format(time.RFC3339),
+	}
+}
+
+// serveResponse is the reply frame for one request, as in the
+// pforgehandler SDK's --serve mode
+type serveResponse struct {
+	Status int             `json:"status"`
+	Output json.RawMessage `json:"output,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// serve answers session requests until stdin is closed, using the
+// pforgehandler SDK's --serve framing: each request and reply is a 4-byte
+// big-endian length followed by that many bytes of JSON, up to
+// maxFrameSize. Sessions are kept in cfg.dir (see sessionStore).
+func serve(cfg serveConfig, stdin io.Reader, stdout io.Writer) error {
+	r := bufio.NewReader(stdin)
+	w := bufio.NewWriter(stdout)
+	store, err := newSessionStore(cfg.dir, cfg.ttl)
+	if err != nil {
+		return err
+	}
+
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("truncated frame header: %w", err)
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size > maxFrameSize {
+			return fmt.Errorf("frame of %d bytes exceeds the %d byte limit", size, maxFrameSize)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return fmt.Errorf("truncated frame: %w", err)
+		}
+
+		var resp serveResponse
+		var req sessionRequest
+		var result sessionResult
+		err := json.Unmarshal(payload, &req)
+		if err == nil {
+			result, err = store.handle(req)
+		}
+		if err != nil {
+			resp.Status = exitCode(err)
+			resp.Error = err.Error()
+		} else {
+			resp.Output, _ = json.Marshal(result)
+		}
+
+		reply, _ := json.Marshal(resp)
+		binary.BigEndian.PutUint32(header[:], uint32(len(reply)))
+		w.Write(header[:])
+		w.Write(reply)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// verify compares the computed digest with the expected hex digest,
+// ignoring case and surrounding whitespace
+func (result *HashResult) verify(expected string) {
+	match := strings.EqualFold(strings.TrimSpace(expected), result.Hash)
+	result.Match = &match
+	result.Expected = expected
+	result.Actual = result.Hash
+}
+
+// splitVerify removes a trailing "--verify <expected>" from the arguments
+func splitVerify(args []string) (rest []string, expected string, ok bool, err error) {
+	for i, arg := range args {
+		if arg != "--verify" {
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, "", false, fmt.Errorf("--verify requires an expected digest")
+		}
+		rest = append(append(rest, args[:i]...), args[i+2:]...)
+		return rest, args[i+1], true, nil
+	}
+	return args, "", false, nil
+}
+
+func exitWithError(code int, err error) {
+	result := map[string]string{"error": err.Error()}
+	json.NewEncoder(os.Stdout).Encode(result)
+	os.Exit(code)
+}
+
+// exitCode classifies an error as bad input or a handler failure
+func exitCode(err error) int {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, errUnsupportedAlgorithm), errors.Is(err, errDirAlgorithms),
+		errors.Is(err, errUnknownSession), errors.Is(err, errUnknownOp),
+		errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return exitBadInput
+	}
+	return exitHandlerError
+}
+
+// serveConfig holds the --serve flags
+type serveConfig struct {
+	ttl time.Duration
+	dir string
+}
+
+// parseServeArgs parses the flags following --serve, rejecting any it
+// does not know and any missing a value
+func parseServeArgs(args []string) (serveConfig, error) {
+	cfg := serveConfig{
+		ttl: defaultSessionTTL,
+		dir: filepath.Join(os.TempDir(), "pforge-hasher-sessions"),
+	}
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		if flag != "--session-ttl" && flag != "--session-dir" {
+			return serveConfig{}, fmt.Errorf("unknown --serve argument %q", flag)
+		}
+		if i+1 >= len(args) {
+			return serveConfig{}, fmt.Errorf("%s requires a value", flag)
+		}
+		i++
+		switch flag {
+		case "--session-ttl":
+			ttl, err := time.ParseDuration(args[i])
+			if err != nil || ttl <= 0 {
+				return serveConfig{}, fmt.Errorf("invalid --session-ttl %q", args[i])
+			}
+			cfg.ttl = ttl
+		case "--session-dir":
+			cfg.dir = args[i]
+		}
+	}
+	return cfg, nil
+}
+
+// finish writes the result and exits non-zero if it was cancelled or
+// failed verification
+func finish(result HashResult) {
 	json.NewEncoder(os.Stdout).Encode(result)
+	if result.Status == "cancelled" || (result.Match != nil && !*result.Match) {
+		os.Exit(exitHandlerError)
+	}
+}
+
+func main() {
+	if err := setClock(); err != nil {
+		exitWithError(exitHandlerError, err)
+	}
+
+	// hasher --serve [--session-ttl <duration>] [--session-dir <path>]
+	if len(os.Args) > 1 && os.Args[1] == "--serve" {
+		cfg, err := parseServeArgs(os.Args[2:])
+		if err != nil {
+			exitWithError(exitBadInput, err)
+		}
+		if err := serve(cfg, os.Stdin, os.Stdout); err != nil {
+			exitWithError(exitHandlerError, err)
+		}
+		return
+	}
+
+	args, expected, verifying, err := splitVerify(os.Args[1:])
+	if err != nil {
+		exitWithError(exitBadInput, err)
+	}
+	if len(args) < 2 {
+		exitWithError(exitBadInput, fmt.Errorf("algorithm and data arguments required"))
+	}
+
+	algorithm := args[0]
+	if verifying && strings.Contains(algorithm, ",") {
+		exitWithError(exitBadInput, errVerifyAlgorithms)
+	}
+
+	// hasher <algorithm> --file <path> [--verify <expected>]
+	if args[1] == "--file" {
+		if len(args) < 3 {
+			exitWithError(exitBadInput, fmt.Errorf("--file requires a path"))
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		result, err := hashFile(ctx, algorithm, args[2])
+		stop()
+		if err != nil {
+			exitWithError(exitCode(err), err)
+		}
+
+		if verifying && result.Status != "cancelled" {
+			result.verify(expected)
+		}
+		finish(result)
+		return
+	}
+
+	// hasher <algorithm> --dir <path> [--skip-unreadable] [--verify <expected>]
+	if args[1] == "--dir" {
+		if len(args) < 3 {
+			exitWithError(exitBadInput, fmt.Errorf("--dir requires a path"))
+		}
+		skipUnreadable := len(args) > 3 && args[3] == "--skip-unreadable"
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		result, err := hashDirectory(ctx, algorithm, args[2], skipUnreadable)
+		stop()
+		if err != nil {
+			exitWithError(exitCode(err), err)
+		}
+
+		if verifying && result.Status != "cancelled" {
+			result.verify(expected)
+		}
+		finish(result)
+		return
+	}
+
+	data := args[1]
+
+	result, err := calculateHash(algorithm, data)
+	if err != nil {
+		exitWithError(exitCode(err), err)
+	}
+
+	if verifying {
+		result.verify(expected)
+	}
+	finish(result)
 }